@@ -2,23 +2,36 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"strings"
+	"syscall"
+	"time"
 )
 
+// DefaultStopGracePeriod is time between SIGTERM and SIGKILL on cancel,
+// it lets wrappers like docker stop own children
+const DefaultStopGracePeriod = 10 * time.Second
+
 //Command is wrapper under exec.Cmd
 type Command struct {
 	exec.Cmd
-	Stdout *bytes.Buffer
+	Stdout    *bytes.Buffer
+	Stderr    *bytes.Buffer
+	outWriter io.Writer
+	errWriter io.Writer
+	stopGrace time.Duration
 }
 
 //New init wrapper
 func New(cmd *exec.Cmd) *Command {
 	return &Command{
-		Cmd:    *cmd,
-		Stdout: bytes.NewBufferString(``),
+		Cmd:       *cmd,
+		Stdout:    bytes.NewBufferString(``),
+		Stderr:    bytes.NewBufferString(``),
+		stopGrace: DefaultStopGracePeriod,
 	}
 }
 
@@ -38,13 +51,66 @@ func (c *Command) WithEnvVarsMap(vars map[string]string) *Command {
 	return c
 }
 
+//WithOutput copy stdout and stderr of command to writers while it runs, nil writer is skipped
+func (c *Command) WithOutput(stdout, stderr io.Writer) *Command {
+	c.outWriter = stdout
+	c.errWriter = stderr
+	return c
+}
+
+//WithStopGracePeriod set time between SIGTERM and SIGKILL on cancel, non positive period is ignored
+func (c *Command) WithStopGracePeriod(period time.Duration) *Command {
+	if period > 0 {
+		c.stopGrace = period
+	}
+	return c
+}
+
 //Run command and use buffers for out results
 func (c *Command) Run() *Error {
-	bytesBuf := bytes.NewBufferString(``)
-	c.Cmd.Stdout = c.Stdout
-	c.Cmd.Stderr = bytesBuf
-	if err := c.Cmd.Run(); err != nil {
-		return NewError(err, []byte(strings.Replace(bytesBuf.String(), "\n", "", -1)))
+	return c.RunContext(context.Background())
+}
+
+//RunContext run command in own process group,
+//whole group is terminated when ctx is done and killed if it is still alive after grace period,
+//so children of command don't survive it
+func (c *Command) RunContext(ctx context.Context) *Error {
+	c.Cmd.Stdout = teeWriter(c.Stdout, c.outWriter)
+	c.Cmd.Stderr = teeWriter(c.Stderr, c.errWriter)
+	c.Cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := c.Cmd.Start(); err != nil {
+		return NewError(err, nil)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func(pid int, stopGrace time.Duration) {
+		select {
+		case <-ctx.Done():
+			// negative pid means process group
+			_ = syscall.Kill(-pid, syscall.SIGTERM)
+		case <-done:
+			return
+		}
+		select {
+		case <-time.After(stopGrace):
+			_ = syscall.Kill(-pid, syscall.SIGKILL)
+		case <-done:
+		}
+	}(c.Process.Pid, c.stopGrace)
+
+	if err := c.Cmd.Wait(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		return NewError(err, c.Stderr.Bytes())
 	}
 	return nil
 }
+
+func teeWriter(buf *bytes.Buffer, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
@@ -3,29 +3,37 @@ package command
 import (
 	"encoding/base64"
 	"encoding/json"
+	"os/exec"
 )
 
 // Error is command error wrapper
 type Error struct {
-	Message error
-	Stderr  []byte
+	Message  error
+	Stderr   []byte
+	ExitCode int
 }
 
 func (e Error) Error() string {
 	return e.Message.Error() + "\n" + string(e.Stderr)
 }
 
-//NewError init command error
+//NewError init command error, exit code is -1 if command was not exited by itself
 func NewError(message error, stderr []byte) *Error {
-	return &Error{Message: message, Stderr: stderr}
+	exitCode := -1
+	if exitErr, ok := message.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	}
+	return &Error{Message: message, Stderr: stderr, ExitCode: exitCode}
 }
 
 func (e Error) MarshalJSON() ([]byte, error) {
 	m := struct {
-		Message string `json:"message"`
-		Stderr  string `json:"stderr"`
+		Message  string `json:"message"`
+		Stderr   string `json:"stderr"`
+		ExitCode int    `json:"exitCode"`
 	}{}
 	m.Message = e.Message.Error()
 	m.Stderr = base64.StdEncoding.EncodeToString(e.Stderr)
+	m.ExitCode = e.ExitCode
 	return json.Marshal(m)
 }
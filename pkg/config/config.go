@@ -7,20 +7,41 @@ import (
 
 	"github.com/makerdao/testchain-deployment/pkg/deploy"
 	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/git"
 	"github.com/makerdao/testchain-deployment/pkg/github"
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/logstream"
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+	"github.com/makerdao/testchain-deployment/pkg/service/grpc"
 	"github.com/makerdao/testchain-deployment/pkg/service/nats"
+	"github.com/makerdao/testchain-deployment/pkg/storage"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	"github.com/makerdao/testchain-deployment/pkg/webhook"
 )
 
 // Config is an application config
 type Config struct {
-	Server   string         `split_word:"true"`
-	Host     string         `split_word:"true"`
-	Port     int            `split_word:"true"`
-	Deploy   deploy.Config  `split_word:"true"`
-	Gateway  gateway.Config `split_word:"true"`
-	Github   github.Config  `split_word:"true"`
-	NATS     nats.Config    `split_word:"true"`
-	LogLevel string         `split_word:"true"`
+	Server   string             `split_word:"true"`
+	Host     string             `split_word:"true"`
+	Port     int                `split_word:"true"`
+	Deploy   deploy.Config      `split_word:"true"`
+	Gateway  gateway.Config     `split_word:"true"`
+	Github   github.Config      `split_word:"true"`
+	Git      git.Config         `split_word:"true"`
+	GitAuth  git.AuthConfig     `envconfig:"GIT_AUTH"`
+	NATS     nats.Config        `split_word:"true"`
+	GRPC     grpc.Config        `split_word:"true"`
+	Storage  storage.Config     `split_word:"true"`
+	Pool     job.PoolConfig     `split_word:"true"`
+	Jobs     job.RegistryConfig `split_word:"true"`
+	Logs     logstream.Config   `split_word:"true"`
+	Secrets  secret.Config      `split_word:"true"`
+	Webhooks webhook.Config     `split_word:"true"`
+	Tracing  tracing.Config     `split_word:"true"`
+	LogLevel string             `split_word:"true"`
+
+	// HTTPWithNATS start HTTP server for logs, health and metrics in NATS server mode
+	HTTPWithNATS bool `envconfig:"HTTP_WITH_NATS"`
 }
 
 // EnvPrefix is prefix for env var, like a TCD_SOME_VAR
@@ -36,7 +57,17 @@ func New() *Config {
 		Deploy:   deploy.GetDefaultConfig(),
 		Gateway:  gateway.GetDefaultConfig(),
 		Github:   github.GetDefaultConfig(),
+		Git:      git.GetDefaultConfig(),
+		GitAuth:  git.GetDefaultAuthConfig(),
 		NATS:     nats.GetDefaultConfig(),
+		GRPC:     grpc.GetDefaultConfig(),
+		Storage:  storage.GetDefaultConfig(),
+		Pool:     job.GetDefaultPoolConfig(),
+		Jobs:     job.GetDefaultRegistryConfig(),
+		Logs:     logstream.GetDefaultConfig(),
+		Secrets:  secret.GetDefaultConfig(),
+		Webhooks: webhook.GetDefaultConfig(),
+		Tracing:  tracing.GetDefaultConfig(),
 		LogLevel: "debug",
 	}
 
@@ -51,12 +82,48 @@ func (c *Config) LoadFromEnv() error {
 // Validate cfg and all inclusion
 // Return first error
 func (c *Config) Validate() error {
-	if c.Server != "NATS" && c.Server != "HTTP" {
-		return errors.New("you should use 'HTTP' or 'NATS' server")
+	if c.Server != "NATS" && c.Server != "HTTP" && c.Server != "GRPC" {
+		return errors.New("you should use 'HTTP', 'NATS' or 'GRPC' server")
+	}
+	if err := c.GRPC.Validate(); err != nil {
+		return err
+	}
+	if err := c.Deploy.Validate(); err != nil {
+		return err
 	}
 	if err := c.Github.Validate(); err != nil {
 		return err
 	}
+	if err := c.Git.Validate(); err != nil {
+		return err
+	}
+	if err := c.GitAuth.Validate(); err != nil {
+		return err
+	}
+	if err := c.Storage.Validate(); err != nil {
+		return err
+	}
+	if err := c.Pool.Validate(); err != nil {
+		return err
+	}
+	if err := c.Jobs.Validate(); err != nil {
+		return err
+	}
+	if err := c.Logs.Validate(); err != nil {
+		return err
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		return err
+	}
+	if err := c.Gateway.Validate(); err != nil {
+		return err
+	}
+	if err := c.Webhooks.Validate(); err != nil {
+		return err
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		return err
+	}
 
 	return nil
 }
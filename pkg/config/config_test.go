@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestLoadFromEnvGitAuth(t *testing.T) {
+	t.Setenv("TCD_GIT_AUTH", "github.com=token:abc")
+	cfg := New()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	cred, ok := cfg.GitAuth.Hosts["github.com"]
+	if !ok || cred.Secret != "abc" {
+		t.Errorf("Credential of host must be loaded from TCD_GIT_AUTH, got %+v", cfg.GitAuth)
+	}
+}
+
+func TestLoadFromEnvHTTPWithNATS(t *testing.T) {
+	t.Setenv("TCD_SERVER", "NATS")
+	t.Setenv("TCD_HTTP_WITH_NATS", "true")
+	cfg := New()
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server != "NATS" || !cfg.HTTPWithNATS {
+		t.Errorf("HTTP server in NATS mode must be enabled by TCD_HTTP_WITH_NATS, got %+v", cfg)
+	}
+}
@@ -0,0 +1,294 @@
+package deploy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+// Env vars which are set for deployment with account
+const (
+	EnvEthKeystore = "ETH_KEYSTORE"
+	EnvEthPassword = "ETH_PASSWORD"
+	EnvEthFrom     = "ETH_FROM"
+)
+
+// keystore is encrypted with light scrypt params, file exists only while deployment runs
+const (
+	keystoreScryptN = 1 << 12
+	keystoreScryptR = 8
+	keystoreScryptP = 6
+	keystoreKeyLen  = 32
+)
+
+var (
+	privateKeyRegexp = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{64}$`)
+	hexAddressRegexp = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{40}$`)
+)
+
+// AccountModel is deploy account of deployment, either keystore JSON with password or raw private key
+type AccountModel struct {
+	// Keystore is content of encrypted key file (keystore v3)
+	Keystore json.RawMessage `json:"keystore,omitempty"`
+	// Password of keystore, random password is used for private key if it is empty
+	Password string `json:"password,omitempty"`
+	// PrivateKey is hex of raw private key
+	PrivateKey string `json:"privateKey,omitempty"`
+}
+
+// Address validate account and return its address with 0x prefix, keystore is decrypted with password,
+// so address is derived from its key and address field of keystore has to match it
+func (m *AccountModel) Address() (string, error) {
+	if err := m.Validate(); err != nil {
+		return "", err
+	}
+	if m.PrivateKey != "" {
+		key, err := m.privateKey()
+		if err != nil {
+			return "", err
+		}
+		return privateKeyAddress(key), nil
+	}
+	var keystore keystoreModel
+	if err := json.Unmarshal(m.Keystore, &keystore); err != nil {
+		return "", fmt.Errorf("keystore is not valid JSON")
+	}
+	key, err := keystore.decrypt(m.Password)
+	if err != nil {
+		return "", err
+	}
+	address := privateKeyAddress(key)
+	if keystore.Address != "" && "0x"+strings.ToLower(strings.TrimPrefix(keystore.Address, "0x")) != address {
+		return "", fmt.Errorf("address of keystore doesn't match its key")
+	}
+	return address, nil
+}
+
+// Validate check format of account without decryption of keystore, it is cheap enough for validation of request
+func (m *AccountModel) Validate() error {
+	switch {
+	case len(m.Keystore) != 0 && m.PrivateKey != "":
+		return fmt.Errorf("only one of keystore and privateKey can be set")
+	case len(m.Keystore) != 0:
+		var keystore keystoreModel
+		if err := json.Unmarshal(m.Keystore, &keystore); err != nil {
+			return fmt.Errorf("keystore is not valid JSON")
+		}
+		if keystore.Address != "" && !hexAddressRegexp.MatchString(keystore.Address) {
+			return fmt.Errorf("keystore has no valid address")
+		}
+		return keystore.validate()
+	case m.PrivateKey != "":
+		_, err := m.privateKey()
+		return err
+	}
+	return fmt.Errorf("keystore or privateKey is required")
+}
+
+// secrets return values of account which have to be redacted
+func (m *AccountModel) secrets() []string {
+	return []string{string(m.Keystore), m.Password, m.PrivateKey, strings.TrimPrefix(m.PrivateKey, "0x")}
+}
+
+func (m *AccountModel) privateKey() (*secp256k1.PrivateKey, error) {
+	if !privateKeyRegexp.MatchString(m.PrivateKey) {
+		return nil, fmt.Errorf("privateKey should be 32 bytes in hex")
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(m.PrivateKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("privateKey should be 32 bytes in hex")
+	}
+	var scalar secp256k1.ModNScalar
+	if overflow := scalar.SetByteSlice(data); overflow || scalar.IsZero() {
+		return nil, fmt.Errorf("privateKey is out of range")
+	}
+	return secp256k1.NewPrivateKey(&scalar), nil
+}
+
+// accountFiles are keystore dir and password file of account in own temp dir
+type accountFiles struct {
+	Dir          string
+	KeystoreDir  string
+	PasswordFile string
+	Address      string
+}
+
+// provisionAccount write keystore and password to new temp dir, address is result of Address of account,
+// dir is accessible only by owner and has to be shredded after deployment
+func provisionAccount(m *AccountModel, address string) (*accountFiles, error) {
+	var err error
+	keystore, password := []byte(m.Keystore), m.Password
+	if m.PrivateKey != "" {
+		if password == "" {
+			password, err = randomHex(16)
+			if err != nil {
+				return nil, err
+			}
+		}
+		key, err := m.privateKey()
+		if err != nil {
+			return nil, err
+		}
+		keystore, err = encryptKey(key, password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "deploy-account-")
+	if err != nil {
+		return nil, err
+	}
+	a := &accountFiles{
+		Dir:          dir,
+		KeystoreDir:  filepath.Join(dir, "keystore"),
+		PasswordFile: filepath.Join(dir, "password"),
+		Address:      address,
+	}
+	keyFile := fmt.Sprintf("UTC--%s--%s", time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), strings.TrimPrefix(address, "0x"))
+	err = os.Chmod(dir, 0700)
+	if err == nil {
+		err = os.Mkdir(a.KeystoreDir, 0700)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(a.KeystoreDir, keyFile), keystore, 0600)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(a.PasswordFile, []byte(password), 0600)
+	}
+	if err != nil {
+		a.Shred(nil)
+		return nil, err
+	}
+	return a, nil
+}
+
+// EnvVars return env vars which are used by dapp tools for account
+func (a *accountFiles) EnvVars() map[string]string {
+	return map[string]string{
+		EnvEthKeystore: a.KeystoreDir,
+		EnvEthPassword: a.PasswordFile,
+		EnvEthFrom:     a.Address,
+	}
+}
+
+// Shred overwrite all files of account with random data and remove dir
+func (a *accountFiles) Shred(log *logrus.Entry) {
+	err := filepath.Walk(a.Dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		return shredFile(path, fi.Size())
+	})
+	if err == nil {
+		err = os.RemoveAll(a.Dir)
+	}
+	if err != nil && log != nil {
+		log.WithError(err).Error("Couldn't shred account files")
+	}
+}
+
+func shredFile(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// privateKeyAddress return ethereum address of key
+func privateKeyAddress(key *secp256k1.PrivateKey) string {
+	pub := key.PubKey().SerializeUncompressed()
+	return "0x" + hex.EncodeToString(keccak256(pub[1:])[12:])
+}
+
+// encryptKey create keystore v3 JSON of key
+func encryptKey(key *secp256k1.PrivateKey, password string) ([]byte, error) {
+	salt := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	uuid := make([]byte, 16)
+	for _, buf := range [][]byte{salt, iv, uuid} {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+	}
+	derivedKey, err := scrypt.Key([]byte(password), salt, keystoreScryptN, keystoreScryptR, keystoreScryptP, keystoreKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	keyBytes := key.Serialize()
+	cipherText := make([]byte, len(keyBytes))
+	cipher.NewCTR(block, iv).XORKeyStream(cipherText, keyBytes)
+	mac := keccak256(append(append([]byte{}, derivedKey[16:32]...), cipherText...))
+
+	// version 4 uuid
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return json.Marshal(map[string]interface{}{
+		"address": strings.TrimPrefix(privateKeyAddress(key), "0x"),
+		"crypto": map[string]interface{}{
+			"cipher":       "aes-128-ctr",
+			"ciphertext":   hex.EncodeToString(cipherText),
+			"cipherparams": map[string]string{"iv": hex.EncodeToString(iv)},
+			"kdf":          "scrypt",
+			"kdfparams": map[string]interface{}{
+				"dklen": keystoreKeyLen,
+				"n":     keystoreScryptN,
+				"p":     keystoreScryptP,
+				"r":     keystoreScryptR,
+				"salt":  hex.EncodeToString(salt),
+			},
+			"mac": hex.EncodeToString(mac),
+		},
+		"id":      fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]),
+		"version": 3,
+	})
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,223 @@
+package deploy
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	testPrivateKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	testAddress    = "0x2c7536e3605d9c16a7a3d7b1898e529396a65c23"
+)
+
+func TestAccountAddress(t *testing.T) {
+	address, err := (&AccountModel{PrivateKey: testPrivateKey}).Address()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != testAddress {
+		t.Errorf("Expected address %s, got %s", testAddress, address)
+	}
+	key, err := (&AccountModel{PrivateKey: testPrivateKey}).privateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keystore, err := encryptKey(key, "pass123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err = (&AccountModel{Keystore: keystore, Password: "pass123"}).Address()
+	if err != nil || address != testAddress {
+		t.Errorf("Expected address %s of keystore, got %s, %v", testAddress, address, err)
+	}
+	pbkdf2Keystore := encryptKeyPBKDF2(t, key, "pass123")
+	address, err = (&AccountModel{Keystore: pbkdf2Keystore, Password: "pass123"}).Address()
+	if err != nil || address != testAddress {
+		t.Errorf("Expected address %s of pbkdf2 keystore, got %s, %v", testAddress, address, err)
+	}
+
+	// address field of keystore is not trusted
+	var fields map[string]interface{}
+	if err := json.Unmarshal(keystore, &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields["address"] = "980957073687abbfc85609ecd7c118d2b7506a17"
+	forged, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&AccountModel{Keystore: forged, Password: "pass123"}).Validate(); err != nil {
+		t.Errorf("Format of forged keystore is valid: %s", err)
+	}
+
+	// kdf params which take too much memory or time are rejected before derivation of key
+	for _, params := range []map[string]interface{}{
+		{"p": 1 << 29},
+		{"dklen": 1 << 40},
+		{"dklen": 64},
+		{"dklen": 16},
+		{"n": 3000},
+		{"n": 1 << 20, "r": 8},
+		{"r": 1 << 30},
+		{"n": 1 << 12, "r": 8, "p": 1 << 8},
+		{"p": 0},
+	} {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(keystore, &fields); err != nil {
+			t.Fatal(err)
+		}
+		kdfParams := fields["crypto"].(map[string]interface{})["kdfparams"].(map[string]interface{})
+		for name, val := range params {
+			kdfParams[name] = val
+		}
+		data, err := json.Marshal(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := (&AccountModel{Keystore: data, Password: "pass123"}).Validate(); err == nil {
+			t.Errorf("Keystore with kdf params %v must be invalid", params)
+		}
+	}
+
+	invalid := []*AccountModel{
+		{},
+		{PrivateKey: "0x01"},
+		{PrivateKey: "0x" + "00000000000000000000000000000000" + "00000000000000000000000000000000"},
+		{Keystore: json.RawMessage(`{"version":3}`)},
+		{Keystore: json.RawMessage(`{"address":"2c7536e3605d9c16a7a3d7b1898e529396a65c23"}`), PrivateKey: testPrivateKey},
+		{Keystore: keystore, Password: "wrong"},
+		{Keystore: forged, Password: "pass123"},
+	}
+	for _, account := range invalid {
+		if _, err := account.Address(); err == nil {
+			t.Errorf("Account %+v must be invalid", account)
+		}
+	}
+}
+
+// encryptKeyPBKDF2 create keystore v3 JSON of key with pbkdf2 and without address
+func encryptKeyPBKDF2(t *testing.T, key *secp256k1.PrivateKey, password string) []byte {
+	salt, iv := make([]byte, 32), make([]byte, aes.BlockSize)
+	derivedKey, err := pbkdf2.Key(sha256.New, password, salt, 1024, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes := key.Serialize()
+	cipherText := make([]byte, len(keyBytes))
+	cipher.NewCTR(block, iv).XORKeyStream(cipherText, keyBytes)
+	mac := keccak256(append(append([]byte{}, derivedKey[16:32]...), cipherText...))
+	data, err := json.Marshal(map[string]interface{}{
+		"crypto": map[string]interface{}{
+			"cipher":       "aes-128-ctr",
+			"ciphertext":   hex.EncodeToString(cipherText),
+			"cipherparams": map[string]string{"iv": hex.EncodeToString(iv)},
+			"kdf":          "pbkdf2",
+			"kdfparams":    map[string]interface{}{"c": 1024, "dklen": 32, "prf": "hmac-sha256", "salt": hex.EncodeToString(salt)},
+			"mac":          hex.EncodeToString(mac),
+		},
+		"version": 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestProvisionAccount(t *testing.T) {
+	account, err := provisionAccount(&AccountModel{PrivateKey: testPrivateKey, Password: "pass123"}, testAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := account.EnvVars()
+	if env[EnvEthFrom] != testAddress {
+		t.Errorf("Unexpected %s: %s", EnvEthFrom, env[EnvEthFrom])
+	}
+	fi, err := os.Stat(account.Dir)
+	if err != nil || fi.Mode().Perm() != 0700 {
+		t.Errorf("Dir of account must be accessible only by owner: %v, %v", fi, err)
+	}
+	password, err := ioutil.ReadFile(env[EnvEthPassword])
+	if err != nil || string(password) != "pass123" {
+		t.Errorf("Unexpected password file: %s, %v", password, err)
+	}
+	files, err := ioutil.ReadDir(env[EnvEthKeystore])
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Keystore dir must contain one file: %v, %v", files, err)
+	}
+	if files[0].Mode().Perm() != 0600 {
+		t.Errorf("Keystore file must be accessible only by owner: %v", files[0].Mode())
+	}
+	keystore, err := ioutil.ReadFile(filepath.Join(env[EnvEthKeystore], files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := decryptKeystore(t, keystore, "pass123"); hex.EncodeToString(key) != testPrivateKey[2:] {
+		t.Errorf("Decrypted key doesn't match: %x", key)
+	}
+
+	account.Shred(nil)
+	if _, err := os.Stat(account.Dir); !os.IsNotExist(err) {
+		t.Errorf("Dir of account must be removed: %v", err)
+	}
+}
+
+// decryptKeystore decrypt keystore v3 with scrypt
+func decryptKeystore(t *testing.T, data []byte, password string) []byte {
+	var keystore struct {
+		Crypto struct {
+			CipherText   string `json:"ciphertext"`
+			CipherParams struct {
+				IV string `json:"iv"`
+			} `json:"cipherparams"`
+			KDFParams struct {
+				DKLen int    `json:"dklen"`
+				N     int    `json:"n"`
+				P     int    `json:"p"`
+				R     int    `json:"r"`
+				Salt  string `json:"salt"`
+			} `json:"kdfparams"`
+			MAC string `json:"mac"`
+		} `json:"crypto"`
+	}
+	if err := json.Unmarshal(data, &keystore); err != nil {
+		t.Fatal(err)
+	}
+	params := keystore.Crypto.KDFParams
+	salt, _ := hex.DecodeString(params.Salt)
+	derivedKey, err := scrypt.Key([]byte(password), salt, params.N, params.R, params.P, params.DKLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipherText, _ := hex.DecodeString(keystore.Crypto.CipherText)
+	mac := keccak256(append(append([]byte{}, derivedKey[16:32]...), cipherText...))
+	if hex.EncodeToString(mac) != keystore.Crypto.MAC {
+		t.Fatal("MAC of keystore doesn't match")
+	}
+	iv, _ := hex.DecodeString(keystore.Crypto.CipherParams.IV)
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := make([]byte, len(cipherText))
+	cipher.NewCTR(block, iv).XORKeyStream(key, cipherText)
+	if bytes.Equal(key, cipherText) {
+		t.Fatal("Key is not encrypted")
+	}
+	return key
+}
@@ -1,7 +1,9 @@
 package deploy
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -11,6 +13,21 @@ type Config struct {
 	DeploymentSubPath string
 	ResultSubPath     string
 	RunUpdateOnStart  string
+	// Executor is used for scenarios if manifest has no executor
+	Executor      string
+	DockerImage   string
+	DockerNetwork string
+	// NixStopGraceSec, ShellStopGraceSec and DockerStopGraceSec are times between SIGTERM and SIGKILL
+	// on cancel of scenario for each executor
+	NixStopGraceSec    int
+	ShellStopGraceSec  int
+	DockerStopGraceSec int
+	// ResultCacheDir is dir of result cache, cache is disabled if it is empty
+	ResultCacheDir string
+	// ResultCacheIgnoreEnv are env vars which are not part of cache key
+	ResultCacheIgnoreEnv []string
+	// ResultCacheMaxEntries is limit of count of cached results, least recently used are removed, 0 - no limit
+	ResultCacheMaxEntries int
 }
 
 // Decode for envconfig
@@ -33,6 +50,40 @@ func (c *Config) Decode(data string) error {
 			c.ResultSubPath = paramArr[1]
 		case "runUpdateOnStart":
 			c.RunUpdateOnStart = paramArr[1]
+		case "executor":
+			c.Executor = paramArr[1]
+		case "dockerImage":
+			c.DockerImage = paramArr[1]
+		case "dockerNetwork":
+			c.DockerNetwork = paramArr[1]
+		case "nixStopGraceSec":
+			v, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.NixStopGraceSec = v
+		case "shellStopGraceSec":
+			v, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.ShellStopGraceSec = v
+		case "dockerStopGraceSec":
+			v, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.DockerStopGraceSec = v
+		case "resultCacheDir":
+			c.ResultCacheDir = paramArr[1]
+		case "resultCacheIgnoreEnv":
+			c.ResultCacheIgnoreEnv = parseEnvList(paramArr[1])
+		case "resultCacheMaxEntries":
+			v, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.ResultCacheMaxEntries = v
 		default:
 			return fmt.Errorf("unknown param '%s' for part of Deploy env", paramArr[0])
 		}
@@ -41,6 +92,26 @@ func (c *Config) Decode(data string) error {
 	return nil
 }
 
+// Validate cfg after load
+func (c *Config) Validate() error {
+	switch c.Executor {
+	case ExecutorNix, ExecutorShell:
+	case ExecutorDocker:
+		if c.DockerImage == "" {
+			return errors.New("dockerImage is required for docker executor")
+		}
+	default:
+		return fmt.Errorf("unknown executor '%s'", c.Executor)
+	}
+	if c.NixStopGraceSec < 1 || c.ShellStopGraceSec < 1 || c.DockerStopGraceSec < 1 {
+		return errors.New("stop grace periods of executors should be positive")
+	}
+	if c.ResultCacheMaxEntries < 0 {
+		return errors.New("resultCacheMaxEntries can't be negative")
+	}
+	return nil
+}
+
 // GetDefaultConfig return default config for local env
 func GetDefaultConfig() Config {
 	return Config{
@@ -48,5 +119,13 @@ func GetDefaultConfig() Config {
 		DeploymentSubPath: "./",
 		ResultSubPath:     "out/addresses.json",
 		RunUpdateOnStart:  "ifNotExists",
+		Executor:          ExecutorNix,
+		NixStopGraceSec:   10,
+		ShellStopGraceSec: 10,
+		// docker needs time to stop container and its children
+		DockerStopGraceSec: 30,
+		// url of chain and paths of account files differ for identical snapshots of chain
+		ResultCacheIgnoreEnv:  []string{"ETH_RPC_URL", EnvEthKeystore, EnvEthPassword},
+		ResultCacheMaxEntries: 1000,
 	}
 }
@@ -1,8 +1,10 @@
 package deploy
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -29,6 +31,7 @@ type StorageInterface interface {
 	SetRun(run bool) error
 	GetRun() bool
 	SetUpdatedAtNow() error
+	HasData() bool
 }
 
 // Component is main module of deploy
@@ -37,15 +40,17 @@ type Component struct {
 	githubClient   *github.Client
 	stepNameRegexp *regexp.Regexp
 	storage        StorageInterface
+	executors      Executors
 }
 
-// New init component
-func New(cfg Config, githubClient *github.Client, storage StorageInterface) *Component {
+// New init component, executors are shared with Deployer
+func New(cfg Config, githubClient *github.Client, storage StorageInterface, executors Executors) *Component {
 	return &Component{
 		cfg:            cfg,
 		githubClient:   githubClient,
 		stepNameRegexp: regexp.MustCompile(`^step-(\d+)\.json$`),
 		storage:        storage,
+		executors:      executors,
 	}
 }
 
@@ -103,6 +108,10 @@ func (c *Component) FirstUpdate(log *logrus.Entry) error {
 	log.Info(c.cfg.RunUpdateOnStart)
 	switch c.cfg.RunUpdateOnStart {
 	case "disable":
+		if c.storage.HasData() {
+			log.Info("Info loaded from storage, skip collecting")
+			return nil
+		}
 		return c.CollectInfo(log)
 	case "enable":
 		// first load source
@@ -124,6 +133,10 @@ func (c *Component) FirstUpdate(log *logrus.Entry) error {
 		}
 		log.Infof("Deployment dir exists: %+v", empty)
 		if !empty {
+			if c.storage.HasData() {
+				log.Info("Info loaded from storage, skip collecting")
+				return nil
+			}
 			return c.CollectInfo(log)
 		}
 		// first load source
@@ -227,9 +240,15 @@ func (c *Component) UpdateSource(log *logrus.Entry) error {
 	return c.CollectInfo(log)
 }
 
-// RunScenario run step command
-// TODO run with ctx, and ability for stop
-func (c *Component) RunScenario(log *logrus.Entry, scenarioNr int, envVars map[string]string) *ResultErrorModel {
+// RunScenario run step command, command is killed when ctx is cancelled,
+// output of command is copied to stdout and stderr writers if they are set
+func (c *Component) RunScenario(
+	ctx context.Context,
+	log *logrus.Entry,
+	scenarioNr int,
+	envVars map[string]string,
+	stdout, stderr io.Writer,
+) *ResultErrorModel {
 	if err := c.storage.SetRun(true); err != nil {
 		return NewResultErrorModelFromErr(err)
 	}
@@ -243,12 +262,22 @@ func (c *Component) RunScenario(log *logrus.Entry, scenarioNr int, envVars map[s
 	if err != nil {
 		return NewResultErrorModelFromErr(err)
 	}
-	cmd := command.New(exec.Command("nix", "run",
-		"-f", ".", // Use Nix expression from current working directory for now
-		"-c", scenario.RunCommand)).
-		WithDir(c.githubClient.GetRepoPath()).
-		WithEnvVarsMap(envVars)
-	if cmdErr := cmd.Run(); cmdErr != nil {
+	manifest, err := c.storage.GetManifest(log)
+	if err != nil {
+		return NewResultErrorModelFromErr(err)
+	}
+	executor, err := selectExecutor(c.executors, c.cfg.Executor, manifest)
+	if err != nil {
+		return NewResultErrorModelFromErr(err)
+	}
+	// legacy scenarios are run inside of repo
+	repoPath := c.githubClient.GetRepoPath()
+	cmd, err := executor.Command(log, repoPath, repoPath, scenario.RunCommand, envVars, nil)
+	if err != nil {
+		return NewResultErrorModelFromErr(err)
+	}
+	cmd.WithOutput(stdout, stderr)
+	if cmdErr := cmd.RunContext(ctx); cmdErr != nil {
 		log.WithError(cmdErr.Message).Error("Cmd running error")
 		log.Debugf("Cmd running error trace: %s", string(cmdErr.Stderr))
 		return NewResultErrorModelFromCmd(cmdErr)
@@ -303,31 +332,39 @@ func ReadManifestFile(readFile ReadFile, repoPath string) (*Manifest, error) {
 	if err := json.Unmarshal(data, &model); err != nil {
 		return nil, err
 	}
+	if model.Version == 0 {
+		model.Version = ManifestVersion1
+	}
+	if model.Version != ManifestVersion1 && model.Version != ManifestVersion2 {
+		return nil, fmt.Errorf("unsupported manifest version: %d", model.Version)
+	}
 
 	scenarios := make([]Scenario, len(model.Scenarios))
 	for i, scenario := range model.Scenarios {
-		configPath := filepath.Join(repoPath, scenario.ConfigPath)
-		config, err := readFile(configPath)
-		if err != nil {
-			return nil, err
-		}
 		var configModel json.RawMessage
-		if err := json.Unmarshal(config, &configModel); err != nil {
-			return nil, err
-		}
-		scenarios[i] = Scenario{
-			scenario.Name,
-			scenario.Description,
-			scenario.RunCommand,
-			configModel,
-			scenario.OutPath,
+		// config is required only for v1
+		if scenario.ConfigPath != "" || model.Version == ManifestVersion1 {
+			configPath := filepath.Join(repoPath, scenario.ConfigPath)
+			config, err := readFile(configPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(config, &configModel); err != nil {
+				return nil, err
+			}
 		}
+		scenarios[i] = newScenario(model.Version, i, scenario, configModel)
 	}
 
 	manifest := Manifest{
-		model.Name,
-		model.Description,
-		scenarios,
+		Version:     model.Version,
+		Name:        model.Name,
+		Description: model.Description,
+		Executor:    model.Executor,
+		Scenarios:   scenarios,
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
 	}
 	return &manifest, nil
 }
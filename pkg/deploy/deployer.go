@@ -0,0 +1,348 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Deployment struct {
+	Commit        git.Commit
+	ScenarioID    string // used if set, otherwise scenario is selected by ScenarioNr
+	ScenarioNr    int
+	DeployEnvVars map[string]string
+	// Account is provisioned for deployment if set, optional
+	Account *AccountModel
+	// SnapshotID is id of chain snapshot which deployment runs against, it is part of cache key
+	SnapshotID string
+	// NoCache skip result cache
+	NoCache bool
+	// Stdout and Stderr get output of deployment command while it runs, optional
+	Stdout io.Writer
+	Stderr io.Writer
+	// OnStage is called when stage of deployment (fetch, manifest, run, output) is finished, optional
+	OnStage func(stage string)
+}
+
+// Deployer runs deployments with executor from manifest or default from config
+type Deployer struct {
+	cfg       Config
+	resolver  git.Resolver
+	secrets   secret.Config
+	executors Executors
+	// cache is nil if it is disabled
+	cache *ResultCache
+}
+
+// NewDeployer init deployer, result cache is enabled if dir of it is configured,
+// executors are shared with Component
+func NewDeployer(cfg Config, resolver git.Resolver, secrets secret.Config, executors Executors) (*Deployer, error) {
+	d := &Deployer{
+		cfg:       cfg,
+		resolver:  resolver,
+		secrets:   secrets,
+		executors: executors,
+	}
+	if cfg.ResultCacheDir != "" {
+		cache, err := NewResultCache(cfg.ResultCacheDir, cfg.ResultCacheIgnoreEnv, cfg.ResultCacheMaxEntries)
+		if err != nil {
+			return nil, err
+		}
+		d.cache = cache
+	}
+	return d, nil
+}
+
+// GetManifest fetch repo and read manifest from it
+func (d *Deployer) GetManifest(log *logrus.Entry, commit git.Commit) (*Manifest, error) {
+	repo, err := d.resolver.GetRepo(log, commit)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Release()
+	return ReadManifestFile(ioutil.ReadFile, repo.Path)
+}
+
+// Deploy fetch repo and run scenario of deployment with executor,
+// running command is stopped when ctx is cancelled.
+// Both result and error contain info about the run.
+func (d *Deployer) Deploy(ctx context.Context, log *logrus.Entry, deployment Deployment) (*ResultModel, *ResultErrorModel) {
+	start := time.Now()
+	scenario := deployment.ScenarioID
+	if scenario == "" {
+		scenario = strconv.Itoa(deployment.ScenarioNr)
+	}
+	// URL can have credentials, so only host and path of repo are used in metrics and spans
+	repoName := git.RepoName(deployment.Commit.URL)
+	metrics.DeploymentStarted(repoName, scenario)
+	ctx, span := tracing.Start(ctx, "deploy", trace.WithAttributes(
+		attribute.String("repo.name", repoName),
+		attribute.String("repo.ref", deployment.Commit.Ref),
+		attribute.String("scenario", scenario),
+	))
+	defer span.End()
+	res, resErr := d.deploy(ctx, log, deployment)
+	if resErr != nil {
+		span.SetStatus(codes.Error, resErr.Msg)
+	}
+	status := metrics.StatusSucceeded
+	switch {
+	case ctx.Err() != nil:
+		status = metrics.StatusCancelled
+	case resErr != nil:
+		status = metrics.StatusFailed
+	case res.Cached:
+		status = metrics.StatusCached
+	}
+	metrics.DeploymentFinished(repoName, scenario, status, start)
+	return res, resErr
+}
+
+func (d *Deployer) deploy(ctx context.Context, log *logrus.Entry, deployment Deployment) (*ResultModel, *ResultErrorModel) {
+	start := time.Now()
+	info := NewRunInfoModel(deployment.DeployEnvVars)
+	fail := func(err error) *ResultErrorModel {
+		info.DurationMs = int64(time.Since(start) / time.Millisecond)
+		return NewResultErrorModelFromErr(err).WithInfo(info)
+	}
+	var stageStart time.Time
+	finishStage := func(stage string) {
+		info.AddTiming(stage, stageStart)
+		tracing.RecordStage(ctx, "deploy."+stage, stageStart)
+		if deployment.OnStage != nil {
+			deployment.OnStage(stage)
+		}
+	}
+
+	log.Debugf("Starting deployment with: %+v", deployment.Commit)
+	if err := ctx.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	log.Debugf("Fetching GIT repo: %+v", deployment.Commit)
+	stageStart = time.Now()
+	repo, err := d.resolver.GetRepo(log, deployment.Commit)
+	if err != nil {
+		log.WithError(err).Error("Couldn't get repository")
+		return nil, fail(err)
+	}
+	defer repo.Release()
+	info.Rev = repo.Rev
+	finishStage("fetch")
+
+	log.Debugf("Reading manifest file from: %s", repo.Path)
+	stageStart = time.Now()
+	manifest, err := ReadManifestFile(ioutil.ReadFile, repo.Path)
+	if err != nil {
+		log.WithError(err).Error("Couldn't read deploy manifest")
+		return nil, fail(err)
+	}
+	scenario, err := findScenario(manifest, deployment)
+	if err != nil {
+		log.WithError(err).Error("Couldn't find scenario")
+		return nil, fail(err)
+	}
+	envVars, redactor, err := d.prepareEnvVars(scenario, deployment.DeployEnvVars, deployment.Account)
+	if err != nil {
+		log.WithError(err).Error("Couldn't prepare environment variables")
+		return nil, fail(err)
+	}
+	var address string
+	if deployment.Account != nil {
+		// keystore is decrypted once, files of account are created only if result is not cached
+		if address, err = deployment.Account.Address(); err != nil {
+			log.WithError(err).Error("Couldn't get address of account")
+			return nil, fail(err)
+		}
+		envVars[EnvEthFrom] = address
+		delete(envVars, EnvEthKeystore)
+		delete(envVars, EnvEthPassword)
+	}
+	info.Scenario = scenario.Name
+	executor, err := selectExecutor(d.executors, d.cfg.Executor, manifest)
+	if err != nil {
+		log.WithError(err).Error("Couldn't select executor")
+		return nil, fail(err)
+	}
+	finishStage("manifest")
+	if err := ctx.Err(); err != nil {
+		return nil, fail(err)
+	}
+
+	// key doesn't contain paths of account files, they are different for each deployment
+	cacheKey := ""
+	if d.cache != nil && !deployment.NoCache {
+		cacheKey = d.cache.Key(repo.Rev, scenario.ID, envVars, deployment.SnapshotID)
+		res, err := d.cache.Get(cacheKey)
+		if err != nil {
+			log.WithError(err).Warn("Couldn't read cached result")
+		}
+		if res != nil {
+			log.Infof("Result of scenario '%s' at %s is found in cache", scenario.ID, repo.Rev)
+			return res, nil
+		}
+	}
+
+	mounts := make([]string, 0)
+	if deployment.Account != nil {
+		account, err := provisionAccount(deployment.Account, address)
+		if err != nil {
+			log.WithError(err).Error("Couldn't provision account")
+			return nil, fail(err)
+		}
+		defer account.Shred(log)
+		for name, val := range account.EnvVars() {
+			envVars[name] = val
+		}
+		mounts = append(mounts, account.Dir)
+		info.Account = account.Address
+	}
+	info.SetEnvVars(envVars)
+	// request is validated without fetch of repo, so parameters are checked here
+	if errs := validateParameters(scenario, envVars, redactor); len(errs) != 0 {
+		err := &ParametersError{Errs: errs}
+		log.WithError(err).Error("Invalid parameters of scenario")
+		return nil, fail(err)
+	}
+
+	workDir, err := ioutil.TempDir("", "deploy-worker-")
+	if err != nil {
+		log.WithError(err).Error("Couldn't create working directory")
+		return nil, fail(err)
+	}
+	defer os.RemoveAll(workDir)
+
+	log.Debugf("Working directory: %s", workDir)
+	log.Debugf("Environment variables: %v", info.EnvVars)
+	log.Debugf("Running deployment command: %s", scenario.RunCommand)
+
+	cmd, err := executor.Command(log, repo.Path, workDir, scenario.RunCommand, envVars, mounts)
+	if err != nil {
+		log.WithError(err).Error("Couldn't prepare deployment command")
+		return nil, fail(err)
+	}
+	stdout := redactor.Writer(deployment.Stdout)
+	stderr := redactor.Writer(deployment.Stderr)
+	cmd.WithOutput(stdout, stderr)
+	stageStart = time.Now()
+	cmdErr := cmd.RunContext(ctx)
+	for _, w := range []*secret.Writer{stdout, stderr} {
+		if err := w.Flush(); err != nil {
+			log.WithError(err).Warn("Couldn't flush output of deployment")
+		}
+	}
+	finishStage("run")
+	info.StdoutSize = cmd.Stdout.Len()
+	info.StderrSize = cmd.Stderr.Len()
+	info.Files = listFiles(log, workDir, filepath.Dir(scenario.OutPath))
+	if cmdErr != nil {
+		info.ExitCode = cmdErr.ExitCode
+		if err := ctx.Err(); err != nil {
+			log.WithError(err).Info("Deployment command stopped")
+			return nil, fail(err)
+		}
+		cmdStderr := redactor.RedactBytes(cmdErr.Stderr)
+		log.WithError(cmdErr.Message).
+			Errorf("Error when running command: %s: %+v\nSTDERR: %s",
+				strings.Join(cmd.Args, " "),
+				cmdErr.Message,
+				string(cmdStderr))
+		return nil, fail(cmdErr.Message).WithStderr(cmdStderr)
+	}
+	info.ExitCode = 0
+
+	log.Debugf("Finished deploy command")
+
+	log.Debugf("Reading deploy outputs from: %s", workDir)
+	stageStart = time.Now()
+	outputs := make(map[string]json.RawMessage, len(scenario.Outputs))
+	for _, output := range scenario.Outputs {
+		res, err := readOutput(filepath.Join(workDir, output.Path))
+		if err != nil {
+			return nil, fail(err)
+		}
+		outputs[output.Name] = redactor.RedactBytes(res)
+	}
+	finishStage("output")
+	var res json.RawMessage
+	if len(scenario.Outputs) != 0 {
+		res = outputs[scenario.Outputs[0].Name]
+	}
+	info.DurationMs = int64(time.Since(start) / time.Millisecond)
+
+	result := &ResultModel{
+		LastUpdated: time.Now(),
+		Data:        res,
+		Outputs:     outputs,
+		Info:        info,
+	}
+	if cacheKey != "" {
+		if err := d.cache.Put(cacheKey, result); err != nil {
+			log.WithError(err).Warn("Couldn't save result to cache")
+		}
+	}
+	return result, nil
+}
+
+// findScenario return scenario of deployment by id or by index
+func findScenario(manifest *Manifest, deployment Deployment) (*Scenario, error) {
+	if deployment.ScenarioID != "" {
+		return manifest.FindScenario(deployment.ScenarioID)
+	}
+	if deployment.ScenarioNr < 0 || deployment.ScenarioNr >= len(manifest.Scenarios) {
+		return nil, fmt.Errorf("Scenario number %d not available", deployment.ScenarioNr)
+	}
+	return &manifest.Scenarios[deployment.ScenarioNr], nil
+}
+
+// readOutput read output file, not json output is kept as string
+func readOutput(path string) (json.RawMessage, error) {
+	res, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if json.Valid(res) {
+		return res, nil
+	}
+	return json.Marshal(string(res))
+}
+
+// listFiles return all files in sub dir of working dir
+func listFiles(log *logrus.Entry, workDir, subDir string) []FileModel {
+	files := make([]FileModel, 0)
+	root := filepath.Join(workDir, subDir)
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(workDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, FileModel{Path: relPath, Size: fi.Size()})
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		log.WithError(err).Warn("Couldn't list output files")
+	}
+	return files
+}
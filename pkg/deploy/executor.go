@@ -0,0 +1,170 @@
+package deploy
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/command"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ExecutorNix    = "nix"
+	ExecutorShell  = "shell"
+	ExecutorDocker = "docker"
+)
+
+// Executor prepares command which runs scenario of checked out repo,
+// scenario output is expected in workDir,
+// mounts are dirs of host which command uses with the same path, e.g. dir of account
+type Executor interface {
+	Command(
+		log *logrus.Entry,
+		repoPath, workDir, run string,
+		envVars map[string]string,
+		mounts []string,
+	) (*command.Command, error)
+}
+
+// Executors are available executors by name, they are shared by Component and Deployer
+type Executors map[string]Executor
+
+// NewExecutors init all available executors by name with grace periods of stop from config
+func NewExecutors(cfg Config) Executors {
+	return Executors{
+		ExecutorNix:   &NixExecutor{StopGrace: secToDuration(cfg.NixStopGraceSec)},
+		ExecutorShell: &ShellExecutor{StopGrace: secToDuration(cfg.ShellStopGraceSec)},
+		ExecutorDocker: &DockerExecutor{
+			Image:     cfg.DockerImage,
+			Network:   cfg.DockerNetwork,
+			StopGrace: secToDuration(cfg.DockerStopGraceSec),
+		},
+	}
+}
+
+func secToDuration(sec int) time.Duration {
+	return time.Duration(sec) * time.Second
+}
+
+// selectExecutor return executor from manifest or default one if manifest has no executor
+func selectExecutor(executors Executors, defaultName string, manifest *Manifest) (Executor, error) {
+	name := defaultName
+	if manifest != nil && manifest.Executor != "" {
+		name = manifest.Executor
+	}
+	executor, ok := executors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown executor: %s", name)
+	}
+	return executor, nil
+}
+
+// NixExecutor runs scenario in environment of default.nix from repo,
+// StopGrace is time between SIGTERM and SIGKILL on cancel, default is used if it is zero
+type NixExecutor struct {
+	StopGrace time.Duration
+}
+
+func (e *NixExecutor) Command(
+	log *logrus.Entry,
+	repoPath, workDir, run string,
+	envVars map[string]string,
+	mounts []string,
+) (*command.Command, error) {
+	args := []string{
+		"run",
+		"-f", repoPath,
+		"-c",
+	}
+	args = append(args, strings.Split(run, " ")...)
+	return command.New(exec.Command("nix", args...)).
+		WithDir(workDir).
+		WithEnvVarsMap(envVars).
+		WithStopGracePeriod(e.StopGrace), nil
+}
+
+// ShellExecutor runs scenario with sh in writable copy of repo,
+// StopGrace is time between SIGTERM and SIGKILL on cancel, default is used if it is zero
+type ShellExecutor struct {
+	StopGrace time.Duration
+}
+
+func (e *ShellExecutor) Command(
+	log *logrus.Entry,
+	repoPath, workDir, run string,
+	envVars map[string]string,
+	mounts []string,
+) (*command.Command, error) {
+	if err := copyRepo(log, repoPath, workDir); err != nil {
+		return nil, err
+	}
+	return command.New(exec.Command("sh", "-c", run)).
+		WithDir(workDir).
+		WithEnvVarsMap(envVars).
+		WithStopGracePeriod(e.StopGrace), nil
+}
+
+// DockerExecutor runs scenario with sh inside of image, copy of repo is mounted as working dir,
+// StopGrace is time between SIGTERM and SIGKILL on cancel, default is used if it is zero
+type DockerExecutor struct {
+	Image     string
+	Network   string
+	StopGrace time.Duration
+}
+
+func (e *DockerExecutor) Command(
+	log *logrus.Entry,
+	repoPath, workDir, run string,
+	envVars map[string]string,
+	mounts []string,
+) (*command.Command, error) {
+	if e.Image == "" {
+		return nil, fmt.Errorf("image for docker executor is not configured")
+	}
+	if err := copyRepo(log, repoPath, workDir); err != nil {
+		return nil, err
+	}
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{
+		"run", "--rm", "--init",
+		"-v", absWorkDir + ":/deployment",
+		"-w", "/deployment",
+	}
+	for _, mount := range mounts {
+		args = append(args, "-v", mount+":"+mount+":ro")
+	}
+	if e.Network != "" {
+		args = append(args, "--network", e.Network)
+	}
+	// only names are in args, docker takes values from own env, so they are not visible in process list
+	for name := range envVars {
+		args = append(args, "-e", name)
+	}
+	args = append(args, e.Image, "sh", "-c", run)
+	return command.New(exec.Command("docker", args...)).
+		WithDir(workDir).
+		WithEnvVarsMap(envVars).
+		WithStopGracePeriod(e.StopGrace), nil
+}
+
+// copyRepo copy content of repo to working dir and make it writable,
+// files from nix store are read only
+func copyRepo(log *logrus.Entry, repoPath, workDir string) error {
+	if filepath.Clean(repoPath) == filepath.Clean(workDir) {
+		return nil
+	}
+	log.Debugf("Copy repo %s to %s", repoPath, workDir)
+	if cmdErr := command.New(exec.Command("cp", "-r", repoPath+"/.", workDir)).Run(); cmdErr != nil {
+		return cmdErr
+	}
+	if cmdErr := command.New(exec.Command("chmod", "-R", "u+w", workDir)).Run(); cmdErr != nil {
+		return cmdErr
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSelectExecutor(t *testing.T) {
+	executors := NewExecutors(Config{DockerImage: "alpine"})
+
+	executor, err := selectExecutor(executors, ExecutorNix, &Manifest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := executor.(*NixExecutor); !ok {
+		t.Errorf("Default executor is expected for manifest without executor, got %T", executor)
+	}
+
+	executor, err = selectExecutor(executors, ExecutorNix, &Manifest{Executor: ExecutorShell})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := executor.(*ShellExecutor); !ok {
+		t.Errorf("Executor from manifest is expected, got %T", executor)
+	}
+
+	if _, err := selectExecutor(executors, ExecutorNix, &Manifest{Executor: "unknown"}); err == nil {
+		t.Error("Error is expected for unknown executor")
+	}
+}
+
+func TestNewExecutorsStopGrace(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.NixStopGraceSec = 1
+	cfg.ShellStopGraceSec = 2
+	cfg.DockerStopGraceSec = 3
+	executors := NewExecutors(cfg)
+	if grace := executors[ExecutorNix].(*NixExecutor).StopGrace; grace != time.Second {
+		t.Errorf("Unexpected grace period of nix executor: %s", grace)
+	}
+	if grace := executors[ExecutorShell].(*ShellExecutor).StopGrace; grace != 2*time.Second {
+		t.Errorf("Unexpected grace period of shell executor: %s", grace)
+	}
+	if grace := executors[ExecutorDocker].(*DockerExecutor).StopGrace; grace != 3*time.Second {
+		t.Errorf("Unexpected grace period of docker executor: %s", grace)
+	}
+}
+
+func TestShellExecutor(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	repoPath, err := ioutil.TempDir("", "executor-repo-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoPath)
+	workDir, err := ioutil.TempDir("", "executor-work-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workDir)
+	script := "mkdir -p out && echo \"{\\\"value\\\": \\\"$VALUE\\\"}\" > out/result.json"
+	if err := ioutil.WriteFile(filepath.Join(repoPath, "deploy.sh"), []byte(script), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd, err := (&ShellExecutor{}).Command(log, repoPath, workDir, "sh deploy.sh", map[string]string{"VALUE": "test"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmdErr := cmd.Run(); cmdErr != nil {
+		t.Fatal(cmdErr)
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(workDir, "out", "result.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(out)) != `{"value": "test"}` {
+		t.Errorf("Unexpected output of scenario: %s", out)
+	}
+}
+
+func TestDockerExecutorArgs(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	workDir, err := ioutil.TempDir("", "executor-work-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workDir)
+
+	executor := &DockerExecutor{Image: "alpine", Network: "testchain"}
+	cmd, err := executor.Command(log, workDir, workDir, "make deploy", map[string]string{"SECRET": "value"}, []string{"/tmp/account"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Join(cmd.Args, " ")
+	expected := "docker run --rm --init -v " + workDir + ":/deployment -w /deployment -v /tmp/account:/tmp/account:ro --network testchain -e SECRET alpine sh -c make deploy"
+	if args != expected {
+		t.Errorf("Unexpected docker args:\n%s\nexpected:\n%s", args, expected)
+	}
+	if strings.Contains(args, "value") {
+		t.Error("Value of env var must not be in docker args")
+	}
+
+	if _, err := (&DockerExecutor{}).Command(log, workDir, workDir, "make", nil, nil); err == nil {
+		t.Error("Error is expected without configured image")
+	}
+}
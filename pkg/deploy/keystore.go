@@ -0,0 +1,133 @@
+package deploy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/scrypt"
+)
+
+// limits of kdf params of keystore, memory of scrypt is 128*n*r*p bytes and time is proportional to n*r*p,
+// they allow standard keystore of geth and prevent requests which take too much memory or time
+const (
+	keystoreMaxScryptNRP    = 1 << 21
+	keystoreMaxPBKDF2Rounds = 1 << 20
+	// keystoreDKLen is length of derived key: aes-128 key and mac key
+	keystoreDKLen = 32
+)
+
+// keystoreModel is keystore v3 JSON, address is optional
+type keystoreModel struct {
+	Address string `json:"address"`
+	Crypto  struct {
+		Cipher       string `json:"cipher"`
+		CipherText   string `json:"ciphertext"`
+		CipherParams struct {
+			IV string `json:"iv"`
+		} `json:"cipherparams"`
+		KDF       string `json:"kdf"`
+		KDFParams struct {
+			DKLen int    `json:"dklen"`
+			Salt  string `json:"salt"`
+			// scrypt
+			N int `json:"n"`
+			R int `json:"r"`
+			P int `json:"p"`
+			// pbkdf2
+			C   int    `json:"c"`
+			PRF string `json:"prf"`
+		} `json:"kdfparams"`
+		MAC string `json:"mac"`
+	} `json:"crypto"`
+}
+
+// validate check that keystore is supported without expensive derivation of key
+func (k *keystoreModel) validate() error {
+	if k.Crypto.Cipher != "aes-128-ctr" {
+		return fmt.Errorf("keystore cipher '%s' is not supported", k.Crypto.Cipher)
+	}
+	params := k.Crypto.KDFParams
+	if params.DKLen != keystoreDKLen {
+		return fmt.Errorf("keystore dklen should be %d", keystoreDKLen)
+	}
+	switch k.Crypto.KDF {
+	case "scrypt":
+		if params.N <= 1 || params.N&(params.N-1) != 0 {
+			return fmt.Errorf("keystore scrypt n should be power of two")
+		}
+		// params are divided instead of multiplied, so check doesn't overflow
+		if params.R <= 0 || params.P <= 0 || params.N > keystoreMaxScryptNRP/params.R ||
+			params.P > keystoreMaxScryptNRP/(params.N*params.R) {
+			return fmt.Errorf("keystore scrypt params are out of range")
+		}
+	case "pbkdf2":
+		if params.PRF != "hmac-sha256" {
+			return fmt.Errorf("keystore prf '%s' is not supported", params.PRF)
+		}
+		if params.C <= 0 || params.C > keystoreMaxPBKDF2Rounds {
+			return fmt.Errorf("keystore pbkdf2 rounds are out of range")
+		}
+	default:
+		return fmt.Errorf("keystore kdf '%s' is not supported", k.Crypto.KDF)
+	}
+	return nil
+}
+
+// decrypt return private key of keystore, error is returned for wrong password
+func (k *keystoreModel) decrypt(password string) (*secp256k1.PrivateKey, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(k.Crypto.KDFParams.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("keystore salt is not hex")
+	}
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("keystore iv should be %d bytes in hex", aes.BlockSize)
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("keystore ciphertext is not hex")
+	}
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("keystore mac is not hex")
+	}
+
+	params := k.Crypto.KDFParams
+	var derivedKey []byte
+	if k.Crypto.KDF == "scrypt" {
+		derivedKey, err = scrypt.Key([]byte(password), salt, params.N, params.R, params.P, params.DKLen)
+	} else {
+		derivedKey, err = pbkdf2.Key(sha256.New, password, salt, params.C, params.DKLen)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't derive key of keystore: %s", err)
+	}
+	expectedMAC := keccak256(append(append([]byte{}, derivedKey[16:32]...), cipherText...))
+	if subtle.ConstantTimeCompare(mac, expectedMAC) != 1 {
+		return nil, fmt.Errorf("keystore can't be decrypted with password")
+	}
+
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	keyBytes := make([]byte, len(cipherText))
+	cipher.NewCTR(block, iv).XORKeyStream(keyBytes, cipherText)
+	var scalar secp256k1.ModNScalar
+	if len(keyBytes) != 32 {
+		return nil, fmt.Errorf("key of keystore should be 32 bytes")
+	}
+	if overflow := scalar.SetByteSlice(keyBytes); overflow || scalar.IsZero() {
+		return nil, fmt.Errorf("key of keystore is out of range")
+	}
+	return secp256k1.NewPrivateKey(&scalar), nil
+}
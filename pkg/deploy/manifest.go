@@ -0,0 +1,182 @@
+package deploy
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	ManifestVersion1 = 1
+	ManifestVersion2 = 2
+)
+
+// Types of scenario parameters
+const (
+	ParameterTypeString  = "string"
+	ParameterTypeAddress = "address"
+	ParameterTypeURL     = "url"
+	ParameterTypeInteger = "integer"
+	ParameterTypeBool    = "bool"
+)
+
+// DefaultOutputName is name of output which is declared with outPath
+const DefaultOutputName = "default"
+
+// newScenario convert model of any manifest version to scenario,
+// v1 scenarios get index as id and outPath as single output
+func newScenario(version, idx int, model ScenarioModel, config []byte) Scenario {
+	scenario := Scenario{
+		ID:          model.ID,
+		Name:        model.Name,
+		Description: model.Description,
+		RunCommand:  model.RunCommand,
+		Config:      config,
+		OutPath:     model.OutPath,
+		DependsOn:   model.DependsOn,
+		Parameters:  model.Parameters,
+		Outputs:     model.Outputs,
+	}
+	if version == ManifestVersion1 {
+		scenario.ID = strconv.Itoa(idx)
+		scenario.DependsOn = nil
+		scenario.Parameters = nil
+		scenario.Outputs = nil
+	}
+	if len(scenario.Outputs) == 0 && scenario.OutPath != "" {
+		scenario.Outputs = []OutputModel{{Name: DefaultOutputName, Path: scenario.OutPath}}
+	}
+	if scenario.OutPath == "" && len(scenario.Outputs) != 0 {
+		scenario.OutPath = scenario.Outputs[0].Path
+	}
+	if scenario.DependsOn == nil {
+		scenario.DependsOn = make([]string, 0)
+	}
+	if scenario.Parameters == nil {
+		scenario.Parameters = make([]ParameterModel, 0)
+	}
+	if scenario.Outputs == nil {
+		scenario.Outputs = make([]OutputModel, 0)
+	}
+	return scenario
+}
+
+// Validate check ids, dependencies, parameters and outputs of scenarios
+func (m *Manifest) Validate() error {
+	deps := make(map[string][]string, len(m.Scenarios))
+	ids := make([]string, 0, len(m.Scenarios))
+	for _, scenario := range m.Scenarios {
+		if scenario.ID == "" {
+			return fmt.Errorf("scenario '%s' has no id", scenario.Name)
+		}
+		if _, ok := deps[scenario.ID]; ok {
+			return fmt.Errorf("duplicated scenario id '%s'", scenario.ID)
+		}
+		deps[scenario.ID] = scenario.DependsOn
+		ids = append(ids, scenario.ID)
+
+		params := make(map[string]bool, len(scenario.Parameters))
+		for _, param := range scenario.Parameters {
+			if param.Name == "" {
+				return fmt.Errorf("scenario '%s' has parameter without name", scenario.ID)
+			}
+			if params[param.Name] {
+				return fmt.Errorf("scenario '%s' has duplicated parameter '%s'", scenario.ID, param.Name)
+			}
+			params[param.Name] = true
+			if !IsValidParameterType(param.Type) {
+				return fmt.Errorf("scenario '%s' has parameter '%s' with unknown type '%s'",
+					scenario.ID, param.Name, param.Type)
+			}
+		}
+
+		outputs := make(map[string]bool, len(scenario.Outputs))
+		for _, output := range scenario.Outputs {
+			if output.Name == "" || output.Path == "" {
+				return fmt.Errorf("scenario '%s' has output without name or path", scenario.ID)
+			}
+			if outputs[output.Name] {
+				return fmt.Errorf("scenario '%s' has duplicated output '%s'", scenario.ID, output.Name)
+			}
+			outputs[output.Name] = true
+		}
+	}
+	_, err := TopoSort(ids, deps)
+	return err
+}
+
+// FindScenario return scenario by id
+func (m *Manifest) FindScenario(id string) (*Scenario, error) {
+	for i := range m.Scenarios {
+		if m.Scenarios[i].ID == id {
+			return &m.Scenarios[i], nil
+		}
+	}
+	return nil, fmt.Errorf("scenario '%s' not available", id)
+}
+
+// WithDefaults return copy of env vars with default values of missing parameters
+func (s *Scenario) WithDefaults(envVars map[string]string) map[string]string {
+	res := make(map[string]string, len(envVars)+len(s.Parameters))
+	for _, param := range s.Parameters {
+		if param.Default != "" {
+			res[param.Name] = param.Default
+		}
+	}
+	for name, val := range envVars {
+		res[name] = val
+	}
+	return res
+}
+
+// IsValidParameterType check that type is known, empty type means string
+func IsValidParameterType(paramType string) bool {
+	switch paramType {
+	case "", ParameterTypeString, ParameterTypeAddress, ParameterTypeURL,
+		ParameterTypeInteger, ParameterTypeBool:
+		return true
+	}
+	return false
+}
+
+// TopoSort return ids in order where dependencies go before dependants,
+// order of independent ids is kept
+func TopoSort(ids []string, deps map[string][]string) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	known := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		known[id] = true
+	}
+	state := make(map[string]int, len(ids))
+	res := make([]string, 0, len(ids))
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		switch state[id] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", append(path, id))
+		}
+		state[id] = visiting
+		for _, dep := range deps[id] {
+			if !known[dep] {
+				return fmt.Errorf("'%s' depends on unknown '%s'", id, dep)
+			}
+			if err := visit(dep, append(path, id)); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+		res = append(res, id)
+		return nil
+	}
+	for _, id := range ids {
+		if err := visit(id, nil); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -76,14 +76,15 @@ func TestReadManifestFile(t *testing.T) {
 func TestNewStepListFromManifest(t *testing.T) {
 	// Setup
 	manifest := Manifest{
-		"TestManifest",
-		"A test manifest",
-		[]Scenario{
+		Name:        "TestManifest",
+		Description: "A test manifest",
+		Scenarios: []Scenario{
 			{
-				"TestScenario1!",
-				"A test scenario",
-				"true",
-				[]byte(
+				ID:          "0",
+				Name:        "TestScenario1!",
+				Description: "A test scenario",
+				RunCommand:  "true",
+				Config: []byte(
 					`{
 						"description": "Step 1",
 						"defaults": {},
@@ -92,13 +93,14 @@ func TestNewStepListFromManifest(t *testing.T) {
 						"pauseDelay": "0"
 					}`,
 				),
-				"out/addresses.json",
+				OutPath: "out/addresses.json",
 			},
 			{
-				"TestScenario2!",
-				"Another test scenario",
-				"true",
-				[]byte(
+				ID:          "1",
+				Name:        "TestScenario2!",
+				Description: "Another test scenario",
+				RunCommand:  "true",
+				Config: []byte(
 					`{
 						"description": "Step 2",
 						"defaults": {},
@@ -107,7 +109,7 @@ func TestNewStepListFromManifest(t *testing.T) {
 						"pauseDelay": "0"
 					}`,
 				),
-				"out/addresses.json",
+				OutPath: "out/addresses.json",
 			},
 		},
 	}
@@ -145,3 +147,106 @@ func TestNewStepListFromManifest(t *testing.T) {
 		t.Errorf("Scenario nr 2's config file content doesn't match step 2's defaults: %s", step2.Defaults)
 	}
 }
+
+func TestReadManifestFileV2(t *testing.T) {
+	// Mock
+	mockFileReader := func(path string) ([]byte, error) {
+		return []byte(`{
+			"version": 2,
+			"name": "TestManifest",
+			"scenarios": [
+				{
+					"id": "oracles",
+					"name": "Oracles",
+					"run": "deploy-oracles",
+					"dependsOn": ["core"],
+					"parameters": [
+						{"name": "ETH_FROM", "type": "address", "required": true},
+						{"name": "ETH_GAS", "type": "integer", "default": "7000000"}
+					],
+					"outputs": [
+						{"name": "addresses", "path": "out/oracles.json"},
+						{"name": "config", "path": "out/config.json"}
+					]
+				},
+				{
+					"id": "core",
+					"name": "Core",
+					"run": "deploy-core",
+					"outPath": "out/addresses.json"
+				}
+			]
+		}`), nil
+	}
+
+	// Run
+	manifest, err := ReadManifestFile(mockFileReader, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assertion
+	scenario, err := manifest.FindScenario("oracles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scenario.OutPath != "out/oracles.json" {
+		t.Errorf("Out path of scenario must be path of first output: %s", scenario.OutPath)
+	}
+	if len(scenario.Outputs) != 2 || len(scenario.Parameters) != 2 {
+		t.Errorf("Outputs and parameters of scenario don't match: %+v", scenario)
+	}
+	envVars := scenario.WithDefaults(map[string]string{"ETH_FROM": "0x01"})
+	if envVars["ETH_GAS"] != "7000000" || envVars["ETH_FROM"] != "0x01" {
+		t.Errorf("Env vars with defaults don't match: %v", envVars)
+	}
+	core, err := manifest.FindScenario("core")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(core.Outputs) != 1 || core.Outputs[0].Name != DefaultOutputName {
+		t.Errorf("Out path must be default output: %+v", core.Outputs)
+	}
+	if _, err := manifest.FindScenario("0"); err == nil {
+		t.Error("Index must not be id of v2 scenario")
+	}
+}
+
+func TestManifestValidate(t *testing.T) {
+	cases := map[string]Manifest{
+		"cycle": {Scenarios: []Scenario{
+			{ID: "a", DependsOn: []string{"b"}},
+			{ID: "b", DependsOn: []string{"a"}},
+		}},
+		"unknown dependency": {Scenarios: []Scenario{
+			{ID: "a", DependsOn: []string{"c"}},
+		}},
+		"duplicated id": {Scenarios: []Scenario{
+			{ID: "a"},
+			{ID: "a"},
+		}},
+		"unknown parameter type": {Scenarios: []Scenario{
+			{ID: "a", Parameters: []ParameterModel{{Name: "ETH_FROM", Type: "float"}}},
+		}},
+	}
+	for name, manifest := range cases {
+		if err := manifest.Validate(); err == nil {
+			t.Errorf("Error is expected for manifest with %s", name)
+		}
+	}
+}
+
+func TestTopoSort(t *testing.T) {
+	ids := []string{"c", "b", "a", "d"}
+	deps := map[string][]string{
+		"c": {"b"},
+		"b": {"a"},
+	}
+	res, err := TopoSort(ids, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(res) != "[a b c d]" {
+		t.Errorf("Unexpected order: %v", res)
+	}
+}
@@ -20,39 +20,73 @@ type StepModel struct {
 }
 
 type Manifest struct {
+	Version     int        `json:"version"`
 	Name        string     `json:"name"`
 	Description string     `json:"description"`
+	Executor    string     `json:"executor,omitempty"`
 	Scenarios   []Scenario `json:"scenarios"`
 }
 
 type Scenario struct {
+	// ID is index of scenario for v1 manifest
+	ID          string          `json:"id"`
 	Name        string          `json:"name"`
 	Description string          `json:"description"`
 	RunCommand  string          `json:"run"`
 	Config      json.RawMessage `json:"config"`
-	OutPath     string          `json:"outPath"`
+	// OutPath is path of first output, kept for v1 clients
+	OutPath    string           `json:"outPath"`
+	DependsOn  []string         `json:"dependsOn"`
+	Parameters []ParameterModel `json:"parameters"`
+	Outputs    []OutputModel    `json:"outputs"`
 }
 
 type ManifestModel struct {
+	Version     int             `json:"version"`
 	Name        string          `json:"name"`
 	Description string          `json:"description"`
+	Executor    string          `json:"executor"`
 	Scenarios   []ScenarioModel `json:"scenarios"`
 }
 
 type ScenarioModel struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	RunCommand  string           `json:"run"`
+	ConfigPath  string           `json:"configPath"`
+	OutPath     string           `json:"outPath"`
+	DependsOn   []string         `json:"dependsOn"`
+	Parameters  []ParameterModel `json:"parameters"`
+	Outputs     []OutputModel    `json:"outputs"`
+}
+
+// ParameterModel is env var which is passed to scenario
+type ParameterModel struct {
 	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Default     string `json:"default,omitempty"`
 	Description string `json:"description"`
-	RunCommand  string `json:"run"`
-	ConfigPath  string `json:"configPath"`
-	OutPath     string `json:"outPath"`
+	// Secret value is redacted in logs and results
+	Secret bool `json:"secret,omitempty"`
+}
+
+// OutputModel is file which is created by scenario, path is relative to working dir
+type OutputModel struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
 }
 
 func NewStepListFromManifest(manifest *Manifest) ([]StepModel, error) {
 	stepList := make([]StepModel, len(manifest.Scenarios))
 	for i, scenario := range manifest.Scenarios {
 		var step StepModel
-		if err := json.Unmarshal(scenario.Config, &step); err != nil {
-			return nil, err
+		// config is optional for v2 scenarios
+		if len(scenario.Config) != 0 {
+			if err := json.Unmarshal(scenario.Config, &step); err != nil {
+				return nil, err
+			}
 		}
 		step.ID = i + 1
 		stepList[i] = step
@@ -61,8 +95,9 @@ func NewStepListFromManifest(manifest *Manifest) ([]StepModel, error) {
 }
 
 type ResultErrorModel struct {
-	Msg       string `json:"msg"`
-	StderrB64 string `json:"stderrB64"`
+	Msg       string        `json:"msg"`
+	StderrB64 string        `json:"stderrB64"`
+	Info      *RunInfoModel `json:"info,omitempty"`
 }
 
 func NewResultErrorModelFromErr(err error) *ResultErrorModel {
@@ -86,10 +121,77 @@ func (m *ResultErrorModel) WithStderr(stderr []byte) *ResultErrorModel {
 	return m
 }
 
+func (m *ResultErrorModel) WithInfo(info *RunInfoModel) *ResultErrorModel {
+	m.Info = info
+	return m
+}
+
 //ResultModel is struct for result of run
 type ResultModel struct {
 	LastUpdated time.Time       `json:"lastUpdated"`
 	Data        json.RawMessage `json:"data"`
+	// Outputs contain all declared outputs of scenario by name, Data is first of them
+	Outputs map[string]json.RawMessage `json:"outputs,omitempty"`
+	Info    *RunInfoModel              `json:"info,omitempty"`
+	// Cached is true if result is taken from result cache without deployment
+	Cached bool `json:"cached,omitempty"`
+}
+
+//RunInfoModel describe how deployment was run
+type RunInfoModel struct {
+	ExitCode   int               `json:"exitCode"`
+	DurationMs int64             `json:"durationMs"`
+	Rev        string            `json:"rev"`
+	Scenario   string            `json:"scenario"`
+	Account    string            `json:"account,omitempty"`
+	EnvVars    map[string]string `json:"envVars"`
+	StdoutSize int               `json:"stdoutSize"`
+	StderrSize int               `json:"stderrSize"`
+	Timings    []TimingModel     `json:"timings"`
+	Files      []FileModel       `json:"files"`
+}
+
+//TimingModel is duration of deployment stage
+type TimingModel struct {
+	Stage      string `json:"stage"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+//FileModel is file produced by deployment, path is relative to working dir
+type FileModel struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// redactedValue replaces values of env vars in results
+const redactedValue = "<redacted>"
+
+//NewRunInfoModel init info with env var names, values of vars are redacted
+func NewRunInfoModel(envVars map[string]string) *RunInfoModel {
+	info := &RunInfoModel{
+		ExitCode: -1,
+		Timings:  make([]TimingModel, 0),
+		Files:    make([]FileModel, 0),
+	}
+	info.SetEnvVars(envVars)
+	return info
+}
+
+//SetEnvVars keep names of env vars, values are redacted
+func (m *RunInfoModel) SetEnvVars(envVars map[string]string) {
+	redacted := make(map[string]string, len(envVars))
+	for name := range envVars {
+		redacted[name] = redactedValue
+	}
+	m.EnvVars = redacted
+}
+
+//AddTiming add duration of stage which started at start
+func (m *RunInfoModel) AddTiming(stage string, start time.Time) {
+	m.Timings = append(m.Timings, TimingModel{
+		Stage:      stage,
+		DurationMs: int64(time.Since(start) / time.Millisecond),
+	})
 }
 
 //NewResultModel init model of result
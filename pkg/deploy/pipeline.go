@@ -0,0 +1,284 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/sirupsen/logrus"
+)
+
+// Statuses of pipeline stage
+const (
+	StageStatusPending   = "pending"
+	StageStatusSucceeded = "succeeded"
+	StageStatusFailed    = "failed"
+	StageStatusSkipped   = "skipped"
+	StageStatusCancelled = "cancelled"
+)
+
+// PipelineStage is deployment of one scenario in pipeline
+type PipelineStage struct {
+	ID         string            `json:"id"`
+	Commit     git.Commit        `json:"commit"`
+	ScenarioID string            `json:"scenarioId"`
+	ScenarioNr int               `json:"scenarioNr"`
+	EnvVars    map[string]string `json:"envVars"`
+	DependsOn  []string          `json:"dependsOn"`
+	// EnvFrom set env vars from outputs of previous stages, key is name of env var
+	EnvFrom map[string]EnvFromModel `json:"envFrom"`
+	// Account is provisioned for stage if set
+	Account *AccountModel `json:"account,omitempty"`
+	// SnapshotID and NoCache are used for result cache like in Deployment
+	SnapshotID string `json:"snapshotId,omitempty"`
+	NoCache    bool   `json:"noCache,omitempty"`
+}
+
+// EnvFromModel point to value in output of stage,
+// path is dot separated keys of JSON objects or indexes of arrays, e.g. `MCD_VAT` or `ilks.0.name`
+type EnvFromModel struct {
+	Stage string `json:"stage"`
+	// Output is name of output, data of stage is used if empty
+	Output string `json:"output,omitempty"`
+	Path   string `json:"path"`
+}
+
+// StageResultModel is status and result of one stage
+type StageResultModel struct {
+	ID     string            `json:"id"`
+	Status string            `json:"status"`
+	Result *ResultModel      `json:"result,omitempty"`
+	Error  *ResultErrorModel `json:"error,omitempty"`
+}
+
+// PipelineResultModel contain merged data of all succeeded stages, later stages override keys of earlier ones
+type PipelineResultModel struct {
+	LastUpdated time.Time          `json:"lastUpdated"`
+	Data        json.RawMessage    `json:"data"`
+	Stages      []StageResultModel `json:"stages"`
+}
+
+// Succeeded return true if all stages are succeeded
+func (m *PipelineResultModel) Succeeded() bool {
+	for _, stage := range m.Stages {
+		if stage.Status != StageStatusSucceeded {
+			return false
+		}
+	}
+	return true
+}
+
+// SortPipelineStages check stages and return them in dependency order,
+// stage depends on stages from dependsOn and from envFrom
+func SortPipelineStages(stages []PipelineStage) ([]PipelineStage, error) {
+	byID := make(map[string]PipelineStage, len(stages))
+	ids := make([]string, 0, len(stages))
+	deps := make(map[string][]string, len(stages))
+	for _, stage := range stages {
+		if stage.ID == "" {
+			return nil, fmt.Errorf("stage without id")
+		}
+		if _, ok := byID[stage.ID]; ok {
+			return nil, fmt.Errorf("duplicated stage id '%s'", stage.ID)
+		}
+		if stage.Commit.URL == "" {
+			return nil, fmt.Errorf("stage '%s' has no repo url", stage.ID)
+		}
+		byID[stage.ID] = stage
+		ids = append(ids, stage.ID)
+		deps[stage.ID] = append(deps[stage.ID], stage.DependsOn...)
+		for name, from := range stage.EnvFrom {
+			if from.Stage == "" || from.Path == "" {
+				return nil, fmt.Errorf("stage '%s' has env var '%s' without stage or path", stage.ID, name)
+			}
+			deps[stage.ID] = append(deps[stage.ID], from.Stage)
+		}
+	}
+	order, err := TopoSort(ids, deps)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]PipelineStage, len(order))
+	for i, id := range order {
+		res[i] = byID[id]
+	}
+	return res, nil
+}
+
+// DeployPipeline run stages one by one in dependency order,
+// stage is skipped if any of its dependencies is not succeeded.
+// onStage is optional, it gets finished stages of deployments as `<stage id>/<stage of deployment>`
+func (d *Deployer) DeployPipeline(
+	ctx context.Context,
+	log *logrus.Entry,
+	stages []PipelineStage,
+	stdout, stderr io.Writer,
+	onStage func(stage string),
+) (*PipelineResultModel, error) {
+	sorted, err := SortPipelineStages(stages)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]*StageResultModel, len(sorted))
+	res := &PipelineResultModel{Stages: make([]StageResultModel, 0, len(sorted))}
+	merged := make(map[string]json.RawMessage)
+	for _, stage := range sorted {
+		stageLog := log.WithField("stage", stage.ID)
+		stageRes := &StageResultModel{ID: stage.ID, Status: StageStatusPending}
+		results[stage.ID] = stageRes
+		d.runStage(ctx, stageLog, stage, results, stageRes, stdout, stderr, onStage)
+		res.Stages = append(res.Stages, *stageRes)
+		if stageRes.Status != StageStatusSucceeded {
+			continue
+		}
+		// only objects can be merged
+		var data map[string]json.RawMessage
+		if err := json.Unmarshal(stageRes.Result.Data, &data); err != nil {
+			stageLog.WithError(err).Warn("Result of stage is not object, it is not merged")
+			continue
+		}
+		for k, v := range data {
+			merged[k] = v
+		}
+	}
+
+	res.Data, err = json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	res.LastUpdated = time.Now()
+	return res, nil
+}
+
+func (d *Deployer) runStage(
+	ctx context.Context,
+	log *logrus.Entry,
+	stage PipelineStage,
+	results map[string]*StageResultModel,
+	stageRes *StageResultModel,
+	stdout, stderr io.Writer,
+	onStage func(stage string),
+) {
+	if ctx.Err() != nil {
+		stageRes.Status = StageStatusCancelled
+		return
+	}
+	deps := append(append([]string{}, stage.DependsOn...), envFromStages(stage)...)
+	for _, dep := range deps {
+		if results[dep].Status != StageStatusSucceeded {
+			log.Infof("Stage is skipped, dependency '%s' is %s", dep, results[dep].Status)
+			stageRes.Status = StageStatusSkipped
+			return
+		}
+	}
+
+	envVars := make(map[string]string, len(stage.EnvVars)+len(stage.EnvFrom))
+	for name, val := range stage.EnvVars {
+		envVars[name] = val
+	}
+	for name, from := range stage.EnvFrom {
+		val, err := outputValue(results[from.Stage].Result, from)
+		if err != nil {
+			log.WithError(err).Errorf("Couldn't get value of env var %s", name)
+			stageRes.Status = StageStatusFailed
+			stageRes.Error = NewResultErrorModelFromErr(fmt.Errorf("env var %s: %s", name, err))
+			return
+		}
+		envVars[name] = val
+	}
+
+	log.Infof("Running stage with scenario '%s' of %s", stage.ScenarioID, stage.Commit.URL)
+	result, resErr := d.Deploy(ctx, log, Deployment{
+		Commit:        stage.Commit,
+		ScenarioID:    stage.ScenarioID,
+		ScenarioNr:    stage.ScenarioNr,
+		DeployEnvVars: envVars,
+		Account:       stage.Account,
+		SnapshotID:    stage.SnapshotID,
+		NoCache:       stage.NoCache,
+		Stdout:        stdout,
+		Stderr:        stderr,
+		OnStage: func(name string) {
+			// stage of deployment is prefixed with id of pipeline stage
+			if onStage != nil {
+				onStage(stage.ID + "/" + name)
+			}
+		},
+	})
+	switch {
+	case ctx.Err() != nil:
+		stageRes.Status = StageStatusCancelled
+		stageRes.Error = resErr
+	case resErr != nil:
+		stageRes.Status = StageStatusFailed
+		stageRes.Error = resErr
+	default:
+		stageRes.Status = StageStatusSucceeded
+		stageRes.Result = result
+	}
+}
+
+func envFromStages(stage PipelineStage) []string {
+	res := make([]string, 0, len(stage.EnvFrom))
+	for _, from := range stage.EnvFrom {
+		res = append(res, from.Stage)
+	}
+	return res
+}
+
+// outputValue return value from output of stage result by path,
+// strings are returned as is, other values as JSON
+func outputValue(result *ResultModel, from EnvFromModel) (string, error) {
+	data := result.Data
+	if from.Output != "" {
+		var ok bool
+		if data, ok = result.Outputs[from.Output]; !ok {
+			return "", fmt.Errorf("stage '%s' has no output '%s'", from.Stage, from.Output)
+		}
+	}
+	return LookupJSONPath(data, from.Path)
+}
+
+// LookupJSONPath return value by dot separated path in JSON,
+// strings are returned as is, other values as JSON
+func LookupJSONPath(data json.RawMessage, path string) (string, error) {
+	var val interface{}
+	// numbers are kept as is, without conversion to float
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&val); err != nil {
+		return "", err
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := val.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if val, ok = node[key]; !ok {
+				return "", fmt.Errorf("path '%s' not found", path)
+			}
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return "", fmt.Errorf("path '%s' not found", path)
+			}
+			val = node[idx]
+		default:
+			return "", fmt.Errorf("path '%s' not found", path)
+		}
+	}
+	if str, ok := val.(string); ok {
+		return str, nil
+	}
+	res, err := json.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
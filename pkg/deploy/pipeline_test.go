@@ -0,0 +1,66 @@
+package deploy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/makerdao/testchain-deployment/pkg/git"
+)
+
+func TestSortPipelineStages(t *testing.T) {
+	commit := git.Commit{URL: "https://github.com/makerdao/dss-deploy-scripts"}
+	stages := []PipelineStage{
+		{ID: "faucet", Commit: commit, DependsOn: []string{"oracles"}},
+		{ID: "oracles", Commit: commit, EnvFrom: map[string]EnvFromModel{
+			"MCD_VAT": {Stage: "dss", Path: "MCD_VAT"},
+		}},
+		{ID: "dss", Commit: commit},
+	}
+
+	sorted, err := SortPipelineStages(stages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(sorted))
+	for i, stage := range sorted {
+		ids[i] = stage.ID
+	}
+	if len(ids) != 3 || ids[0] != "dss" || ids[1] != "oracles" || ids[2] != "faucet" {
+		t.Errorf("Unexpected order of stages: %v", ids)
+	}
+
+	stages[2].EnvFrom = map[string]EnvFromModel{"FAUCET": {Stage: "faucet", Path: "FAUCET"}}
+	if _, err := SortPipelineStages(stages); err == nil {
+		t.Error("Error is expected for cycle in stages")
+	}
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	data := json.RawMessage(`{
+		"MCD_VAT": "0x01",
+		"ilks": [{"name": "ETH-A", "line": 10000000}],
+		"enabled": true
+	}`)
+	cases := map[string]string{
+		"MCD_VAT":     "0x01",
+		"ilks.0.name": "ETH-A",
+		"ilks.0.line": "10000000",
+		"enabled":     "true",
+		"ilks.0":      `{"line":10000000,"name":"ETH-A"}`,
+	}
+	for path, expected := range cases {
+		val, err := LookupJSONPath(data, path)
+		if err != nil {
+			t.Errorf("Path %s: %s", path, err)
+			continue
+		}
+		if val != expected {
+			t.Errorf("Path %s: expected %s, got %s", path, expected, val)
+		}
+	}
+	for _, path := range []string{"MCD_CAT", "ilks.1.name", "MCD_VAT.name"} {
+		if _, err := LookupJSONPath(data, path); err == nil {
+			t.Errorf("Error is expected for path %s", path)
+		}
+	}
+}
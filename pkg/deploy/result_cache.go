@@ -0,0 +1,153 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResultCache keep successful results of deployments on disk,
+// key is content address of deployment: rev, scenario, env vars and snapshot of chain.
+// Count of results is limited, least recently used results are removed
+type ResultCache struct {
+	dir string
+	// ignoreEnv are env vars which don't change result for the same snapshot, e.g. url of chain
+	ignoreEnv  map[string]bool
+	maxEntries int
+	mu         sync.Mutex
+}
+
+// NewResultCache init cache in dir, dir is created if it doesn't exist, maxEntries 0 - no limit
+func NewResultCache(dir string, ignoreEnv []string, maxEntries int) (*ResultCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	c := &ResultCache{dir: dir, ignoreEnv: make(map[string]bool, len(ignoreEnv)), maxEntries: maxEntries}
+	for _, name := range ignoreEnv {
+		c.ignoreEnv[name] = true
+	}
+	return c, nil
+}
+
+// Key return key of deployment, env vars are sorted by name, so order of them doesn't matter.
+// Ignored env vars are skipped only with snapshot, without it url of chain is part of key
+func (c *ResultCache) Key(rev, scenarioID string, envVars map[string]string, snapshotID string) string {
+	names := make([]string, 0, len(envVars))
+	for name := range envVars {
+		if snapshotID == "" || !c.ignoreEnv[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	envHash := sha256.New()
+	for _, name := range names {
+		envHash.Write([]byte(name + "=" + envVars[name] + "\x00"))
+	}
+
+	hash := sha256.New()
+	for _, part := range []string{rev, scenarioID, hex.EncodeToString(envHash.Sum(nil)), snapshotID} {
+		hash.Write([]byte(part + "\x00"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get return cached result by key, nil if result is not cached, result is marked as recently used
+func (c *ResultCache) Get(key string) (*ResultModel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := ioutil.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := os.Chtimes(c.path(key), now, now); err != nil {
+		return nil, err
+	}
+	var res ResultModel
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	res.Cached = true
+	return &res, nil
+}
+
+// Put save result, file is replaced atomically, least recently used results are removed over limit
+func (c *ResultCache) Put(key string, res *ResultModel) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tmp, err := ioutil.TempFile(c.dir, key+".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// evict remove least recently used results while count of them is over limit, lock has to be held
+func (c *ResultCache) evict() error {
+	if c.maxEntries == 0 {
+		return nil
+	}
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	results := make([]os.FileInfo, 0, len(files))
+	for _, fi := range files {
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), ".json") {
+			results = append(results, fi)
+		}
+	}
+	if len(results) <= c.maxEntries {
+		return nil
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ModTime().Before(results[j].ModTime())
+	})
+	for _, fi := range results[:len(results)-c.maxEntries] {
+		if err := os.Remove(filepath.Join(c.dir, fi.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ResultCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// parseEnvList split comma separated list of env var names
+func parseEnvList(list string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,153 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+	"github.com/sirupsen/logrus"
+)
+
+// staticResolver return the same dir for all commits
+type staticResolver struct {
+	repo git.Repo
+}
+
+func (r *staticResolver) GetRepo(log *logrus.Entry, commit git.Commit) (*git.Repo, error) {
+	repo := r.repo
+	return &repo, nil
+}
+
+func TestResultCacheKey(t *testing.T) {
+	cache, err := NewResultCache(filepath.Join(os.TempDir(), "result-cache-key-test"), []string{"ETH_RPC_URL"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache.dir)
+
+	env := map[string]string{"ETH_FROM": "0x01", "ETH_GAS": "7000000", "ETH_RPC_URL": "http://chain1:8545"}
+	key := cache.Key("rev1", "core", env, "snap1")
+	sameEnv := map[string]string{"ETH_GAS": "7000000", "ETH_FROM": "0x01", "ETH_RPC_URL": "http://chain2:8545"}
+	if cache.Key("rev1", "core", sameEnv, "snap1") != key {
+		t.Error("Key must not depend on ignored env vars")
+	}
+	if cache.Key("rev1", "core", sameEnv, "") == cache.Key("rev1", "core", env, "") {
+		t.Error("Key without snapshot must depend on all env vars")
+	}
+	otherEnv := map[string]string{"ETH_FROM": "0x02", "ETH_GAS": "7000000"}
+	for _, other := range []string{
+		cache.Key("rev2", "core", env, "snap1"),
+		cache.Key("rev1", "oracles", env, "snap1"),
+		cache.Key("rev1", "core", otherEnv, "snap1"),
+		cache.Key("rev1", "core", env, ""),
+	} {
+		if other == key {
+			t.Error("Key must depend on rev, scenario, env vars and snapshot")
+		}
+	}
+}
+
+func TestDeployResultCache(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "result-cache-repo-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoPath)
+	manifest := `{"version": 2, "executor": "shell", "scenarios": [{"id": "core", "run": "sh deploy.sh", "outPath": "out/result.json"}]}`
+	// every run is counted in file out of repo
+	counter := filepath.Join(repoPath, "runs")
+	script := "mkdir -p out && echo run >> " + counter + ` && echo "{\"value\": \"$VALUE\"}" > out/result.json`
+	for name, content := range map[string]string{".staxx-scenarios": manifest, "deploy.sh": script} {
+		if err := ioutil.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := GetDefaultConfig()
+	cfg.ResultCacheDir = filepath.Join(repoPath, "cache")
+	d, err := NewDeployer(cfg, &staticResolver{repo: git.Repo{Path: repoPath, Rev: "rev1"}}, secret.GetDefaultConfig(), NewExecutors(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.NewEntry(logrus.New())
+	deployScenario := func(deployment Deployment) *ResultModel {
+		deployment.Commit = git.Commit{URL: "https://github.com/makerdao/dss-deploy-scripts"}
+		deployment.ScenarioID = "core"
+		res, resErr := d.Deploy(context.Background(), log, deployment)
+		if resErr != nil {
+			t.Fatalf("Deploy failed: %+v", resErr)
+		}
+		return res
+	}
+	runs := func() int {
+		data, _ := ioutil.ReadFile(counter)
+		return len(data) / len("run\n")
+	}
+
+	env := map[string]string{"VALUE": "a", "ETH_RPC_URL": "http://chain1:8545"}
+	if res := deployScenario(Deployment{DeployEnvVars: env, SnapshotID: "snap"}); res.Cached {
+		t.Error("First result must not be cached")
+	}
+	env = map[string]string{"VALUE": "a", "ETH_RPC_URL": "http://chain2:8545"}
+	res := deployScenario(Deployment{DeployEnvVars: env, SnapshotID: "snap"})
+	if !res.Cached || string(res.Data) != `{"value":"a"}` {
+		t.Errorf("Cached result is expected, got %+v", res)
+	}
+	if runs() != 1 {
+		t.Errorf("Scenario must be run once, got %d", runs())
+	}
+
+	deployScenario(Deployment{DeployEnvVars: env, SnapshotID: "snap", NoCache: true})
+	deployScenario(Deployment{DeployEnvVars: env, SnapshotID: "other"})
+	if runs() != 3 {
+		t.Errorf("Scenario must be run without cache and for other snapshot, got %d runs", runs())
+	}
+	// without snapshot result is cached only for the same chain url
+	deployScenario(Deployment{DeployEnvVars: env})
+	deployScenario(Deployment{DeployEnvVars: map[string]string{"VALUE": "a", "ETH_RPC_URL": "http://chain3:8545"}})
+	if res := deployScenario(Deployment{DeployEnvVars: env}); !res.Cached || runs() != 5 {
+		t.Errorf("Result without snapshot must be cached by chain url, got %d runs", runs())
+	}
+}
+
+func TestResultCacheEvict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "result-cache-evict-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := NewResultCache(dir, nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range []string{"k1", "k2", "k3"} {
+		if err := cache.Put(key, &ResultModel{}); err != nil {
+			t.Fatal(err)
+		}
+		// modification time is resolution of LRU order
+		past := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if err := os.Chtimes(cache.path(key), past, past); err != nil {
+			t.Fatal(err)
+		}
+		if key == "k2" {
+			// k1 is used after k2, so k2 is removed first
+			if res, err := cache.Get("k1"); err != nil || res == nil {
+				t.Fatalf("Expected cached k1, got %v, %v", res, err)
+			}
+		}
+	}
+	for key, cached := range map[string]bool{"k1": true, "k2": false, "k3": true} {
+		res, err := cache.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (res != nil) != cached {
+			t.Errorf("Expected cached %s: %v, got %v", key, cached, res != nil)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+)
+
+// prepareEnvVars add defaults of scenario and resolve references to secret files,
+// redactor hides values of env vars which are declared as secret in manifest, match secret patterns
+// or are read from files, and credentials of account
+func (d *Deployer) prepareEnvVars(
+	scenario *Scenario,
+	envVars map[string]string,
+	account *AccountModel,
+) (map[string]string, *secret.Redactor, error) {
+	res, fileNames, err := d.secrets.ResolveFiles(scenario.WithDefaults(envVars))
+	if err != nil {
+		return nil, nil, err
+	}
+	secretNames := make(map[string]bool)
+	for _, name := range fileNames {
+		secretNames[name] = true
+	}
+	for _, param := range scenario.Parameters {
+		if param.Secret {
+			secretNames[param.Name] = true
+		}
+	}
+	values := make([]string, 0)
+	for name, val := range res {
+		if secretNames[name] || d.secrets.IsSecretName(name) {
+			values = append(values, val)
+		}
+	}
+	if account != nil {
+		values = append(values, account.secrets()...)
+	}
+	return res, secret.NewRedactor(values...), nil
+}
+
+// OutputRedactor return redactor of secrets which are known without manifest: env vars matching secret patterns,
+// content of secret files and credentials of account, it protects output which is streamed outside of deployment
+func (d *Deployer) OutputRedactor(envVars map[string]string, account *AccountModel) *secret.Redactor {
+	return secret.NewRedactor(d.outputSecrets(envVars, account)...)
+}
+
+// PipelineOutputRedactor return redactor of secrets of all stages, see OutputRedactor
+func (d *Deployer) PipelineOutputRedactor(stages []PipelineStage) *secret.Redactor {
+	values := make([]string, 0)
+	for _, stage := range stages {
+		values = append(values, d.outputSecrets(stage.EnvVars, stage.Account)...)
+	}
+	return secret.NewRedactor(values...)
+}
+
+func (d *Deployer) outputSecrets(envVars map[string]string, account *AccountModel) []string {
+	values := make([]string, 0)
+	resolved, fileNames, err := d.secrets.ResolveFiles(envVars)
+	if err != nil {
+		// deployment fails on the same error, only patterns are used
+		resolved, fileNames = envVars, nil
+	}
+	secretNames := make(map[string]bool)
+	for _, name := range fileNames {
+		secretNames[name] = true
+	}
+	for name, val := range resolved {
+		if secretNames[name] || d.secrets.IsSecretName(name) {
+			values = append(values, val)
+		}
+	}
+	if account != nil {
+		values = append(values, account.secrets()...)
+	}
+	return values
+}
@@ -0,0 +1,63 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+)
+
+func TestPrepareEnvVars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "keystore"), []byte(`{"address":"980957"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	secrets := secret.GetDefaultConfig()
+	secrets.FilesDir = dir
+	d := &Deployer{secrets: secrets}
+	scenario := &Scenario{
+		Parameters: []ParameterModel{
+			{Name: "MNEMONIC", Secret: true},
+			{Name: "ETH_GAS", Default: "7000000"},
+		},
+	}
+	envVars, redactor, err := d.prepareEnvVars(scenario, map[string]string{
+		"MNEMONIC":     "test test junk",
+		"ETH_PASSWORD": "pass123",
+		"ACCOUNT":      secret.FileRefPrefix + "keystore",
+		"ETH_FROM":     "0x980957",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envVars["ACCOUNT"] != `{"address":"980957"}` || envVars["ETH_GAS"] != "7000000" {
+		t.Errorf("Unexpected env vars: %v", envVars)
+	}
+	out := redactor.Redact(`mnemonic test test junk, password pass123, account {"address":"980957"}, from 0x980957`)
+	expected := `mnemonic <redacted>, password <redacted>, account <redacted>, from 0x980957`
+	if out != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, out)
+	}
+}
+
+func TestValidateSecretEnvVars(t *testing.T) {
+	scenario := &Scenario{
+		Parameters: []ParameterModel{
+			{Name: "ETH_FROM", Type: ParameterTypeAddress, Secret: true},
+		},
+	}
+	errs := ValidateEnvVars(scenario, map[string]string{"ETH_FROM": "0xsecret"})
+	if len(errs) != 1 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if msg := errs[0].(*FieldError).Message; msg != "invalid value of secret address" {
+		t.Errorf("Value of secret must not be in error: %s", msg)
+	}
+}
@@ -0,0 +1,232 @@
+package deploy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+	"github.com/sirupsen/logrus"
+)
+
+var addressRegexp = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// placeholderAddress is ETH_FROM for validation of request with account
+const placeholderAddress = "0x0000000000000000000000000000000000000000"
+
+// FieldError is error of one field of request
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func newFieldError(field, message string) *FieldError {
+	return &FieldError{Field: field, Message: message}
+}
+
+// Validate check deployment before it is queued: url, ref and rev of repo, secret files, account,
+// manifest, scenario and its parameters. Repo is taken without network access if it is already fetched
+// by git.LocalResolver, otherwise it is fetched by resolver (nix caches fetchGit, so deployment doesn't fetch it again),
+// if fetch fails, manifest and parameters are checked by deployment
+func (d *Deployer) Validate(log *logrus.Entry, deployment Deployment) []error {
+	return d.validate(log, deployment, nil)
+}
+
+// ValidatePipeline check each stage of sorted pipeline like Validate, field of error is prefixed
+// with `stages.<id>.`, env vars from envFrom are known only after run of stages, so they are not checked
+func (d *Deployer) ValidatePipeline(log *logrus.Entry, stages []PipelineStage) []error {
+	errs := make([]error, 0)
+	for _, stage := range stages {
+		deferred := make(map[string]bool, len(stage.EnvFrom))
+		for name := range stage.EnvFrom {
+			deferred[name] = true
+		}
+		stageErrs := d.validate(log, Deployment{
+			Commit:        stage.Commit,
+			ScenarioID:    stage.ScenarioID,
+			ScenarioNr:    stage.ScenarioNr,
+			DeployEnvVars: stage.EnvVars,
+			Account:       stage.Account,
+		}, deferred)
+		for _, err := range stageErrs {
+			if fieldErr, ok := err.(*FieldError); ok {
+				fieldErr.Field = "stages." + stage.ID + "." + fieldErr.Field
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// validate deployment, parameters from deferred are set later and are not checked
+func (d *Deployer) validate(log *logrus.Entry, deployment Deployment, deferred map[string]bool) []error {
+	if err := git.ValidateURL(deployment.Commit.URL); err != nil {
+		return []error{newFieldError("repoUrl", err.Error())}
+	}
+	errs := make([]error, 0)
+	if err := git.ValidateRef(deployment.Commit.Ref); err != nil {
+		errs = append(errs, newFieldError("repoRef", err.Error()))
+	}
+	if err := git.ValidateRev(deployment.Commit.Rev); err != nil {
+		errs = append(errs, newFieldError("repoRev", err.Error()))
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	if _, _, err := d.secrets.ResolveFiles(deployment.DeployEnvVars); err != nil {
+		return []error{envVarsError(err)}
+	}
+	if deployment.Account != nil {
+		// keystore is decrypted only by deployment, kdf takes too much time for request
+		if err := deployment.Account.Validate(); err != nil {
+			return []error{newFieldError("account", err.Error())}
+		}
+	}
+
+	repo, err := d.getValidationRepo(log, deployment.Commit)
+	if err != nil {
+		log.WithError(err).Warn("Couldn't get repository for validation")
+		return nil
+	}
+	defer repo.Release()
+	manifest, err := ReadManifestFile(ioutil.ReadFile, repo.Path)
+	if err != nil {
+		return []error{newFieldError("manifest", err.Error())}
+	}
+	if _, err := selectExecutor(d.executors, d.cfg.Executor, manifest); err != nil {
+		return []error{newFieldError("manifest", err.Error())}
+	}
+	scenario, err := findScenario(manifest, deployment)
+	if err != nil {
+		field := "scenarioNr"
+		if deployment.ScenarioID != "" {
+			field = "scenarioId"
+		}
+		return []error{newFieldError(field, err.Error())}
+	}
+	envVars, redactor, err := d.prepareEnvVars(scenario, deployment.DeployEnvVars, deployment.Account)
+	if err != nil {
+		return []error{envVarsError(err)}
+	}
+	if deployment.Account != nil {
+		// files of account are created and address is derived from key only for deployment,
+		// parameters are checked with real address by deployment again
+		envVars[EnvEthFrom] = placeholderAddress
+		envVars[EnvEthKeystore] = "keystore"
+		envVars[EnvEthPassword] = "password"
+	}
+	if len(deferred) != 0 {
+		params := make([]ParameterModel, 0, len(scenario.Parameters))
+		for _, param := range scenario.Parameters {
+			if !deferred[param.Name] {
+				params = append(params, param)
+			}
+		}
+		checked := *scenario
+		checked.Parameters = params
+		scenario = &checked
+	}
+	return validateParameters(scenario, envVars, redactor)
+}
+
+// getValidationRepo return repo from git.LocalResolver if it is already fetched, otherwise it is fetched by resolver
+func (d *Deployer) getValidationRepo(log *logrus.Entry, commit git.Commit) (*git.Repo, error) {
+	if local, ok := d.resolver.(git.LocalResolver); ok {
+		repo, err := local.GetLocalRepo(log, commit)
+		if err == nil {
+			return repo, nil
+		}
+		if err != git.ErrNotCached {
+			return nil, err
+		}
+	}
+	return d.resolver.GetRepo(log, commit)
+}
+
+func envVarsError(err error) error {
+	if refErr, ok := err.(*secret.FileRefError); ok {
+		return newFieldError("envVars."+refErr.Name, refErr.Err.Error())
+	}
+	return newFieldError("envVars", err.Error())
+}
+
+// ParametersError is error of deployment with env vars which don't match parameters of scenario
+type ParametersError struct {
+	Errs []error
+}
+
+func (e *ParametersError) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid parameters of scenario: " + strings.Join(msgs, "; ")
+}
+
+// validateParameters check env vars with ValidateEnvVars, secret values are removed from messages
+func validateParameters(scenario *Scenario, envVars map[string]string, redactor *secret.Redactor) []error {
+	errs := ValidateEnvVars(scenario, envVars)
+	for _, err := range errs {
+		if fieldErr, ok := err.(*FieldError); ok {
+			fieldErr.Message = redactor.Redact(fieldErr.Message)
+		}
+	}
+	return errs
+}
+
+// ValidateEnvVars check that required parameters of scenario are set and have valid values,
+// env vars which are not declared as parameters are not checked
+func ValidateEnvVars(scenario *Scenario, envVars map[string]string) []error {
+	errs := make([]error, 0)
+	for _, param := range scenario.Parameters {
+		field := "envVars." + param.Name
+		val, ok := envVars[param.Name]
+		if !ok || val == "" {
+			if param.Required {
+				errs = append(errs, newFieldError(field, "required"))
+			}
+			continue
+		}
+		if err := validateParameterValue(param.Type, val); err != nil {
+			if param.Secret {
+				errs = append(errs, newFieldError(field, "invalid value of secret "+param.Type))
+				continue
+			}
+			errs = append(errs, newFieldError(field, err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateParameterValue(paramType, val string) error {
+	switch paramType {
+	case ParameterTypeAddress:
+		if !addressRegexp.MatchString(val) {
+			return fmt.Errorf("'%s' is not an address", val)
+		}
+	case ParameterTypeURL:
+		u, err := url.Parse(val)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("'%s' is not an url", val)
+		}
+	case ParameterTypeInteger:
+		// values in wei don't fit int64
+		if _, ok := new(big.Int).SetString(val, 10); !ok {
+			return fmt.Errorf("'%s' is not an integer", val)
+		}
+	case ParameterTypeBool:
+		if _, err := strconv.ParseBool(val); err != nil {
+			return fmt.Errorf("'%s' is not a bool", val)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidateEnvVars(t *testing.T) {
+	scenario := &Scenario{
+		Parameters: []ParameterModel{
+			{Name: "ETH_FROM", Type: ParameterTypeAddress, Required: true},
+			{Name: "ETH_RPC_URL", Type: ParameterTypeURL, Required: true},
+			{Name: "ETH_GAS", Type: ParameterTypeInteger},
+			{Name: "SKIP_ORACLES", Type: ParameterTypeBool},
+			{Name: "NAME"},
+		},
+	}
+
+	errs := ValidateEnvVars(scenario, map[string]string{
+		"ETH_FROM":     "0x980957073687abbfc85609ecd7c118d2b7506a17",
+		"ETH_RPC_URL":  "http://localhost:8545",
+		"ETH_GAS":      "100000000000000000000000",
+		"SKIP_ORACLES": "true",
+	})
+	if len(errs) != 0 {
+		t.Errorf("No errors are expected for valid env vars: %v", errs)
+	}
+
+	errs = ValidateEnvVars(scenario, map[string]string{
+		"ETH_FROM":     "0x9809",
+		"ETH_GAS":      "7e6",
+		"SKIP_ORACLES": "maybe",
+		"NAME":         "any value",
+	})
+	fields := make([]string, len(errs))
+	for i, err := range errs {
+		fields[i] = err.(*FieldError).Field
+	}
+	sort.Strings(fields)
+	expected := []string{"envVars.ETH_FROM", "envVars.ETH_GAS", "envVars.ETH_RPC_URL", "envVars.SKIP_ORACLES"}
+	if len(fields) != len(expected) {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	for i := range expected {
+		if fields[i] != expected[i] {
+			t.Errorf("Expected error for %s, got %s", expected[i], fields[i])
+		}
+	}
+}
+
+// localResolver return the same dir for all commits as already fetched repo
+type localResolver struct {
+	staticResolver
+}
+
+func (r *localResolver) GetLocalRepo(log *logrus.Entry, commit git.Commit) (*git.Repo, error) {
+	return r.GetRepo(log, commit)
+}
+
+func TestValidatePipeline(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "validate-pipeline-repo-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoPath)
+	manifest := `{"version": 2, "executor": "shell", "scenarios": [{"id": "core", "run": "sh deploy.sh",
+		"parameters": [{"name": "MCD_VAT", "type": "address", "required": true}]}]}`
+	if err := ioutil.WriteFile(filepath.Join(repoPath, ".staxx-scenarios"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := GetDefaultConfig()
+	resolver := &localResolver{staticResolver{repo: git.Repo{Path: repoPath, Rev: "rev1"}}}
+	d, err := NewDeployer(cfg, resolver, secret.GetDefaultConfig(), NewExecutors(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := git.Commit{URL: "https://github.com/makerdao/dss-deploy-scripts"}
+
+	// required parameter from output of previous stage is not checked
+	errs := d.ValidatePipeline(logrus.NewEntry(logrus.New()), []PipelineStage{
+		{ID: "core", Commit: commit, ScenarioID: "core", EnvVars: map[string]string{"MCD_VAT": "0x980957073687abbfc85609ecd7c118d2b7506a17"}},
+		{ID: "next", Commit: commit, ScenarioID: "core", EnvFrom: map[string]EnvFromModel{"MCD_VAT": {Stage: "core", Path: "MCD_VAT"}}},
+	})
+	if len(errs) != 0 {
+		t.Errorf("No errors are expected for valid pipeline: %v", errs)
+	}
+
+	errs = d.ValidatePipeline(logrus.NewEntry(logrus.New()), []PipelineStage{
+		{ID: "core", Commit: commit, ScenarioID: "core"},
+		{ID: "next", Commit: commit, ScenarioID: "unknown"},
+	})
+	fields := make([]string, len(errs))
+	for i, err := range errs {
+		fields[i] = err.(*FieldError).Field
+	}
+	expected := []string{"stages.core.envVars.MCD_VAT", "stages.next.scenarioId"}
+	if len(fields) != len(expected) {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	for i := range expected {
+		if fields[i] != expected[i] {
+			t.Errorf("Expected error for %s, got %s", expected[i], fields[i])
+		}
+	}
+}
+
+func TestDeployInvalidParameters(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "validate-deploy-repo-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoPath)
+	manifest := `{"version": 2, "executor": "shell", "scenarios": [{"id": "core", "run": "sh deploy.sh",
+		"parameters": [{"name": "MCD_VAT", "type": "address", "required": true}]}]}`
+	if err := ioutil.WriteFile(filepath.Join(repoPath, ".staxx-scenarios"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := GetDefaultConfig()
+	// resolver without local cache like nix resolver, repo is fetched for validation
+	d, err := NewDeployer(cfg, &staticResolver{repo: git.Repo{Path: repoPath, Rev: "rev1"}}, secret.GetDefaultConfig(), NewExecutors(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.NewEntry(logrus.New())
+	deployment := Deployment{
+		Commit:        git.Commit{URL: "https://github.com/makerdao/dss-deploy-scripts"},
+		ScenarioID:    "core",
+		DeployEnvVars: map[string]string{"MCD_VAT": "0x9809"},
+	}
+	errs := d.Validate(log, deployment)
+	if len(errs) != 1 || errs[0].(*FieldError).Field != "envVars.MCD_VAT" {
+		t.Errorf("Expected error of parameter, got: %v", errs)
+	}
+	if errs := d.Validate(log, Deployment{Commit: git.Commit{URL: "https://github.com/makerdao/dss-deploy-scripts"}, ScenarioNr: 5}); len(errs) != 1 || errs[0].(*FieldError).Field != "scenarioNr" {
+		t.Errorf("Expected error of scenario number, got: %v", errs)
+	}
+	if errs := d.Validate(log, Deployment{Commit: git.Commit{URL: "ftp://host/repo"}}); len(errs) != 1 {
+		t.Errorf("Expected error of repo url, got: %v", errs)
+	}
+	errs = d.Validate(log, Deployment{Commit: git.Commit{
+		URL: "https://github.com/makerdao/dss-deploy-scripts",
+		Ref: `master"; x = "`,
+		Rev: "--all",
+	}})
+	if len(errs) != 2 || errs[0].(*FieldError).Field != "repoRef" || errs[1].(*FieldError).Field != "repoRev" {
+		t.Errorf("Expected errors of repo ref and rev, got: %v", errs)
+	}
+	_, resErr := d.Deploy(context.Background(), log, deployment)
+	if resErr == nil || !strings.Contains(resErr.Msg, "envVars.MCD_VAT") {
+		t.Errorf("Expected error of parameters, got: %+v", resErr)
+	}
+}
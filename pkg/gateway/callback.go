@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Transports of results
+const (
+	TransportHTTP = "http"
+	TransportNATS = "nats"
+	TransportBoth = "both"
+)
+
+// Callback is per-request target of result, it replaces transports from config for the job.
+// If only one of URL and Subject is set, result is sent only with that transport
+type Callback struct {
+	// URL of http endpoint which accepts rpc request with result
+	URL string `json:"url,omitempty"`
+	// Subject of NATS where result is published
+	Subject string `json:"subject,omitempty"`
+}
+
+// Validate callback from request, host of url should match allowedHosts if they are set,
+// otherwise url with loopback, private or link-local address is denied
+func (c *Callback) Validate(allowedHosts []string) error {
+	if c.URL == "" && c.Subject == "" {
+		return fmt.Errorf("callback should have url or subject")
+	}
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("callback url should be absolute http or https url")
+		}
+		if err := CheckHost(u.Hostname(), allowedHosts); err != nil {
+			return fmt.Errorf("callback %s", err)
+		}
+	}
+	if c.Subject != "" && strings.ContainsAny(c.Subject, " \t\r\n*>") {
+		return fmt.Errorf("callback subject should not contain spaces and wildcards")
+	}
+	return nil
+}
+
+// useTransports return transports of result by callback or by config if callback is not set
+func useTransports(transports string, callback *Callback) (useHTTP bool, useNATS bool) {
+	if callback != nil {
+		return callback.URL != "", callback.Subject != ""
+	}
+	return transports != TransportNATS, transports != TransportHTTP
+}
+
+// CheckHost check host of url which is requested by service (callback or webhook),
+// address of host name is checked again on connect by client from NewRestrictedClient
+func CheckHost(host string, allowedHosts []string) error {
+	if len(allowedHosts) != 0 {
+		if !hostAllowed(host, allowedHosts) {
+			return fmt.Errorf("host '%s' is not allowed", host)
+		}
+		return nil
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return fmt.Errorf("host '%s' is not allowed", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && deniedIP(ip) {
+		return fmt.Errorf("address %s is not allowed", ip)
+	}
+	return nil
+}
+
+// NewRestrictedClient init http client for urls from requests, it doesn't follow redirects
+// and doesn't connect to internal addresses if allowed hosts are not set
+func NewRestrictedClient(timeout time.Duration, allowedHosts []string) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if len(allowedHosts) == 0 {
+		dialer.Control = denyInternalAddress
+	}
+	// proxy isn't used, so address of url is checked by dialer
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// hostAllowed match host with list of hosts, `*.example.com` matches subdomains of example.com
+func hostAllowed(host string, allowedHosts []string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range allowedHosts {
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// deniedIP return true for addresses of local host and internal networks
+func deniedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// denyInternalAddress is control of dialer for callbacks, it rejects address which host name is resolved to
+func denyInternalAddress(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || deniedIP(ip) {
+		return fmt.Errorf("address %s is not allowed", host)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +10,10 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
 	"github.com/makerdao/testchain-deployment/pkg/service/nats"
 	"github.com/makerdao/testchain-deployment/pkg/service/protocol"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
 	gonats "github.com/nats-io/go-nats"
 	"github.com/sirupsen/logrus"
 )
@@ -23,17 +26,22 @@ const (
 type Client struct {
 	basePath string
 	client   *http.Client
-	nats     *gonats.Conn
-	natsCfg  nats.Config
+	// callbackClient send results to url of callback, it doesn't follow redirects
+	// and doesn't connect to internal addresses if allowed hosts are not set
+	callbackClient *http.Client
+	nats           *gonats.Conn
+	natsCfg        nats.Config
 }
 
 // NewClient init client
 func NewClient(cfg Config, nats *gonats.Conn, natsCfg nats.Config) *Client {
+	timeout := time.Duration(cfg.ClientTimeoutInSecond) * time.Second
 	return &Client{
-		basePath: fmt.Sprintf("http://%s:%d", cfg.Host, cfg.Port),
-		client:   &http.Client{Timeout: time.Duration(cfg.ClientTimeoutInSecond) * time.Second},
-		nats:     nats,
-		natsCfg:  natsCfg,
+		basePath:       fmt.Sprintf("http://%s:%d", cfg.Host, cfg.Port),
+		client:         &http.Client{Timeout: timeout},
+		callbackClient: NewRestrictedClient(timeout, cfg.CallbackHosts),
+		nats:           nats,
+		natsCfg:        natsCfg,
 	}
 }
 
@@ -70,37 +78,31 @@ func (c *Client) Unregister(log *logrus.Entry, req *ServiceData) error {
 type RunResultRequestType string
 
 const (
-	RunResultRequestTypeOK  = "ok"
-	RunResultRequestTypeErr = "error"
+	RunResultRequestTypeOK        = "ok"
+	RunResultRequestTypeErr       = "error"
+	RunResultRequestTypeCancelled = "cancelled"
 )
 
 type RunResultRequest struct {
-	ID     string               `json:"id"`
-	Type   RunResultRequestType `json:"type"`
-	Result json.RawMessage      `json:"result"`
+	ID             string               `json:"id"`
+	Type           RunResultRequestType `json:"type"`
+	Result         json.RawMessage      `json:"result"`
+	IdempotencyKey string               `json:"idempotencyKey,omitempty"`
+	// Callback overrides transports of result, it is not sent to gateway
+	Callback *Callback `json:"-"`
 }
 
 func (r *RunResultRequest) SetErr(err error) *RunResultRequest {
 	r.Type = RunResultRequestTypeErr
-	r.Result = json.RawMessage(fmt.Sprintf(`{"message":"%s"}`, err.Error()))
+	r.Result = ErrorMessage(err)
 	return r
 }
 
-// RunResult send result of run to gateway
-func (c *Client) RunResult(log *logrus.Entry, req *RunResultRequest) error {
-	reqBytes, err := json.Marshal(req)
-	if err != nil {
-		return err
-	}
-
-	_, httpErr := c.req(log, "RunResult", reqBytes)
-
-	natsErr := c.nats.Publish(c.getPublishTopic("RunResult", req.ID), reqBytes)
-	if natsErr != nil || httpErr != nil {
-		return fmt.Errorf("http: %+v, nats: %+v", httpErr, natsErr)
-	}
-
-	return nil
+// SetCancelled mark result as cancelled by request
+func (r *RunResultRequest) SetCancelled() *RunResultRequest {
+	r.Type = RunResultRequestTypeCancelled
+	r.Result = json.RawMessage(`{"message":"deployment cancelled"}`)
+	return r
 }
 
 // UpdateResult -=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=
@@ -113,34 +115,20 @@ const (
 )
 
 type UpdateResultRequest struct {
-	ID     string                  `json:"id"`
-	Type   UpdateResultRequestType `json:"type"`
-	Result json.RawMessage         `json:"result"`
+	ID             string                  `json:"id"`
+	Type           UpdateResultRequestType `json:"type"`
+	Result         json.RawMessage         `json:"result"`
+	IdempotencyKey string                  `json:"idempotencyKey,omitempty"`
+	// Callback overrides transports of result, it is not sent to gateway
+	Callback *Callback `json:"-"`
 }
 
 func (r *UpdateResultRequest) SetErr(err error) *UpdateResultRequest {
 	r.Type = UpdateResultRequestTypeErr
-	r.Result = json.RawMessage(fmt.Sprintf(`{"message":"%s"}`, err.Error()))
+	r.Result = ErrorMessage(err)
 	return r
 }
 
-// UpdateResult send result of update source
-func (c *Client) UpdateResult(log *logrus.Entry, req *UpdateResultRequest) error {
-	reqBytes, err := json.Marshal(req)
-	if err != nil {
-		return err
-	}
-
-	_, httpErr := c.req(log, "UpdateResult", reqBytes)
-
-	natsErr := c.nats.Publish(c.getPublishTopic("UpdateResult", req.ID), reqBytes)
-	if natsErr != nil || httpErr != nil {
-		return fmt.Errorf("http: %+v, nats: %+v", httpErr, natsErr)
-	}
-
-	return nil
-}
-
 // CheckoutResult -=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=
 
 type CheckoutResultRequestType string
@@ -151,54 +139,89 @@ const (
 )
 
 type CheckoutResultRequest struct {
-	ID     string               `json:"id"`
-	Type   RunResultRequestType `json:"type"`
-	Result json.RawMessage      `json:"result"`
+	ID             string               `json:"id"`
+	Type           RunResultRequestType `json:"type"`
+	Result         json.RawMessage      `json:"result"`
+	IdempotencyKey string               `json:"idempotencyKey,omitempty"`
+	// Callback overrides transports of result, it is not sent to gateway
+	Callback *Callback `json:"-"`
 }
 
 func (r *CheckoutResultRequest) SetErr(err error) *CheckoutResultRequest {
 	r.Type = RunResultRequestTypeErr
-	r.Result = json.RawMessage(fmt.Sprintf(`{"message":"%s"}`, err.Error()))
+	r.Result = ErrorMessage(err)
 	return r
 }
 
-// CheckoutResult send result of checkout to commit
-func (c *Client) CheckoutResult(log *logrus.Entry, req *CheckoutResultRequest) error {
+// -=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-
+
+func (c *Client) reqRegisterUnregister(log *logrus.Entry, method string, req *ServiceData) error {
 	reqBytes, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
 
-	_, httpErr := c.req(log, "CheckoutResult", reqBytes)
-
-	natsErr := c.nats.Publish(c.getPublishTopic("CheckoutResult", req.ID), reqBytes)
-	if natsErr != nil || httpErr != nil {
-		return fmt.Errorf("http: %+v, nats: %+v", httpErr, natsErr)
+	if _, err := c.req(context.Background(), log, method, reqBytes, ""); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// -=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-=-
+// sendHTTP send result over http to url or to gateway if url is empty,
+// key is sent in Idempotency-Key header
+func (c *Client) sendHTTP(ctx context.Context, log *logrus.Entry, url, method, key string, data json.RawMessage) error {
+	client := c.callbackClient
+	if url == "" {
+		url, client = c.basePath+rpcURI, c.client
+	}
+	_, err := c.reqURL(ctx, log, client, url, method, data, key)
+	metrics.ObserveCallback(TransportHTTP, method, err)
+	return err
+}
 
-func (c *Client) reqRegisterUnregister(log *logrus.Entry, method string, req *ServiceData) error {
-	reqBytes, err := json.Marshal(req)
-	if err != nil {
-		return err
+// sendNATS publish result to subject or to topic of method if subject is empty,
+// NATS messages have no headers, so trace context is added to `traceContext` field of result
+func (c *Client) sendNATS(ctx context.Context, subject, method, id string, data json.RawMessage) error {
+	if subject == "" {
+		subject = c.getPublishTopic(method, id)
 	}
+	err := c.nats.Publish(subject, withTraceContext(data, tracing.Inject(ctx)))
+	metrics.ObserveCallback(TransportNATS, method, err)
+	return err
+}
 
-	if _, err := c.req(log, method, reqBytes); err != nil {
-		return err
+// withTraceContext add trace context to JSON object, data is returned as is if it is not an object
+func withTraceContext(data json.RawMessage, traceContext map[string]string) json.RawMessage {
+	if len(traceContext) == 0 {
+		return data
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return data
+	}
+	traceBytes, err := json.Marshal(traceContext)
+	if err != nil {
+		return data
+	}
+	obj["traceContext"] = traceBytes
+	res, err := json.Marshal(obj)
+	if err != nil {
+		return data
 	}
+	return res
+}
 
-	return nil
+func (c *Client) req(ctx context.Context, log *logrus.Entry, method string, reqBytes json.RawMessage, idempotencyKey string) (json.RawMessage, error) {
+	return c.reqURL(ctx, log, c.client, c.basePath+rpcURI, method, reqBytes, idempotencyKey)
 }
 
-func (c *Client) req(log *logrus.Entry, method string, reqBytes json.RawMessage) (json.RawMessage, error) {
+func (c *Client) reqURL(ctx context.Context, log *logrus.Entry, client *http.Client, url, method string, reqBytes json.RawMessage, idempotencyKey string) (json.RawMessage, error) {
 	log.WithField("Client.Method", "Gateway."+method)
 	reqBody := protocol.Request{
-		Method: method,
-		Data:   reqBytes,
+		Method:       method,
+		Data:         reqBytes,
+		TraceContext: tracing.Inject(ctx),
 	}
 	reqBodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
@@ -206,13 +229,17 @@ func (c *Client) req(log *logrus.Entry, method string, reqBytes json.RawMessage)
 	}
 
 	log.Debugf("Request data: %s", string(reqBodyBytes))
-	httpReq, err := http.NewRequest(http.MethodPost, c.basePath+rpcURI, bytes.NewReader(reqBodyBytes))
+	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(reqBodyBytes))
 	if err != nil {
 		return nil, err
 	}
 	httpReq.Header.Add("Content-Type", "application/json")
+	tracing.InjectHTTP(ctx, httpReq.Header)
+	if idempotencyKey != "" {
+		httpReq.Header.Add("Idempotency-Key", idempotencyKey)
+	}
 
-	httpResp, err := c.client.Do(httpReq)
+	httpResp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -242,6 +269,14 @@ func (c *Client) req(log *logrus.Entry, method string, reqBytes json.RawMessage)
 	return respBody.Result, nil
 }
 
+// ErrorMessage return json object with message of error
+func ErrorMessage(err error) json.RawMessage {
+	msg, _ := json.Marshal(struct {
+		Message string `json:"message"`
+	}{err.Error()})
+	return msg
+}
+
 func (c *Client) getPublishTopic(name string, id string) string {
 	return fmt.Sprintf("%s.%s.%s", c.natsCfg.TopicPrefix, name, id)
 }
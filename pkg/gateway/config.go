@@ -12,6 +12,19 @@ type Config struct {
 	Port                  int
 	ClientTimeoutInSecond int
 	RegisterPeriodInSec   int
+	// DeliveryDir keep results which are not delivered to gateway yet,
+	// it is required by service and is used only by one instance
+	DeliveryDir      string
+	RetryMaxAttempts int
+	RetryInitialMs   int
+	RetryMaxSec      int
+	// WorkerWaitSec is max time which worker waits for delivery of result before exit
+	WorkerWaitSec int
+	// Transports of results: http, nats or both
+	Transports string
+	// CallbackHosts allow only these hosts in callback url of request, `*.example.com` matches subdomains.
+	// If it is empty, any host is allowed except loopback, private and link-local addresses
+	CallbackHosts []string
 }
 
 // Decode for envconfig
@@ -46,6 +59,41 @@ func (c *Config) Decode(data string) error {
 				return err
 			}
 			c.RegisterPeriodInSec = v
+		case "transports":
+			c.Transports = paramArr[1]
+		case "callbackHosts":
+			c.CallbackHosts = make([]string, 0)
+			for _, host := range strings.Split(paramArr[1], ",") {
+				if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+					c.CallbackHosts = append(c.CallbackHosts, host)
+				}
+			}
+		case "deliveryDir":
+			c.DeliveryDir = paramArr[1]
+		case "retryMaxAttempts":
+			v, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.RetryMaxAttempts = v
+		case "retryInitialMs":
+			v, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.RetryInitialMs = v
+		case "retryMaxSec":
+			v, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.RetryMaxSec = v
+		case "workerWaitSec":
+			v, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.WorkerWaitSec = v
 		default:
 			return fmt.Errorf("unknown param '%s' for part of GateWay env", paramArr[0])
 		}
@@ -61,5 +109,30 @@ func GetDefaultConfig() Config {
 		Port:                  4000,
 		ClientTimeoutInSecond: 5,
 		RegisterPeriodInSec:   10,
+		RetryMaxAttempts:      10,
+		RetryInitialMs:        500,
+		RetryMaxSec:           60,
+		WorkerWaitSec:         300,
+		Transports:            TransportBoth,
+	}
+}
+
+// Validate config of gateway
+func (c *Config) Validate() error {
+	if c.RegisterPeriodInSec < 0 {
+		return fmt.Errorf("registerPeriodInSec should not be negative")
+	}
+	if c.Transports != TransportHTTP && c.Transports != TransportNATS && c.Transports != TransportBoth {
+		return fmt.Errorf("transports should be '%s', '%s' or '%s'", TransportHTTP, TransportNATS, TransportBoth)
+	}
+	if c.RetryMaxAttempts < 1 {
+		return fmt.Errorf("retryMaxAttempts should be positive")
 	}
+	if c.RetryInitialMs < 1 || c.RetryMaxSec < 1 {
+		return fmt.Errorf("retryInitialMs and retryMaxSec should be positive")
+	}
+	if c.WorkerWaitSec < 1 {
+		return fmt.Errorf("workerWaitSec should be positive")
+	}
+	return nil
 }
@@ -0,0 +1,477 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrDeliveryNotFound is returned when delivery with key is not known
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// deliveryLockFile is created in delivery dir, so dir isn't shared by instances of service
+const deliveryLockFile = ".lock"
+
+// Delivery is result which is sent to gateway until all its transports accept it
+type Delivery struct {
+	// Key is idempotency key, it is the same for all attempts of the same result
+	Key    string          `json:"key"`
+	Method string          `json:"method"`
+	ID     string          `json:"id"`
+	Data   json.RawMessage `json:"data"`
+	// Callback is target of result from request, transports from config are used if it is nil
+	Callback *Callback `json:"callback,omitempty"`
+	// TraceContext is W3C trace context of job which produced result
+	TraceContext map[string]string `json:"traceContext,omitempty"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	Attempts     int               `json:"attempts"`
+	// NextAttempt is time of next try, zero for dead delivery
+	NextAttempt time.Time `json:"nextAttempt,omitempty"`
+	LastError   string    `json:"lastError,omitempty"`
+	// HTTPDone and NATSDone are set when transport accepted result, it is not sent again
+	HTTPDone bool `json:"httpDone"`
+	NATSDone bool `json:"natsDone"`
+	// Dead is set when all attempts are failed, delivery waits for manual redelivery
+	Dead bool `json:"dead"`
+}
+
+// sender send results to gateway with one transport
+type sender interface {
+	sendHTTP(ctx context.Context, log *logrus.Entry, url, method, key string, data json.RawMessage) error
+	sendNATS(ctx context.Context, subject, method, id string, data json.RawMessage) error
+}
+
+// Dispatcher deliver results to gateway in background with exponential backoff,
+// undelivered results are kept on disk and are sent again after restart
+type Dispatcher struct {
+	cfg    Config
+	client sender
+	// dir keep undelivered results, it is empty for dispatcher of worker
+	dir   string
+	lock  *os.File
+	mu    sync.Mutex
+	queue map[string]*Delivery
+	// wake signals loop about new delivery
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewDispatcher init dispatcher of service and load undelivered results from dir,
+// dir is required and is locked, so it can't be used by other instance
+func NewDispatcher(log *logrus.Entry, cfg Config, client *Client) (*Dispatcher, error) {
+	return newDispatcher(log, cfg, client)
+}
+
+// NewWorkerDispatcher init dispatcher of worker, results are kept only in memory,
+// so worker doesn't replay results of service from delivery dir
+func NewWorkerDispatcher(cfg Config, client *Client) *Dispatcher {
+	return newMemoryDispatcher(cfg, client)
+}
+
+func newMemoryDispatcher(cfg Config, client sender) *Dispatcher {
+	return &Dispatcher{
+		cfg:    cfg,
+		client: client,
+		queue:  make(map[string]*Delivery),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+func newDispatcher(log *logrus.Entry, cfg Config, client sender) (*Dispatcher, error) {
+	if cfg.DeliveryDir == "" {
+		return nil, fmt.Errorf("deliveryDir of gateway config is required")
+	}
+	d := newMemoryDispatcher(cfg, client)
+	d.dir = cfg.DeliveryDir
+	if err := os.MkdirAll(d.dir, 0700); err != nil {
+		return nil, err
+	}
+	lock, err := lockDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	d.lock = lock
+	files, err := filepath.Glob(filepath.Join(d.dir, "*.json"))
+	if err != nil {
+		d.unlock()
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			d.unlock()
+			return nil, err
+		}
+		var delivery Delivery
+		if err := json.Unmarshal(data, &delivery); err != nil {
+			log.WithError(err).Warnf("Skip broken delivery file %s", file)
+			continue
+		}
+		// replay right after start
+		if !delivery.Dead {
+			delivery.NextAttempt = time.Now()
+		}
+		d.queue[delivery.Key] = &delivery
+	}
+	if len(d.queue) != 0 {
+		log.Infof("Loaded %d undelivered results", len(d.queue))
+	}
+	return d, nil
+}
+
+// RunResult queue result of run
+func (d *Dispatcher) RunResult(ctx context.Context, log *logrus.Entry, req *RunResultRequest) error {
+	req.IdempotencyKey = idempotencyKey("RunResult", req.ID, string(req.Type), req.Result)
+	return d.dispatch(ctx, log, "RunResult", req.ID, req.IdempotencyKey, req.Callback, req)
+}
+
+// UpdateResult queue result of update source
+func (d *Dispatcher) UpdateResult(ctx context.Context, log *logrus.Entry, req *UpdateResultRequest) error {
+	req.IdempotencyKey = idempotencyKey("UpdateResult", req.ID, string(req.Type), req.Result)
+	return d.dispatch(ctx, log, "UpdateResult", req.ID, req.IdempotencyKey, req.Callback, req)
+}
+
+// CheckoutResult queue result of checkout to commit
+func (d *Dispatcher) CheckoutResult(ctx context.Context, log *logrus.Entry, req *CheckoutResultRequest) error {
+	req.IdempotencyKey = idempotencyKey("CheckoutResult", req.ID, string(req.Type), req.Result)
+	return d.dispatch(ctx, log, "CheckoutResult", req.ID, req.IdempotencyKey, req.Callback, req)
+}
+
+// dispatch persist delivery and wake loop, error is returned only if delivery can't be saved,
+// trace context of ctx is kept, so callbacks continue trace of job
+func (d *Dispatcher) dispatch(ctx context.Context, log *logrus.Entry, method, id, key string, callback *Callback, req interface{}) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	useHTTP, useNATS := useTransports(d.cfg.Transports, callback)
+	delivery := &Delivery{
+		Key:          key,
+		Method:       method,
+		ID:           id,
+		Data:         data,
+		Callback:     callback,
+		TraceContext: tracing.Inject(ctx),
+		CreatedAt:    time.Now(),
+		NextAttempt:  time.Now(),
+		// not used transports are done from the start
+		HTTPDone: !useHTTP,
+		NATSDone: !useNATS,
+	}
+	d.mu.Lock()
+	if _, ok := d.queue[key]; ok {
+		d.mu.Unlock()
+		log.Infof("Result %s is already queued for delivery", key)
+		return nil
+	}
+	d.queue[key] = delivery
+	err = d.saveLocked(delivery)
+	d.mu.Unlock()
+	d.notify()
+	return err
+}
+
+// ValidateCallback validate callback of request with allowed hosts from config
+func (d *Dispatcher) ValidateCallback(callback *Callback) error {
+	return callback.Validate(d.cfg.CallbackHosts)
+}
+
+// List return undelivered results, oldest first
+func (d *Dispatcher) List() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	res := make([]Delivery, 0, len(d.queue))
+	for _, delivery := range d.queue {
+		res = append(res, *delivery)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return res
+}
+
+// Redeliver schedule delivery for immediate attempt, dead delivery gets all attempts again
+func (d *Dispatcher) Redeliver(key string) error {
+	d.mu.Lock()
+	delivery, ok := d.queue[key]
+	if !ok {
+		d.mu.Unlock()
+		return ErrDeliveryNotFound
+	}
+	delivery.Dead = false
+	delivery.Attempts = 0
+	delivery.NextAttempt = time.Now()
+	err := d.saveLocked(delivery)
+	d.mu.Unlock()
+	d.notify()
+	return err
+}
+
+// Pending return count of deliveries which are still retried
+func (d *Dispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	count := 0
+	for _, delivery := range d.queue {
+		if !delivery.Dead {
+			count++
+		}
+	}
+	return count
+}
+
+// Wait block until there are no pending deliveries or ctx is done
+func (d *Dispatcher) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for d.Pending() != 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Run deliver results until shutdown
+func (d *Dispatcher) Run(log *logrus.Entry) error {
+	log = log.WithField("component", "dispatcher")
+	ctx, cancel := context.WithCancel(context.Background())
+	d.mu.Lock()
+	d.cancel = cancel
+	d.mu.Unlock()
+	defer close(d.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.wake:
+		case <-timer.C:
+		}
+		next := d.deliverDue(log)
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next)
+	}
+}
+
+// Shutdown stop loop and release delivery dir, undelivered results stay on disk
+func (d *Dispatcher) Shutdown(ctx context.Context, log *logrus.Entry) error {
+	d.mu.Lock()
+	cancel := d.cancel
+	d.mu.Unlock()
+	if cancel == nil {
+		d.unlock()
+		return nil
+	}
+	cancel()
+	select {
+	case <-d.done:
+		d.unlock()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliverDue try all due deliveries and return time until next one
+func (d *Dispatcher) deliverDue(log *logrus.Entry) time.Duration {
+	d.mu.Lock()
+	due := make([]*Delivery, 0)
+	for _, delivery := range d.queue {
+		if !delivery.Dead && !delivery.NextAttempt.After(time.Now()) {
+			due = append(due, delivery)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, delivery := range due {
+		d.attempt(log, delivery)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	next := time.Hour
+	for _, delivery := range d.queue {
+		if delivery.Dead {
+			continue
+		}
+		if wait := time.Until(delivery.NextAttempt); wait < next {
+			next = wait
+		}
+	}
+	if next < 0 {
+		next = 0
+	}
+	return next
+}
+
+// attempt send delivery with transports which didn't accept it yet
+func (d *Dispatcher) attempt(log *logrus.Entry, delivery *Delivery) {
+	log = log.WithField("key", delivery.Key)
+	d.mu.Lock()
+	httpDone, natsDone := delivery.HTTPDone, delivery.NATSDone
+	d.mu.Unlock()
+
+	var url, subject string
+	if delivery.Callback != nil {
+		url, subject = delivery.Callback.URL, delivery.Callback.Subject
+	}
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), delivery.TraceContext), "gateway "+delivery.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("request.id", delivery.ID),
+			attribute.Int("delivery.attempt", delivery.Attempts+1),
+		),
+	)
+	errs := make([]string, 0)
+	if !httpDone {
+		if err := d.client.sendHTTP(ctx, log, url, delivery.Method, delivery.Key, delivery.Data); err != nil {
+			errs = append(errs, "http: "+err.Error())
+		} else {
+			httpDone = true
+		}
+	}
+	if !natsDone {
+		if err := d.client.sendNATS(ctx, subject, delivery.Method, delivery.ID, delivery.Data); err != nil {
+			errs = append(errs, "nats: "+err.Error())
+		} else {
+			natsDone = true
+		}
+	}
+
+	var attemptErr error
+	if len(errs) != 0 {
+		attemptErr = errors.New(strings.Join(errs, ", "))
+	}
+	tracing.End(span, attemptErr)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delivery.HTTPDone, delivery.NATSDone = httpDone, natsDone
+	delivery.Attempts++
+	if len(errs) == 0 {
+		log.Infof("%s %s is delivered to gateway", delivery.Method, delivery.ID)
+		delete(d.queue, delivery.Key)
+		d.removeLocked(log, delivery.Key)
+		return
+	}
+	delivery.LastError = strings.Join(errs, ", ")
+	if delivery.Attempts >= d.cfg.RetryMaxAttempts {
+		log.Errorf("%s %s is not delivered after %d attempts: %s",
+			delivery.Method, delivery.ID, delivery.Attempts, delivery.LastError)
+		delivery.Dead = true
+		delivery.NextAttempt = time.Time{}
+	} else {
+		delay := d.backoff(delivery.Attempts)
+		log.Warnf("%s %s is not delivered, retry in %s: %s", delivery.Method, delivery.ID, delay, delivery.LastError)
+		delivery.NextAttempt = time.Now().Add(delay)
+	}
+	if err := d.saveLocked(delivery); err != nil {
+		log.WithError(err).Error("Can't save undelivered result")
+	}
+}
+
+// backoff return delay after attempt, it is doubled on each attempt up to max
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := time.Duration(d.cfg.RetryInitialMs) * time.Millisecond
+	max := time.Duration(d.cfg.RetryMaxSec) * time.Second
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) saveLocked(delivery *Delivery) error {
+	if d.dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	path := d.path(delivery.Key)
+	if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (d *Dispatcher) removeLocked(log *logrus.Entry, key string) {
+	if d.dir == "" {
+		return
+	}
+	if err := os.Remove(d.path(key)); err != nil && !os.IsNotExist(err) {
+		log.WithError(err).Warn("Can't remove file of delivered result")
+	}
+}
+
+func (d *Dispatcher) path(key string) string {
+	return filepath.Join(d.dir, key+".json")
+}
+
+// lockDir take exclusive lock of dir, lock is released on exit of process too
+func lockDir(dir string) (*os.File, error) {
+	file, err := os.OpenFile(filepath.Join(dir, deliveryLockFile), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("delivery dir %s is used by other instance: %s", dir, err)
+	}
+	return file, nil
+}
+
+func (d *Dispatcher) unlock() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.lock != nil {
+		d.lock.Close()
+		d.lock = nil
+	}
+}
+
+// idempotencyKey is hash of result, so the same result has the same key after restart
+func idempotencyKey(method, id, resultType string, result json.RawMessage) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00", method, id, resultType)
+	hash.Write(result)
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
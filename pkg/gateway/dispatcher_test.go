@@ -0,0 +1,347 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/service/nats"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeSender fail http until failHTTP is zero and count sent results
+type fakeSender struct {
+	mu       sync.Mutex
+	failHTTP int
+	http     []string
+	nats     []string
+	targets  []string
+}
+
+func (s *fakeSender) sendHTTP(ctx context.Context, log *logrus.Entry, url, method, key string, data json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failHTTP > 0 {
+		s.failHTTP--
+		return errors.New("gateway is down")
+	}
+	s.http = append(s.http, key)
+	s.targets = append(s.targets, url)
+	return nil
+}
+
+func (s *fakeSender) sendNATS(ctx context.Context, subject, method, id string, data json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nats = append(s.nats, id)
+	s.targets = append(s.targets, subject)
+	return nil
+}
+
+func (s *fakeSender) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.http), len(s.nats)
+}
+
+func testDispatcherConfig(t *testing.T) Config {
+	dir, err := ioutil.TempDir("", "deliveries-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := GetDefaultConfig()
+	cfg.DeliveryDir = dir
+	cfg.RetryInitialMs = 10
+	cfg.RetryMaxSec = 1
+	cfg.RetryMaxAttempts = 3
+	return cfg
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	cfg := testDispatcherConfig(t)
+	defer os.RemoveAll(cfg.DeliveryDir)
+	log := logrus.NewEntry(logrus.New())
+	sender := &fakeSender{failHTTP: 2}
+	d, err := newDispatcher(log, cfg, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go d.Run(log)
+	defer d.Shutdown(context.Background(), log)
+
+	req := &RunResultRequest{ID: "req1", Type: RunResultRequestTypeOK, Result: json.RawMessage(`{}`)}
+	if err := d.RunResult(context.Background(), log, req); err != nil {
+		t.Fatal(err)
+	}
+	if req.IdempotencyKey == "" {
+		t.Error("Idempotency key must be set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	httpCount, natsCount := sender.counts()
+	if httpCount != 1 || natsCount != 1 {
+		t.Errorf("Result must be sent once by each transport, got http %d, nats %d", httpCount, natsCount)
+	}
+	if sender.http[0] != req.IdempotencyKey {
+		t.Errorf("Unexpected idempotency key %s", sender.http[0])
+	}
+	files, _ := filepath.Glob(filepath.Join(cfg.DeliveryDir, "*.json"))
+	if len(files) != 0 || len(d.List()) != 0 {
+		t.Error("Delivered result must be removed")
+	}
+}
+
+func TestDispatcherDeadLetterAndReplay(t *testing.T) {
+	cfg := testDispatcherConfig(t)
+	defer os.RemoveAll(cfg.DeliveryDir)
+	log := logrus.NewEntry(logrus.New())
+	sender := &fakeSender{failHTTP: 100}
+	d, err := newDispatcher(log, cfg, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go d.Run(log)
+
+	req := &UpdateResultRequest{ID: "req2", Type: UpdateResultRequestTypeOK, Result: json.RawMessage(`{}`)}
+	if err := d.UpdateResult(context.Background(), log, req); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Shutdown(context.Background(), log); err != nil {
+		t.Fatal(err)
+	}
+	list := d.List()
+	if len(list) != 1 || !list[0].Dead || list[0].Attempts != cfg.RetryMaxAttempts || !list[0].NATSDone {
+		t.Fatalf("Dead result is expected, got %+v", list)
+	}
+
+	// result is loaded after restart and is delivered after redelivery
+	sender.failHTTP = 0
+	d, err = newDispatcher(log, cfg, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := d.List(); len(list) != 1 || !list[0].Dead {
+		t.Fatalf("Dead result must be loaded from disk, got %+v", list)
+	}
+	go d.Run(log)
+	defer d.Shutdown(context.Background(), log)
+	if err := d.Redeliver("unknown"); err != ErrDeliveryNotFound {
+		t.Errorf("Unknown key must not be found, got %v", err)
+	}
+	if err := d.Redeliver(req.IdempotencyKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	httpCount, natsCount := sender.counts()
+	if httpCount != 1 || natsCount != 1 {
+		t.Errorf("Transport which accepted result must not be used again, got http %d, nats %d", httpCount, natsCount)
+	}
+}
+
+func TestDispatcherDeliveryDir(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	if _, err := newDispatcher(log, GetDefaultConfig(), &fakeSender{}); err == nil {
+		t.Error("Delivery dir must be required")
+	}
+
+	cfg := testDispatcherConfig(t)
+	defer os.RemoveAll(cfg.DeliveryDir)
+	d, err := newDispatcher(log, cfg, &fakeSender{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newDispatcher(log, cfg, &fakeSender{}); err == nil {
+		t.Error("Delivery dir must not be shared by instances")
+	}
+	req := &RunResultRequest{ID: "req4", Type: RunResultRequestTypeOK, Result: json.RawMessage(`{}`)}
+	if err := d.RunResult(context.Background(), log, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Shutdown(context.Background(), log); err != nil {
+		t.Fatal(err)
+	}
+
+	// dispatcher of worker doesn't replay results of service
+	if list := newMemoryDispatcher(cfg, &fakeSender{}).List(); len(list) != 0 {
+		t.Errorf("Worker must not load results from dir, got %+v", list)
+	}
+	d, err = newDispatcher(log, cfg, &fakeSender{})
+	if err != nil {
+		t.Fatal("Delivery dir must be released after shutdown:", err)
+	}
+	defer d.Shutdown(context.Background(), log)
+	if list := d.List(); len(list) != 1 {
+		t.Errorf("Result must be loaded by next instance, got %+v", list)
+	}
+}
+
+func TestDispatcherTransports(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	for _, tt := range []struct {
+		transports string
+		callback   *Callback
+		http, nats int
+		targets    []string
+	}{
+		{transports: TransportBoth, http: 1, nats: 1, targets: []string{"", ""}},
+		{transports: TransportHTTP, http: 1, targets: []string{""}},
+		{transports: TransportNATS, nats: 1, targets: []string{""}},
+		{transports: TransportBoth, callback: &Callback{Subject: "env1.results"}, nats: 1, targets: []string{"env1.results"}},
+		{transports: TransportNATS, callback: &Callback{URL: "http://localhost:8080/rpc"}, http: 1, targets: []string{"http://localhost:8080/rpc"}},
+	} {
+		cfg := testDispatcherConfig(t)
+		cfg.Transports = tt.transports
+		sender := &fakeSender{}
+		d, err := newDispatcher(log, cfg, sender)
+		if err != nil {
+			t.Fatal(err)
+		}
+		go d.Run(log)
+		req := &CheckoutResultRequest{ID: "req3", Type: RunResultRequestTypeOK, Callback: tt.callback}
+		if err := d.CheckoutResult(context.Background(), log, req); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := d.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		d.Shutdown(context.Background(), log)
+		os.RemoveAll(cfg.DeliveryDir)
+
+		httpCount, natsCount := sender.counts()
+		if httpCount != tt.http || natsCount != tt.nats {
+			t.Errorf("Transports %s, callback %+v: unexpected count http %d, nats %d",
+				tt.transports, tt.callback, httpCount, natsCount)
+		}
+		if strings.Join(sender.targets, ",") != strings.Join(tt.targets, ",") {
+			t.Errorf("Transports %s, callback %+v: unexpected targets %v", tt.transports, tt.callback, sender.targets)
+		}
+	}
+}
+
+func TestCallbackValidate(t *testing.T) {
+	for _, cb := range []Callback{
+		{URL: "http://staxx.example.com:8080/rpc"},
+		{Subject: "env1.results"},
+		{URL: "https://staxx.example.com/rpc", Subject: "env1.results"},
+		{URL: "https://8.8.8.8/rpc"},
+	} {
+		if err := cb.Validate(nil); err != nil {
+			t.Errorf("Callback %+v must be valid, got %s", cb, err)
+		}
+	}
+	for _, cb := range []Callback{
+		{},
+		{URL: "localhost:8080"},
+		{URL: "ftp://staxx.example.com/rpc"},
+		{Subject: "env1.>"},
+		{Subject: "env 1"},
+		// internal addresses are denied by default
+		{URL: "http://localhost:8080/rpc"},
+		{URL: "http://127.0.0.1:8080/rpc"},
+		{URL: "http://10.0.0.5/rpc"},
+		{URL: "http://192.168.1.1/rpc"},
+		{URL: "http://169.254.169.254/latest/meta-data"},
+		{URL: "http://[::1]/rpc"},
+		{URL: "http://[fe80::1]/rpc"},
+		{URL: "http://0.0.0.0/rpc"},
+	} {
+		if err := cb.Validate(nil); err == nil {
+			t.Errorf("Callback %+v must be invalid", cb)
+		}
+	}
+
+	allowed := []string{"staxx.local", "*.example.com"}
+	for url, valid := range map[string]bool{
+		"http://staxx.local:8080/rpc":     true,
+		"https://api.example.com/rpc":     true,
+		"https://API.Example.com/rpc":     true,
+		"https://example.com/rpc":         false,
+		"https://evil-example.com/rpc":    false,
+		"https://staxx.local.evil.io/rpc": false,
+		"http://10.0.0.5/rpc":             false,
+	} {
+		cb := Callback{URL: url}
+		if err := cb.Validate(allowed); (err == nil) != valid {
+			t.Errorf("Callback %s with allowed hosts: expected valid %v, got %v", url, valid, err)
+		}
+	}
+}
+
+func TestCallbackClientDeniesInternalAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"ok","result":{}}`))
+	}))
+	defer server.Close()
+	log := logrus.NewEntry(logrus.New())
+
+	// host name which is resolved to loopback address is rejected on connect
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	client := NewClient(GetDefaultConfig(), nil, nats.Config{})
+	err := client.sendHTTP(context.Background(), log, url, "RunResult", "key", json.RawMessage(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "is not allowed") {
+		t.Errorf("Internal address must be denied, got %v", err)
+	}
+
+	cfg := GetDefaultConfig()
+	cfg.CallbackHosts = []string{"localhost"}
+	client = NewClient(cfg, nil, nats.Config{})
+	if err := client.sendHTTP(context.Background(), log, url, "RunResult", "key", json.RawMessage(`{}`)); err != nil {
+		t.Errorf("Allowed host must be used, got %s", err)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := &Dispatcher{cfg: Config{RetryInitialMs: 500, RetryMaxSec: 3}}
+	for attempts, expected := range map[int]time.Duration{
+		1:  500 * time.Millisecond,
+		2:  time.Second,
+		3:  2 * time.Second,
+		4:  3 * time.Second,
+		10: 3 * time.Second,
+	} {
+		if delay := d.backoff(attempts); delay != expected {
+			t.Errorf("Unexpected delay after %d attempts: %s", attempts, delay)
+		}
+	}
+}
+
+func TestWithTraceContext(t *testing.T) {
+	traceContext := map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	data := withTraceContext(json.RawMessage(`{"id":"1","type":"ok"}`), traceContext)
+	var res struct {
+		ID           string            `json:"id"`
+		TraceContext map[string]string `json:"traceContext"`
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "1" || res.TraceContext["traceparent"] != traceContext["traceparent"] {
+		t.Errorf("Result with trace context is expected, got %s", data)
+	}
+	for _, data := range []string{`[1]`, `null`, `"ok"`} {
+		if res := withTraceContext(json.RawMessage(data), traceContext); string(res) != data {
+			t.Errorf("Not object must be returned as is, got %s", res)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -13,23 +14,32 @@ type Registrator struct {
 	tickerDuration time.Duration
 	host           string
 	port           int
+	mu             sync.Mutex
 	registered     bool
 	stopCh         chan bool
+	// ready is checked before registration, instance is registered only when it is ready
+	ready func() bool
 }
 
-// NewRegistrator init registrator
-func NewRegistrator(cfg Config, client *Client, host string, port int) *Registrator {
+// NewRegistrator init registrator, ready can be nil if instance is ready from the start
+func NewRegistrator(cfg Config, client *Client, host string, port int, ready func() bool) *Registrator {
 	return &Registrator{
 		client:         client,
 		tickerDuration: time.Duration(cfg.RegisterPeriodInSec) * time.Second,
 		host:           host,
 		port:           port,
 		stopCh:         make(chan bool, 1),
+		ready:          ready,
 	}
 }
 
 // Run registrator
 func (r *Registrator) Run(log *logrus.Entry) error {
+	// standalone instance is not registered
+	if r.tickerDuration == 0 {
+		log.Info("Registration on gateway is disabled")
+		return nil
+	}
 	ticker := time.NewTicker(r.tickerDuration)
 	//nolint:megacheck
 	for {
@@ -37,6 +47,10 @@ func (r *Registrator) Run(log *logrus.Entry) error {
 		case <-r.stopCh:
 			return nil
 		case <-ticker.C:
+			if r.ready != nil && !r.ready() {
+				log.Debug("Instance is not ready for registration on gateway")
+				continue
+			}
 			err := r.client.Register(
 				log.WithField("component", "gateway_client"),
 				&ServiceData{
@@ -48,18 +62,32 @@ func (r *Registrator) Run(log *logrus.Entry) error {
 				log.WithError(err).Warn("Can't register instance on gateway")
 				continue
 			}
+			r.mu.Lock()
 			r.registered = true
+			r.mu.Unlock()
 			return nil
 		}
 	}
 }
 
+// Enabled return false for standalone instance which is not registered on gateway
+func (r *Registrator) Enabled() bool {
+	return r.tickerDuration != 0
+}
+
+// Registered return true if instance is registered on gateway
+func (r *Registrator) Registered() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.registered
+}
+
 //Shutdown unregister from gateway
 func (r *Registrator) Shutdown(ctx context.Context, log *logrus.Entry) error {
 	log.Debug("Start graceful shutdown registrator")
 	defer log.Debug("Graceful shutdown registrator: done")
 	r.stopCh <- true
-	if !r.registered {
+	if !r.Registered() {
 		log.Info("Deployment was not registered")
 		return nil
 	}
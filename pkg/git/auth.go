@@ -0,0 +1,227 @@
+package git
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// Types of credentials
+const (
+	CredentialToken = "token"
+	CredentialBasic = "basic"
+	CredentialSSH   = "ssh"
+)
+
+// tokenUsername is username for token in basic auth, it is accepted by github and gitlab
+const tokenUsername = "x-access-token"
+
+// Credential for one host, Secret is token, password or path to ssh key
+type Credential struct {
+	Type     string
+	Username string
+	Secret   string
+}
+
+// AuthConfig is credentials by host, it is never printed with secrets
+type AuthConfig struct {
+	Hosts map[string]Credential
+}
+
+// Decode for envconfig, format is `host=token:value;host=basic:user:password;host=ssh:/path/to/key`
+func (c *AuthConfig) Decode(data string) error {
+	c.Hosts = make(map[string]Credential)
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.SplitN(p, "=", 2)
+		if len(paramArr) != 2 || paramArr[0] == "" {
+			return fmt.Errorf("bad param in part of GitAuth env for host '%s'", paramArr[0])
+		}
+		credArr := strings.SplitN(paramArr[1], ":", 2)
+		if len(credArr) != 2 || credArr[1] == "" {
+			return fmt.Errorf("bad credential in part of GitAuth env for host '%s'", paramArr[0])
+		}
+		cred := Credential{Type: credArr[0], Secret: credArr[1]}
+		switch cred.Type {
+		case CredentialToken, CredentialSSH:
+		case CredentialBasic:
+			basicArr := strings.SplitN(credArr[1], ":", 2)
+			if len(basicArr) != 2 {
+				return fmt.Errorf("basic credential for host '%s' should be user:password", paramArr[0])
+			}
+			cred.Username, cred.Secret = basicArr[0], basicArr[1]
+		default:
+			return fmt.Errorf("unknown credential type '%s' for host '%s' in GitAuth env", cred.Type, paramArr[0])
+		}
+		c.Hosts[paramArr[0]] = cred
+	}
+
+	return nil
+}
+
+// Validate cfg after load
+func (c *AuthConfig) Validate() error {
+	for host, cred := range c.Hosts {
+		if cred.Secret == "" {
+			return errors.New("empty credential for git host " + host)
+		}
+	}
+	return nil
+}
+
+// String print hosts and types of credentials without secrets
+func (c AuthConfig) String() string {
+	hosts := make([]string, 0, len(c.Hosts))
+	for host, cred := range c.Hosts {
+		hosts = append(hosts, fmt.Sprintf("%s=%s:<redacted>", host, cred.Type))
+	}
+	sort.Strings(hosts)
+	return "{" + strings.Join(hosts, ";") + "}"
+}
+
+// GoString is used for %#v
+func (c AuthConfig) GoString() string {
+	return c.String()
+}
+
+// GetDefaultAuthConfig return config without credentials
+func GetDefaultAuthConfig() AuthConfig {
+	return AuthConfig{Hosts: make(map[string]Credential)}
+}
+
+// credential return credential for host of repo url
+func (c *AuthConfig) credential(repoURL string) (Credential, bool) {
+	if c == nil {
+		return Credential{}, false
+	}
+	cred, ok := c.Hosts[urlHost(repoURL)]
+	return cred, ok
+}
+
+// CheckURL return error if credential of host of repo url can't be used with scheme of url:
+// token and basic credentials are sent only over https and ssh key is used only for ssh url,
+// so repo with credential is never fetched without it or with secret over plain http
+func (c *AuthConfig) CheckURL(repoURL string) error {
+	cred, ok := c.credential(repoURL)
+	if !ok {
+		return nil
+	}
+	scheme := urlScheme(repoURL)
+	if cred.Type == CredentialSSH {
+		if scheme != "ssh" {
+			return fmt.Errorf("ssh credential of host '%s' can't be used with %s url", urlHost(repoURL), scheme)
+		}
+		return nil
+	}
+	if scheme != "https" {
+		return fmt.Errorf("%s credential of host '%s' can be used only with https url, got %s url",
+			cred.Type, urlHost(repoURL), scheme)
+	}
+	return nil
+}
+
+// EnvVars return env vars for git binary and nix fetchGit:
+// auth header for all https hosts and ssh command with key for host of repo url,
+// error is returned if credential of host doesn't fit repo url, see CheckURL
+func (c *AuthConfig) EnvVars(repoURL string) (map[string]string, error) {
+	env := make(map[string]string)
+	if c == nil {
+		return env, nil
+	}
+	if err := c.CheckURL(repoURL); err != nil {
+		return nil, err
+	}
+	hosts := make([]string, 0, len(c.Hosts))
+	for host := range c.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	count := 0
+	for _, host := range hosts {
+		cred := c.Hosts[host]
+		if cred.Type == CredentialSSH {
+			continue
+		}
+		// config from env doesn't get into process list and is scoped by url, so it works for submodules
+		env[fmt.Sprintf("GIT_CONFIG_KEY_%d", count)] = fmt.Sprintf("http.https://%s/.extraHeader", host)
+		env[fmt.Sprintf("GIT_CONFIG_VALUE_%d", count)] = "Authorization: Basic " + basicAuth(cred)
+		count++
+	}
+	if count != 0 {
+		env["GIT_CONFIG_COUNT"] = strconv.Itoa(count)
+	}
+	if cred, ok := c.credential(repoURL); ok && cred.Type == CredentialSSH {
+		env["GIT_SSH_COMMAND"] = fmt.Sprintf("ssh -i '%s' -o IdentitiesOnly=yes", strings.Replace(cred.Secret, "'", "", -1))
+	}
+	return env, nil
+}
+
+// GoGitAuth return auth method for go-git, nil if host has no credential,
+// error is returned if credential of host doesn't fit repo url, see CheckURL
+func (c *AuthConfig) GoGitAuth(repoURL string) (transport.AuthMethod, error) {
+	cred, ok := c.credential(repoURL)
+	if !ok {
+		return nil, nil
+	}
+	if err := c.CheckURL(repoURL); err != nil {
+		return nil, err
+	}
+	switch cred.Type {
+	case CredentialSSH:
+		user := "git"
+		if u, err := url.Parse(repoURL); err == nil && u.User != nil {
+			user = u.User.Username()
+		} else if idx := strings.Index(repoURL, "@"); idx != -1 && scpURLRegexp.MatchString(repoURL) {
+			user = repoURL[:idx]
+		}
+		return ssh.NewPublicKeysFromFile(user, cred.Secret, "")
+	case CredentialBasic:
+		return &http.BasicAuth{Username: cred.Username, Password: cred.Secret}, nil
+	default:
+		return &http.BasicAuth{Username: tokenUsername, Password: cred.Secret}, nil
+	}
+}
+
+func basicAuth(cred Credential) string {
+	username := cred.Username
+	if cred.Type == CredentialToken {
+		username = tokenUsername
+	}
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + cred.Secret))
+}
+
+// urlScheme return scheme of url, scp-like address is ssh
+func urlScheme(repoURL string) string {
+	if scpURLRegexp.MatchString(repoURL) {
+		return "ssh"
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme
+}
+
+// urlHost return host of url or scp-like address
+func urlHost(repoURL string) string {
+	if scpURLRegexp.MatchString(repoURL) {
+		host := repoURL[strings.Index(repoURL, "@")+1:]
+		return host[:strings.Index(host, ":")]
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
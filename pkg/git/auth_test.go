@@ -0,0 +1,143 @@
+package git
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func TestAuthConfigDecode(t *testing.T) {
+	var cfg AuthConfig
+	err := cfg.Decode("github.com=token:tok123;gitlab.com=basic:bob:pa:ss;git.example.com=ssh:/keys/id_rsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]Credential{
+		"github.com":      {Type: CredentialToken, Secret: "tok123"},
+		"gitlab.com":      {Type: CredentialBasic, Username: "bob", Secret: "pa:ss"},
+		"git.example.com": {Type: CredentialSSH, Secret: "/keys/id_rsa"},
+	}
+	for host, cred := range expected {
+		if cfg.Hosts[host] != cred {
+			t.Errorf("Credential of %s must be %+v, got %+v", host, cred, cfg.Hosts[host])
+		}
+	}
+
+	for _, data := range []string{"github.com", "github.com=tok123", "github.com=oauth:tok", "github.com=basic:bob", "=token:tok"} {
+		if err := cfg.Decode(data); err == nil {
+			t.Errorf("Decode of '%s' must fail", data)
+		}
+	}
+}
+
+func TestAuthConfigNotPrinted(t *testing.T) {
+	var cfg AuthConfig
+	if err := cfg.Decode("github.com=token:tok123;gitlab.com=basic:bob:pass456"); err != nil {
+		t.Fatal(err)
+	}
+	// config is printed as part of app config on start
+	wrapper := struct {
+		GitAuth AuthConfig
+	}{cfg}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		out := fmt.Sprintf(format, wrapper)
+		if strings.Contains(out, "tok123") || strings.Contains(out, "pass456") {
+			t.Errorf("Secret is printed with %s: %s", format, out)
+		}
+		if !strings.Contains(out, "github.com=token:<redacted>") {
+			t.Errorf("Host must be printed with %s: %s", format, out)
+		}
+	}
+}
+
+func TestAuthConfigEnvVars(t *testing.T) {
+	var cfg AuthConfig
+	if err := cfg.Decode("github.com=token:tok123;git.example.com=ssh:/keys/id_rsa"); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := cfg.EnvVars("https://github.com/makerdao/private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte("x-access-token:tok123"))
+	if env["GIT_CONFIG_COUNT"] != "1" ||
+		env["GIT_CONFIG_KEY_0"] != "http.https://github.com/.extraHeader" ||
+		env["GIT_CONFIG_VALUE_0"] != header {
+		t.Errorf("Unexpected env for https: %+v", env)
+	}
+	if _, ok := env["GIT_SSH_COMMAND"]; ok {
+		t.Errorf("Ssh command must not be set for https host")
+	}
+
+	env, err = cfg.EnvVars("git@git.example.com:makerdao/private.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["GIT_SSH_COMMAND"] != "ssh -i '/keys/id_rsa' -o IdentitiesOnly=yes" {
+		t.Errorf("Unexpected ssh command: %s", env["GIT_SSH_COMMAND"])
+	}
+
+	var empty *AuthConfig
+	if env, err := empty.EnvVars("https://github.com/makerdao/private"); err != nil || len(env) != 0 {
+		t.Errorf("Nil config must not set env vars")
+	}
+}
+
+func TestAuthConfigCheckURL(t *testing.T) {
+	var cfg AuthConfig
+	if err := cfg.Decode("github.com=token:tok123;git.example.com=ssh:/keys/id_rsa"); err != nil {
+		t.Fatal(err)
+	}
+	for _, repoURL := range []string{
+		"https://github.com/makerdao/private",
+		"git@git.example.com:makerdao/private.git",
+		"ssh://git@git.example.com/makerdao/private.git",
+		// hosts without credential are not checked
+		"http://bitbucket.org/makerdao/public",
+		"file:///tmp/repo",
+	} {
+		if err := cfg.CheckURL(repoURL); err != nil {
+			t.Errorf("Url %s must be accepted, got: %s", repoURL, err)
+		}
+	}
+
+	// credential is not sent over http and is not silently skipped for other schemes
+	for _, repoURL := range []string{
+		"http://github.com/makerdao/private",
+		"git@github.com:makerdao/private.git",
+		"git://github.com/makerdao/private",
+		"https://git.example.com/makerdao/private.git",
+	} {
+		if err := cfg.CheckURL(repoURL); err == nil {
+			t.Errorf("Url %s must be rejected", repoURL)
+		}
+		if _, err := cfg.EnvVars(repoURL); err == nil {
+			t.Errorf("Env vars for %s must not be returned", repoURL)
+		}
+		if _, err := cfg.GoGitAuth(repoURL); err == nil {
+			t.Errorf("Go-git auth for %s must not be returned", repoURL)
+		}
+	}
+}
+
+func TestAuthConfigGoGitAuth(t *testing.T) {
+	var cfg AuthConfig
+	if err := cfg.Decode("github.com=token:tok123;gitlab.com=basic:bob:pass456"); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := cfg.GoGitAuth("https://gitlab.com/makerdao/private.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if basic, ok := auth.(*http.BasicAuth); !ok || basic.Username != "bob" || basic.Password != "pass456" {
+		t.Errorf("Unexpected auth for gitlab.com: %#v", auth)
+	}
+	auth, err = cfg.GoGitAuth("https://bitbucket.org/makerdao/public.git")
+	if err != nil || auth != nil {
+		t.Errorf("Host without credential must have no auth, got %v, %v", auth, err)
+	}
+}
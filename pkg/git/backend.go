@@ -0,0 +1,287 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	gogitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
+)
+
+// Backends of git operations
+const (
+	BackendExec  = "exec"
+	BackendGoGit = "gogit"
+)
+
+// Types of refs
+const (
+	RefTypeHead   = "head"
+	RefTypeBranch = "branch"
+	RefTypeTag    = "tag"
+	// RefTypePeeled is `<tag>^{}` ref with commit of annotated tag
+	RefTypePeeled = "peeled"
+	RefTypeOther  = "other"
+)
+
+const peeledSuffix = "^{}"
+
+// Ref is remote ref, URL, Ref and Rev are the same as in ls-remote output: Rev of annotated tag is hash
+// of tag object and peeled ref `<tag>^{}` is listed too, typed fields are added for new clients
+type Ref struct {
+	URL  string `json:"url"`
+	Ref  string `json:"ref"`
+	Rev  string `json:"rev"`
+	Type string `json:"type"`
+	// Name is short name of branch or tag
+	Name string `json:"name"`
+	// Commit is hash of commit of ref, it is peeled commit for annotated tag
+	Commit string `json:"commit"`
+}
+
+// CommitInfo is commit of local repo with refs which point to it
+type CommitInfo struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+	// Refs are full names of refs, e.g. refs/tags/v1 or refs/remotes/origin/master
+	Refs []string `json:"refs"`
+}
+
+// Backend do git operations which don't need working tree
+type Backend interface {
+	// ListRemoteRefs return refs of remote repo
+	ListRemoteRefs(url string) ([]Ref, error)
+	// ListCommits return all commits of local repo, newest first
+	ListCommits(repoPath string) ([]CommitInfo, error)
+}
+
+// NewBackend init backend by name, auth is used for remote repos
+func NewBackend(name string, auth *AuthConfig) (Backend, error) {
+	switch name {
+	case BackendExec:
+		return &ExecBackend{auth: auth}, nil
+	case BackendGoGit:
+		return &GoGitBackend{auth: auth}, nil
+	}
+	return nil, fmt.Errorf("unknown git backend '%s'", name)
+}
+
+// ExecBackend run git binary
+type ExecBackend struct {
+	auth *AuthConfig
+}
+
+// ListRemoteRefs run git ls-remote
+func (b *ExecBackend) ListRemoteRefs(url string) ([]Ref, error) {
+	env, err := b.auth.EnvVars(url)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	stdout, err := runGitEnv(env, "ls-remote", url)
+	metrics.ObserveCommand("ls-remote", start, err)
+	if err != nil {
+		return nil, err
+	}
+	rawRefs := make(map[string]string)
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		rawRefs[fields[1]] = fields[0]
+	}
+	return newRefs(url, rawRefs), nil
+}
+
+// commit fields are separated with unit separator and records with NUL, so subject can contain any text
+const execLogFormat = "--pretty=format:%H%x1f%an <%ae>%x1f%aI%x1f%D%x1f%s%x00"
+
+// ListCommits run git log for all refs
+func (b *ExecBackend) ListCommits(repoPath string) ([]CommitInfo, error) {
+	cmd := exec.Command("git", "log", "--all", "--decorate=full", execLogFormat)
+	cmd.Dir = repoPath
+	stdout, err := runCmd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	commits := make([]CommitInfo, 0)
+	for _, record := range strings.Split(stdout, "\x00") {
+		record = strings.TrimPrefix(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x1f", 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected git log record: %q", record)
+		}
+		date, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, CommitInfo{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Date:    date,
+			Subject: fields[4],
+			Refs:    parseDecoration(fields[3]),
+		})
+	}
+	return commits, nil
+}
+
+// parseDecoration parse full %D decoration like `HEAD -> refs/heads/master, tag: refs/tags/v1`
+func parseDecoration(decoration string) []string {
+	refs := make([]string, 0)
+	if decoration == "" {
+		return refs
+	}
+	for _, ref := range strings.Split(decoration, ", ") {
+		ref = strings.TrimPrefix(ref, "tag: ")
+		if idx := strings.Index(ref, " -> "); idx != -1 {
+			ref = ref[idx+len(" -> "):]
+		}
+		// symbolic refs are skipped like in go-git
+		if ref == "HEAD" || strings.HasSuffix(ref, "/HEAD") {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// GoGitBackend use go-git library without git binary
+type GoGitBackend struct {
+	auth *AuthConfig
+}
+
+// ListRemoteRefs list refs with go-git remote
+func (b *GoGitBackend) ListRemoteRefs(url string) ([]Ref, error) {
+	auth, err := b.auth.GoGitAuth(url)
+	if err != nil {
+		return nil, err
+	}
+	remote := gogit.NewRemote(memory.NewStorage(), &gogitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{url},
+	})
+	start := time.Now()
+	list, err := remote.List(&gogit.ListOptions{PeelingOption: gogit.AppendPeeled, Auth: auth})
+	metrics.ObserveCommand("ls-remote", start, err)
+	if err != nil {
+		return nil, err
+	}
+	rawRefs := make(map[string]string)
+	symbolic := make(map[string]string)
+	for _, ref := range list {
+		if ref.Type() == plumbing.SymbolicReference {
+			symbolic[ref.Name().String()] = ref.Target().String()
+			continue
+		}
+		rawRefs[ref.Name().String()] = ref.Hash().String()
+	}
+	// HEAD is returned as symbolic ref to default branch
+	for name, target := range symbolic {
+		if hash, ok := rawRefs[target]; ok {
+			rawRefs[name] = hash
+		}
+	}
+	return newRefs(url, rawRefs), nil
+}
+
+// ListCommits walk all refs of repo
+func (b *GoGitBackend) ListCommits(repoPath string) ([]CommitInfo, error) {
+	repo, err := gogit.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	refsByHash := make(map[plumbing.Hash][]string)
+	refIter, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	err = refIter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || ref.Name() == plumbing.HEAD {
+			return nil
+		}
+		hash := ref.Hash()
+		// annotated tag points to tag object
+		if tag, err := repo.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			hash = commit.Hash
+		}
+		refsByHash[hash] = append(refsByHash[hash], ref.Name().String())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logIter, err := repo.Log(&gogit.LogOptions{All: true, Order: gogit.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	commits := make([]CommitInfo, 0)
+	err = logIter.ForEach(func(c *object.Commit) error {
+		refs := refsByHash[c.Hash]
+		if refs == nil {
+			refs = make([]string, 0)
+		}
+		sort.Strings(refs)
+		commits = append(commits, CommitInfo{
+			Hash:    c.Hash.String(),
+			Author:  fmt.Sprintf("%s <%s>", c.Author.Name, c.Author.Email),
+			Date:    c.Author.When,
+			Subject: strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0],
+			Refs:    refs,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// newRefs build typed refs from names and hashes, commit of annotated tag is taken from its peeled ref
+func newRefs(url string, rawRefs map[string]string) []Ref {
+	refs := make([]Ref, 0, len(rawRefs))
+	for name, hash := range rawRefs {
+		ref := Ref{URL: url, Ref: name, Rev: hash, Type: RefTypeOther, Name: name, Commit: hash}
+		switch {
+		case name == "HEAD":
+			ref.Type = RefTypeHead
+		case strings.HasPrefix(name, "refs/heads/"):
+			ref.Type = RefTypeBranch
+			ref.Name = strings.TrimPrefix(name, "refs/heads/")
+		case strings.HasPrefix(name, "refs/tags/") && strings.HasSuffix(name, peeledSuffix):
+			ref.Type = RefTypePeeled
+			ref.Name = strings.TrimSuffix(strings.TrimPrefix(name, "refs/tags/"), peeledSuffix)
+		case strings.HasPrefix(name, "refs/tags/"):
+			ref.Type = RefTypeTag
+			ref.Name = strings.TrimPrefix(name, "refs/tags/")
+			if peeled, ok := rawRefs[name+peeledSuffix]; ok {
+				ref.Commit = peeled
+			}
+		}
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Ref < refs[j].Ref
+	})
+	return refs
+}
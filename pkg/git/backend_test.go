@@ -0,0 +1,117 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFixtureRepo create local repo with two commits on master, lightweight tag v1 on first commit,
+// annotated tag v2 and branch feature on second one
+func newFixtureRepo(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "origin")
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		cmd := exec.Command("git", args...)
+		cmd.Dir = path
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s %s", args, err, out)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(path, ".staxx-scenarios"), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "--quiet")
+	git("checkout", "--quiet", "-b", "master")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "init")
+	git("tag", "v1")
+	git("commit", "--quiet", "--allow-empty", "-m", "fix | with pipe")
+	git("tag", "-a", "v2", "-m", "release")
+	git("branch", "feature")
+	return path
+}
+
+func revParse(t *testing.T, repoPath, rev string) string {
+	cmd := exec.Command("git", "rev-parse", rev)
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-backend-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	origin := newFixtureRepo(t, dir)
+	url := "file://" + origin
+	first := revParse(t, origin, "v1")
+	second := revParse(t, origin, "master")
+	tagObject := revParse(t, origin, "v2")
+
+	clonePath := filepath.Join(dir, "clone")
+	if out, err := exec.Command("git", "clone", "--quiet", url, clonePath).CombinedOutput(); err != nil {
+		t.Fatalf("%s %s", err, out)
+	}
+
+	for _, name := range []string{BackendExec, BackendGoGit} {
+		backend, err := NewBackend(name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		refs, err := backend.ListRemoteRefs(url)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		// url, ref and rev are the same as in ls-remote output
+		expected := []Ref{
+			{URL: url, Ref: "HEAD", Rev: second, Type: RefTypeHead, Name: "HEAD", Commit: second},
+			{URL: url, Ref: "refs/heads/feature", Rev: second, Type: RefTypeBranch, Name: "feature", Commit: second},
+			{URL: url, Ref: "refs/heads/master", Rev: second, Type: RefTypeBranch, Name: "master", Commit: second},
+			{URL: url, Ref: "refs/tags/v1", Rev: first, Type: RefTypeTag, Name: "v1", Commit: first},
+			{URL: url, Ref: "refs/tags/v2", Rev: tagObject, Type: RefTypeTag, Name: "v2", Commit: second},
+			{URL: url, Ref: "refs/tags/v2^{}", Rev: second, Type: RefTypePeeled, Name: "v2", Commit: second},
+		}
+		if len(refs) != len(expected) {
+			t.Fatalf("%s: unexpected refs: %+v", name, refs)
+		}
+		for i := range expected {
+			if refs[i] != expected[i] {
+				t.Errorf("%s: ref %d is %+v, expected %+v", name, i, refs[i], expected[i])
+			}
+		}
+
+		commits, err := backend.ListCommits(clonePath)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if len(commits) != 2 {
+			t.Fatalf("%s: unexpected commits: %+v", name, commits)
+		}
+		if commits[0].Hash != second || commits[0].Subject != "fix | with pipe" {
+			t.Errorf("%s: unexpected newest commit: %+v", name, commits[0])
+		}
+		if commits[0].Author != "test <test@example.com>" {
+			t.Errorf("%s: unexpected author: %s", name, commits[0].Author)
+		}
+		refsOfSecond := strings.Join(commits[0].Refs, ",")
+		if refsOfSecond != "refs/heads/master,refs/remotes/origin/feature,refs/remotes/origin/master,refs/tags/v2" {
+			t.Errorf("%s: unexpected refs of newest commit: %s", name, refsOfSecond)
+		}
+		if commits[1].Hash != first || strings.Join(commits[1].Refs, ",") != "refs/tags/v1" {
+			t.Errorf("%s: unexpected first commit: %+v", name, commits[1])
+		}
+	}
+}
@@ -0,0 +1,676 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	cacheIndexFile    = "index.json"
+	cacheMirrorsDir   = "mirrors"
+	cacheWorktreesDir = "worktrees"
+)
+
+var fullRevRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ErrNotCached is returned by GetLocalRepo when commit can't be found without fetch
+var ErrNotCached = errors.New("commit is not cached")
+
+// MirrorStatus is bare mirror of remote repo
+type MirrorStatus struct {
+	URL       string    `json:"url"`
+	Path      string    `json:"path"`
+	SizeBytes int64     `json:"sizeBytes"`
+	LastFetch time.Time `json:"lastFetch"`
+	LastUsed  time.Time `json:"lastUsed"`
+}
+
+// WorktreeStatus is checked out rev of mirror
+type WorktreeStatus struct {
+	URL       string    `json:"url"`
+	Rev       string    `json:"rev"`
+	Path      string    `json:"path"`
+	SizeBytes int64     `json:"sizeBytes"`
+	LastUsed  time.Time `json:"lastUsed"`
+	InUse     bool      `json:"inUse"`
+}
+
+// CacheStatus is content of cache
+type CacheStatus struct {
+	Dir          string           `json:"dir"`
+	SizeBytes    int64            `json:"sizeBytes"`
+	MaxSizeBytes int64            `json:"maxSizeBytes"`
+	Mirrors      []MirrorStatus   `json:"mirrors"`
+	Worktrees    []WorktreeStatus `json:"worktrees"`
+}
+
+type cacheIndex struct {
+	Mirrors   map[string]*MirrorStatus   `json:"mirrors"`
+	Worktrees map[string]*WorktreeStatus `json:"worktrees"`
+}
+
+// Cache keep bare mirrors of repos and worktree per rev,
+// known rev is returned without network access, least recently used entries are evicted over size limit
+type Cache struct {
+	cfg   Config
+	auth  *AuthConfig
+	mu    sync.Mutex
+	index cacheIndex
+	// inUse is count of not released repos by worktree key
+	inUse map[string]int
+	// urlLocks serialize git commands for the same mirror, see lockURL
+	urlLocks map[string]*urlLock
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewCache init cache in dir from config, index of cache is loaded if exists
+func NewCache(log *logrus.Entry, cfg Config, auth *AuthConfig) (*Cache, error) {
+	c := &Cache{
+		cfg:  cfg,
+		auth: auth,
+		index: cacheIndex{
+			Mirrors:   make(map[string]*MirrorStatus),
+			Worktrees: make(map[string]*WorktreeStatus),
+		},
+		inUse:    make(map[string]int),
+		urlLocks: make(map[string]*urlLock),
+	}
+	for _, dir := range []string{cacheMirrorsDir, cacheWorktreesDir} {
+		if err := os.MkdirAll(filepath.Join(cfg.CacheDir, dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(cfg.CacheDir, cacheIndexFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &c.index); err != nil {
+			return nil, fmt.Errorf("can't parse index of git cache: %s", err)
+		}
+	}
+	// entries removed outside of service are forgotten
+	for url, m := range c.index.Mirrors {
+		if _, err := os.Stat(m.Path); err != nil {
+			delete(c.index.Mirrors, url)
+		}
+	}
+	for key, w := range c.index.Worktrees {
+		_, err := os.Stat(w.Path)
+		if _, ok := c.index.Mirrors[w.URL]; err != nil || !ok {
+			_ = os.RemoveAll(w.Path)
+			delete(c.index.Worktrees, key)
+		}
+	}
+	return c, c.save()
+}
+
+// GetRepo return worktree for commit, mirror is fetched only if rev is unknown or not set
+func (c *Cache) GetRepo(log *logrus.Entry, commit Commit) (*Repo, error) {
+	if err := validateCommit(commit); err != nil {
+		return nil, err
+	}
+	if rev := c.fullRev(commit); rev != "" {
+		if repo := c.acquire(worktreeKey(commit.URL, rev)); repo != nil {
+			return repo, nil
+		}
+	}
+
+	repo, err := c.checkout(log, commit)
+	if err != nil {
+		return nil, err
+	}
+	c.evict(log)
+	return repo, nil
+}
+
+func (c *Cache) checkout(log *logrus.Entry, commit Commit) (*Repo, error) {
+	defer c.lockURL(commit.URL)()
+
+	mirrorPath, err := c.ensureMirror(log, commit)
+	if err != nil {
+		return nil, err
+	}
+	rev, err := resolveRev(mirrorPath, commit)
+	if err != nil {
+		return nil, err
+	}
+	return c.addWorktree(log, commit.URL, mirrorPath, rev)
+}
+
+// GetLocalRepo return worktree for commit from mirror without fetch, ref is resolved with refs of last fetch,
+// ErrNotCached is returned if there is no mirror or rev is unknown
+func (c *Cache) GetLocalRepo(log *logrus.Entry, commit Commit) (*Repo, error) {
+	if err := validateCommit(commit); err != nil {
+		return nil, err
+	}
+	if rev := c.fullRev(commit); rev != "" {
+		if repo := c.acquire(worktreeKey(commit.URL, rev)); repo != nil {
+			return repo, nil
+		}
+	}
+	defer c.lockURL(commit.URL)()
+
+	c.mu.Lock()
+	mirror, ok := c.index.Mirrors[commit.URL]
+	c.mu.Unlock()
+	if !ok {
+		return nil, ErrNotCached
+	}
+	rev, err := resolveRev(mirror.Path, commit)
+	if err != nil {
+		return nil, ErrNotCached
+	}
+	return c.addWorktree(log, commit.URL, mirror.Path, rev)
+}
+
+// addWorktree return worktree of rev from mirror, it is created if it doesn't exist,
+// lock of url has to be held
+func (c *Cache) addWorktree(log *logrus.Entry, url, mirrorPath, rev string) (*Repo, error) {
+	key := worktreeKey(url, rev)
+	if repo := c.acquire(key); repo != nil {
+		return repo, nil
+	}
+
+	path := filepath.Join(c.cfg.CacheDir, cacheWorktreesDir, key)
+	log.Debugf("Adding worktree %s of %s", rev, url)
+	_ = os.RemoveAll(path)
+	if _, err := runGit("--git-dir", mirrorPath, "worktree", "add", "--detach", path, rev); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.index.Worktrees[key] = &WorktreeStatus{
+		URL:       url,
+		Rev:       rev,
+		Path:      path,
+		SizeBytes: dirSize(path),
+		LastUsed:  time.Now(),
+	}
+	c.mu.Unlock()
+	if err := c.save(); err != nil {
+		log.WithError(err).Warn("Can't save index of git cache")
+	}
+	return c.acquire(key), nil
+}
+
+// ensureMirror clone mirror if it doesn't exist and fetch it if rev is unknown,
+// without rev failed fetch is ignored, so cached refs are used offline
+func (c *Cache) ensureMirror(log *logrus.Entry, commit Commit) (string, error) {
+	env, err := c.auth.EnvVars(commit.URL)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	mirror, ok := c.index.Mirrors[commit.URL]
+	c.mu.Unlock()
+
+	if !ok {
+		path := filepath.Join(c.cfg.CacheDir, cacheMirrorsDir, urlKey(commit.URL)+".git")
+		log.Debugf("Cloning mirror of %s", commit.URL)
+		_ = os.RemoveAll(path)
+		start := time.Now()
+		_, err := runGitEnv(env, "clone", "--mirror", "--quiet", commit.URL, path)
+		metrics.ObserveCommand("clone", start, err)
+		if err != nil {
+			_ = os.RemoveAll(path)
+			return "", err
+		}
+		mirror = &MirrorStatus{URL: commit.URL, Path: path, LastFetch: time.Now()}
+	}
+	fetched := false
+	if ok && (commit.Rev == "" || !hasCommit(mirror.Path, commit.Rev)) {
+		log.Debugf("Fetching mirror of %s", commit.URL)
+		start := time.Now()
+		_, err := runGitEnv(env, "--git-dir", mirror.Path, "fetch", "--prune", "--quiet", "origin")
+		metrics.ObserveCommand("fetch", start, err)
+		if err != nil {
+			if commit.Rev != "" {
+				return "", err
+			}
+			log.WithError(err).Warn("Can't fetch mirror, cached refs are used")
+		} else {
+			fetched = true
+		}
+	}
+	size := dirSize(mirror.Path)
+
+	c.mu.Lock()
+	if fetched {
+		mirror.LastFetch = time.Now()
+	}
+	mirror.LastUsed = time.Now()
+	mirror.SizeBytes = size
+	c.index.Mirrors[commit.URL] = mirror
+	c.mu.Unlock()
+	return mirror.Path, nil
+}
+
+// fullRev return full hash of rev of commit, short rev is resolved with mirror without fetch,
+// so it finds worktree by the same key, empty string is returned if rev is not set or unknown
+func (c *Cache) fullRev(commit Commit) string {
+	if commit.Rev == "" || fullRevRegexp.MatchString(commit.Rev) {
+		return commit.Rev
+	}
+	c.mu.Lock()
+	mirror, ok := c.index.Mirrors[commit.URL]
+	c.mu.Unlock()
+	if !ok {
+		return ""
+	}
+	rev, err := resolveRev(mirror.Path, Commit{URL: commit.URL, Rev: commit.Rev})
+	if err != nil {
+		return ""
+	}
+	return rev
+}
+
+// acquire return repo for worktree if it exists and mark it as used
+func (c *Cache) acquire(key string) *Repo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, ok := c.index.Worktrees[key]
+	if !ok {
+		return nil
+	}
+	w.LastUsed = time.Now()
+	c.inUse[key]++
+	var once sync.Once
+	return &Repo{
+		Path: w.Path,
+		Rev:  w.Rev,
+		release: func() {
+			once.Do(func() {
+				c.mu.Lock()
+				defer c.mu.Unlock()
+				c.inUse[key]--
+				if c.inUse[key] <= 0 {
+					delete(c.inUse, key)
+				}
+				if w, ok := c.index.Worktrees[key]; ok {
+					w.LastUsed = time.Now()
+				}
+			})
+		},
+	}
+}
+
+// Status return content of cache
+func (c *Cache) Status() CacheStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := CacheStatus{
+		Dir:          c.cfg.CacheDir,
+		SizeBytes:    c.sizeLocked(),
+		MaxSizeBytes: int64(c.cfg.MaxSizeMB) << 20,
+		Mirrors:      make([]MirrorStatus, 0, len(c.index.Mirrors)),
+		Worktrees:    make([]WorktreeStatus, 0, len(c.index.Worktrees)),
+	}
+	for _, m := range c.index.Mirrors {
+		status.Mirrors = append(status.Mirrors, *m)
+	}
+	for key, w := range c.index.Worktrees {
+		ws := *w
+		ws.InUse = c.inUse[key] > 0
+		status.Worktrees = append(status.Worktrees, ws)
+	}
+	sort.Slice(status.Mirrors, func(i, j int) bool {
+		return status.Mirrors[i].URL < status.Mirrors[j].URL
+	})
+	sort.Slice(status.Worktrees, func(i, j int) bool {
+		return status.Worktrees[i].Path < status.Worktrees[j].Path
+	})
+	return status
+}
+
+// Purge remove worktrees and mirrors of url or all if url is empty,
+// worktrees in use and their mirrors are kept, return count of removed entries
+func (c *Cache) Purge(log *logrus.Entry, url string) (int, error) {
+	c.mu.Lock()
+	keys := make([]string, 0)
+	for key, w := range c.index.Worktrees {
+		if url == "" || w.URL == url {
+			keys = append(keys, key)
+		}
+	}
+	urls := make([]string, 0)
+	for u := range c.index.Mirrors {
+		if url == "" || u == url {
+			urls = append(urls, u)
+		}
+	}
+	c.mu.Unlock()
+
+	removed := 0
+	for _, key := range keys {
+		if c.removeWorktree(log, key) {
+			removed++
+		}
+	}
+	for _, u := range urls {
+		if c.removeMirror(log, u) {
+			removed++
+		}
+	}
+	return removed, c.save()
+}
+
+// evict remove least recently used entries while cache is over size limit
+func (c *Cache) evict(log *logrus.Entry) {
+	if c.cfg.MaxSizeMB == 0 {
+		return
+	}
+	maxSize := int64(c.cfg.MaxSizeMB) << 20
+	for {
+		c.mu.Lock()
+		size := c.sizeLocked()
+		key, url := c.evictCandidateLocked()
+		c.mu.Unlock()
+		if size <= maxSize {
+			break
+		}
+		switch {
+		case key != "":
+			c.removeWorktree(log, key)
+		case url != "":
+			c.removeMirror(log, url)
+		default:
+			log.Warnf("Git cache size %d is over limit, but all entries are in use", size)
+			return
+		}
+	}
+	if err := c.save(); err != nil {
+		log.WithError(err).Warn("Can't save index of git cache")
+	}
+}
+
+// evictCandidateLocked return least recently used worktree which is not in use,
+// or least recently used mirror without worktrees
+func (c *Cache) evictCandidateLocked() (string, string) {
+	var key string
+	var lastUsed time.Time
+	for k, w := range c.index.Worktrees {
+		if c.inUse[k] > 0 {
+			continue
+		}
+		if key == "" || w.LastUsed.Before(lastUsed) {
+			key, lastUsed = k, w.LastUsed
+		}
+	}
+	if key != "" {
+		return key, ""
+	}
+	withWorktrees := make(map[string]bool)
+	for _, w := range c.index.Worktrees {
+		withWorktrees[w.URL] = true
+	}
+	var url string
+	for u, m := range c.index.Mirrors {
+		if withWorktrees[u] {
+			continue
+		}
+		if url == "" || m.LastUsed.Before(lastUsed) {
+			url, lastUsed = u, m.LastUsed
+		}
+	}
+	return "", url
+}
+
+func (c *Cache) removeWorktree(log *logrus.Entry, key string) bool {
+	c.mu.Lock()
+	w, ok := c.index.Worktrees[key]
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+	defer c.lockURL(w.URL)()
+
+	c.mu.Lock()
+	if c.inUse[key] > 0 {
+		c.mu.Unlock()
+		return false
+	}
+	delete(c.index.Worktrees, key)
+	mirror := c.index.Mirrors[w.URL]
+	c.mu.Unlock()
+
+	log.Debugf("Removing worktree %s of %s", w.Rev, w.URL)
+	if err := os.RemoveAll(w.Path); err != nil {
+		log.WithError(err).Warn("Can't remove worktree")
+	}
+	if mirror != nil {
+		if _, err := runGit("--git-dir", mirror.Path, "worktree", "prune"); err != nil {
+			log.WithError(err).Warn("Can't prune worktrees of mirror")
+		}
+	}
+	return true
+}
+
+func (c *Cache) removeMirror(log *logrus.Entry, url string) bool {
+	defer c.lockURL(url)()
+
+	c.mu.Lock()
+	mirror, ok := c.index.Mirrors[url]
+	for _, w := range c.index.Worktrees {
+		if w.URL == url {
+			ok = false
+			break
+		}
+	}
+	if ok {
+		delete(c.index.Mirrors, url)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	log.Debugf("Removing mirror of %s", url)
+	if err := os.RemoveAll(mirror.Path); err != nil {
+		log.WithError(err).Warn("Can't remove mirror")
+	}
+	return true
+}
+
+// Run start background fetch of mirrors
+func (c *Cache) Run(log *logrus.Entry) error {
+	if c.cfg.FetchIntervalSec == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(time.Duration(c.cfg.FetchIntervalSec) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.fetchAll(log)
+			}
+		}
+	}()
+	return nil
+}
+
+// Shutdown stop background fetch
+func (c *Cache) Shutdown(ctx context.Context, log *logrus.Entry) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+	return c.save()
+}
+
+func (c *Cache) fetchAll(log *logrus.Entry) {
+	c.mu.Lock()
+	urls := make([]string, 0, len(c.index.Mirrors))
+	for url := range c.index.Mirrors {
+		urls = append(urls, url)
+	}
+	c.mu.Unlock()
+
+	for _, url := range urls {
+		unlock := c.lockURL(url)
+		c.mu.Lock()
+		mirror, ok := c.index.Mirrors[url]
+		c.mu.Unlock()
+		if ok {
+			env, err := c.auth.EnvVars(mirror.URL)
+			if err == nil {
+				start := time.Now()
+				_, err = runGitEnv(env, "--git-dir", mirror.Path, "fetch", "--prune", "--quiet", "origin")
+				metrics.ObserveCommand("fetch", start, err)
+			}
+			if err != nil {
+				log.WithError(err).Warnf("Can't fetch mirror of %s", url)
+			} else {
+				size := dirSize(mirror.Path)
+				c.mu.Lock()
+				mirror.LastFetch = time.Now()
+				mirror.SizeBytes = size
+				c.mu.Unlock()
+			}
+		}
+		unlock()
+	}
+	if err := c.save(); err != nil {
+		log.WithError(err).Warn("Can't save index of git cache")
+	}
+}
+
+// urlLock is lock of mirror with count of holders and waiters
+type urlLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockURL lock git commands for url and return unlock func,
+// lock is removed when nobody holds or waits for it, so locks of removed mirrors don't stay in memory
+func (c *Cache) lockURL(url string) func() {
+	c.mu.Lock()
+	lock, ok := c.urlLocks[url]
+	if !ok {
+		lock = &urlLock{}
+		c.urlLocks[url] = lock
+	}
+	lock.refs++
+	c.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(c.urlLocks, url)
+		}
+	}
+}
+
+func (c *Cache) sizeLocked() int64 {
+	var size int64
+	for _, m := range c.index.Mirrors {
+		size += m.SizeBytes
+	}
+	for _, w := range c.index.Worktrees {
+		size += w.SizeBytes
+	}
+	return size
+}
+
+// save write index of cache atomically
+func (c *Cache) save() error {
+	c.mu.Lock()
+	data, err := json.Marshal(c.index)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(c.cfg.CacheDir, cacheIndexFile)
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// resolveRev return full hash of commit rev or of ref, default branch is used if both are empty
+func resolveRev(mirrorPath string, commit Commit) (string, error) {
+	candidates := []string{"HEAD"}
+	switch {
+	case commit.Rev != "":
+		candidates = []string{commit.Rev}
+	case commit.Ref != "":
+		ref := strings.TrimPrefix(commit.Ref, "refs/")
+		candidates = []string{"refs/" + ref, "refs/heads/" + ref, "refs/tags/" + ref}
+	}
+	for _, name := range candidates {
+		out, err := runGit("--git-dir", mirrorPath, "rev-parse", "--verify", "--quiet", name+"^{commit}")
+		if err == nil {
+			return strings.TrimSpace(out), nil
+		}
+	}
+	return "", fmt.Errorf("can't find %s in %s", candidates[0], commit.URL)
+}
+
+func hasCommit(mirrorPath, rev string) bool {
+	_, err := runGit("--git-dir", mirrorPath, "cat-file", "-e", rev+"^{commit}")
+	return err == nil
+}
+
+// runGit run git without interactive prompts
+func runGit(args ...string) (string, error) {
+	return runGitEnv(nil, args...)
+}
+
+// runGitEnv run git with additional env vars, e.g. credentials of remote
+func runGitEnv(env map[string]string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), envList(env)...)
+	return runCmd(cmd)
+}
+
+func urlKey(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(hash[:8])
+}
+
+func worktreeKey(url, rev string) string {
+	return urlKey(url) + "-" + rev
+}
+
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !fi.IsDir() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,151 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCache(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	dir, err := ioutil.TempDir("", "git-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	origin := newFixtureRepo(t, dir)
+	url := "file://" + origin
+
+	cache, err := NewCache(log, Config{CacheDir: filepath.Join(dir, "cache")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.GetLocalRepo(log, Commit{URL: url, Ref: "v1"}); err != ErrNotCached {
+		t.Errorf("Expected %v for repo without mirror, got %v", ErrNotCached, err)
+	}
+	repo, err := cache.GetRepo(log, Commit{URL: url, Ref: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Path, ".staxx-scenarios")); err != nil {
+		t.Errorf("Worktree doesn't contain files of repo: %s", err)
+	}
+	if len(repo.Rev) != 40 {
+		t.Errorf("Full hash of commit is expected: %s", repo.Rev)
+	}
+	status := cache.Status()
+	if len(status.Mirrors) != 1 || len(status.Worktrees) != 1 || !status.Worktrees[0].InUse {
+		t.Errorf("Unexpected status of cache: %+v", status)
+	}
+	repo.Release()
+
+	// known rev doesn't need origin
+	if err := os.RemoveAll(origin); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := cache.GetRepo(log, Commit{URL: url, Rev: repo.Rev})
+	if err != nil {
+		t.Fatalf("Known rev must be returned without fetch: %s", err)
+	}
+	if cached.Path != repo.Path {
+		t.Errorf("Cached worktree is expected: %s != %s", cached.Path, repo.Path)
+	}
+	short, err := cache.GetRepo(log, Commit{URL: url, Rev: repo.Rev[:8]})
+	if err != nil {
+		t.Fatalf("Known short rev must be returned without fetch: %s", err)
+	}
+	if short.Path != repo.Path {
+		t.Errorf("Worktree of full hash is expected for short rev: %s != %s", short.Path, repo.Path)
+	}
+	short.Release()
+	// ref is resolved from mirror if origin is not available
+	master, err := cache.GetRepo(log, Commit{URL: url, Ref: "master"})
+	if err != nil {
+		t.Fatalf("Cached refs must be used if fetch failed: %s", err)
+	}
+	master.Release()
+	local, err := cache.GetLocalRepo(log, Commit{URL: url, Ref: "master"})
+	if err != nil {
+		t.Fatalf("Fetched ref must be returned without network: %s", err)
+	}
+	if local.Path != master.Path {
+		t.Errorf("Worktree of master is expected: %s != %s", local.Path, master.Path)
+	}
+	local.Release()
+	if _, err := cache.GetLocalRepo(log, Commit{URL: url, Ref: "unknown"}); err != ErrNotCached {
+		t.Errorf("Expected %v for unknown ref, got %v", ErrNotCached, err)
+	}
+
+	// in use worktree and its mirror are kept
+	removed, err := cache.Purge(log, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("Only released worktree of master can be removed, removed: %d", removed)
+	}
+	if _, err := os.Stat(repo.Path); err != nil {
+		t.Errorf("Worktree in use must be kept: %s", err)
+	}
+	cached.Release()
+	removed, err = cache.Purge(log, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("Worktree and mirror are expected to be removed, removed: %d", removed)
+	}
+	if _, err := os.Stat(repo.Path); !os.IsNotExist(err) {
+		t.Errorf("Worktree dir is expected to be removed: %v", err)
+	}
+	if _, err := cache.GetRepo(log, Commit{URL: url, Rev: repo.Rev}); err == nil {
+		t.Error("Purged repo can't be fetched without origin")
+	}
+	if len(cache.urlLocks) != 0 {
+		t.Errorf("Locks of urls must be removed after use, got %d", len(cache.urlLocks))
+	}
+}
+
+func TestCacheEvict(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	dir, err := ioutil.TempDir("", "git-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	url := "file://" + newFixtureRepo(t, dir)
+
+	// every entry is over 1MB limit
+	cache, err := NewCache(log, Config{CacheDir: filepath.Join(dir, "cache"), MaxSizeMB: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := cache.GetRepo(log, Commit{URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.mu.Lock()
+	for _, w := range cache.index.Worktrees {
+		w.SizeBytes = 2 << 20
+	}
+	cache.mu.Unlock()
+
+	cache.evict(log)
+	if len(cache.Status().Worktrees) != 1 {
+		t.Error("Worktree in use must not be evicted")
+	}
+	repo.Release()
+	cache.evict(log)
+	status := cache.Status()
+	if len(status.Worktrees) != 0 {
+		t.Errorf("Released worktree must be evicted: %+v", status.Worktrees)
+	}
+	if !strings.HasPrefix(status.Dir, dir) {
+		t.Errorf("Unexpected dir of cache: %s", status.Dir)
+	}
+}
@@ -1,16 +1,20 @@
 package git
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
-	"regexp"
 	"strings"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
 )
 
 const (
-	nixExpr = `toString (fetchGit {
+	nixExpr = `let src = fetchGit {
     url = "%s";ref = "%s";%s
-  })`
+  }; in { path = toString src; rev = src.rev; }`
 )
 
 type Commit struct {
@@ -44,34 +48,65 @@ func runCmd(cmd *exec.Cmd) (string, error) {
 	return string(out), nil
 }
 
-func GetRefs(url string) ([]Commit, error) {
-	remoteRefParser := regexp.MustCompile(`(?m)^(.*)[ \t]+(.*)$`)
+// envList convert env vars to list for exec.Cmd, values aren't printed by runCmd
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for name, val := range env {
+		list = append(list, name+"="+val)
+	}
+	return list
+}
 
-	stdout, err := runCmd(exec.Command("git", "ls-remote", url))
-	if err != nil {
-		return nil, err
+// Repo is checked out GIT repo
+type Repo struct {
+	// Path to nix store with content of repo
+	Path string `json:"path"`
+	// Rev is resolved hash of checked out commit
+	Rev string `json:"rev"`
+	// release is called when repo is not used anymore
+	release func()
+}
+
+// Release mark repo as not used, so it can be evicted from cache
+func (r *Repo) Release() {
+	if r.release != nil {
+		r.release()
 	}
+}
 
-	remoteRes := remoteRefParser.FindAllStringSubmatch(stdout, -1)
-	refList := make([]Commit, len(remoteRes))
+// GetRepo fetch repo with nix and return path to it with resolved commit hash
+func GetRepo(commit Commit) (*Repo, error) {
+	return getRepo(commit, nil)
+}
 
-	for i, cr := range remoteRes {
-		refList[i] = Commit{
-			URL: url,
-			Rev: cr[1],
-			Ref: cr[2],
-		}
+// getRepo fetch repo with nix, env vars are passed to git which is run by fetchGit
+func getRepo(commit Commit, env map[string]string) (*Repo, error) {
+	if err := validateCommit(commit); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("nix-instantiate", "--eval", "--json", "--strict", "-E", commitToNix(commit))
+	cmd.Env = append(os.Environ(), envList(env)...)
+	start := time.Now()
+	stdout, err := runCmd(cmd)
+	metrics.ObserveCommand("nix-instantiate", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to checkout GIT repo %s %s: %+v", commit.URL, commit.Rev, err)
+	}
+	var repo Repo
+	if err := json.Unmarshal([]byte(stdout), &repo); err != nil {
+		return nil, fmt.Errorf("Failed to parse repo info %s %s: %+v", commit.URL, commit.Rev, err)
 	}
-	return refList, nil
+	if repo.Path == "" {
+		return nil, fmt.Errorf("Failed to get path to repo %s %s", commit.URL, commit.Rev)
+	}
+	return &repo, nil
 }
 
+// GetRepoPath fetch repo with nix and return path to it
 func GetRepoPath(commit Commit) (string, error) {
-	stdout, err := runCmd(exec.Command("nix-instantiate", "--eval", "--json", "-E", commitToNix(commit)))
+	repo, err := GetRepo(commit)
 	if err != nil {
-		return "", fmt.Errorf("Failed to checkout GIT repo %s %s: %+v", commit.URL, commit.Rev, err)
-	}
-	if stdout == "" {
-		return "", fmt.Errorf("Failed to get path to repo %s %s", commit.URL, commit.Rev)
+		return "", err
 	}
-	return strings.Trim(stdout, `"`), nil
+	return repo.Path, nil
 }
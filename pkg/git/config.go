@@ -0,0 +1,92 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resolvers of repo
+const (
+	ResolverCache = "cache"
+	ResolverNix   = "nix"
+)
+
+// Config of git pkg
+type Config struct {
+	// Backend is used for refs and commit list, exec or gogit
+	Backend string
+	// Resolver is nix for fetchGit on every request or cache, cache is enabled explicitly with own CacheDir
+	Resolver string
+	CacheDir string
+	// MaxSizeMB is limit of cache size, 0 - no limit
+	MaxSizeMB int
+	// FetchIntervalSec is interval of background fetch of mirrors, 0 - disabled
+	FetchIntervalSec int
+}
+
+// Decode for envconfig
+func (c *Config) Decode(data string) error {
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.Split(p, "=")
+		if len(paramArr) != 2 {
+			return fmt.Errorf("bad param in part of Git env '%s'", p)
+		}
+		switch paramArr[0] {
+		case "backend":
+			c.Backend = paramArr[1]
+		case "resolver":
+			c.Resolver = paramArr[1]
+		case "cacheDir":
+			c.CacheDir = paramArr[1]
+		case "maxSizeMb":
+			val, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.MaxSizeMB = val
+		case "fetchIntervalSec":
+			val, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.FetchIntervalSec = val
+		default:
+			return fmt.Errorf("unknown param '%s' for part of Git env", paramArr[0])
+		}
+	}
+
+	return nil
+}
+
+// Validate cfg after load
+func (c *Config) Validate() error {
+	if c.Backend != BackendExec && c.Backend != BackendGoGit {
+		return errors.New("git backend can be only 'exec' or 'gogit'")
+	}
+	if c.Resolver != ResolverCache && c.Resolver != ResolverNix {
+		return errors.New("git resolver can be only 'cache' or 'nix'")
+	}
+	if c.Resolver == ResolverCache && c.CacheDir == "" {
+		return errors.New("cacheDir is required for git cache")
+	}
+	if c.MaxSizeMB < 0 || c.FetchIntervalSec < 0 {
+		return errors.New("maxSizeMb and fetchIntervalSec can't be negative")
+	}
+	return nil
+}
+
+// GetDefaultConfig return default config for git pkg
+func GetDefaultConfig() Config {
+	return Config{
+		Backend:          BackendGoGit,
+		Resolver:         ResolverNix,
+		MaxSizeMB:        2048,
+		FetchIntervalSec: 600,
+	}
+}
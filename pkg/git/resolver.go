@@ -0,0 +1,43 @@
+package git
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Resolver fetch commit of repo and return path to its content,
+// repo has to be released after usage
+type Resolver interface {
+	GetRepo(log *logrus.Entry, commit Commit) (*Repo, error)
+}
+
+// LocalResolver return repo which is already fetched, without network access,
+// ErrNotCached is returned if commit is unknown locally
+type LocalResolver interface {
+	GetLocalRepo(log *logrus.Entry, commit Commit) (*Repo, error)
+}
+
+// NixResolver fetch repo with nix fetchGit on every call
+type NixResolver struct {
+	auth *AuthConfig
+}
+
+// GetRepo fetch repo with nix
+func (r *NixResolver) GetRepo(log *logrus.Entry, commit Commit) (*Repo, error) {
+	env, err := r.auth.EnvVars(commit.URL)
+	if err != nil {
+		return nil, err
+	}
+	return getRepo(commit, env)
+}
+
+// NewResolver init resolver from config, cache is nil for nix resolver
+func NewResolver(log *logrus.Entry, cfg Config, auth *AuthConfig) (Resolver, *Cache, error) {
+	if cfg.Resolver == ResolverNix {
+		return &NixResolver{auth: auth}, nil, nil
+	}
+	cache, err := NewCache(log, cfg, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cache, cache, nil
+}
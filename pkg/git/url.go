@@ -0,0 +1,109 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// scpURLRegexp match scp-like syntax, e.g. git@github.com:makerdao/dss-deploy-scripts.git
+var scpURLRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[A-Za-z0-9._~/-]+$`)
+
+// revRegexp match full or abbreviated commit hash
+var revRegexp = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// ValidateURL check that URL of repo has supported format,
+// URL is used in nix expression, so quotes, spaces and interpolation are not allowed
+func ValidateURL(repoURL string) error {
+	if repoURL == "" {
+		return fmt.Errorf("url is empty")
+	}
+	if strings.ContainsAny(repoURL, "\"\\ \t\n${}") {
+		return fmt.Errorf("url contains not allowed characters")
+	}
+	if scpURLRegexp.MatchString(repoURL) {
+		return nil
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "ssh", "git":
+		if u.Host == "" {
+			return fmt.Errorf("url has no host")
+		}
+	case "file":
+		if u.Path == "" {
+			return fmt.Errorf("url has no path")
+		}
+	default:
+		return fmt.Errorf("unsupported url scheme '%s'", u.Scheme)
+	}
+	return nil
+}
+
+// ValidateRev check that rev is full or abbreviated commit hash, empty rev is allowed
+func ValidateRev(rev string) error {
+	if rev != "" && !revRegexp.MatchString(rev) {
+		return fmt.Errorf("'%s' is not a commit hash", rev)
+	}
+	return nil
+}
+
+// ValidateRef check ref with rules of git check-ref-format (one level names are allowed), empty ref is allowed.
+// Ref is used in nix expression and as argument of git, so quotes, `$` and leading `-` are not allowed too
+func ValidateRef(ref string) error {
+	if ref == "" {
+		return nil
+	}
+	for _, r := range ref {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\\"$", r) {
+			return fmt.Errorf("ref contains not allowed characters")
+		}
+	}
+	if ref == "@" || strings.HasPrefix(ref, "-") || strings.HasPrefix(ref, "/") ||
+		strings.HasSuffix(ref, "/") || strings.HasSuffix(ref, ".") ||
+		strings.Contains(ref, "..") || strings.Contains(ref, "//") || strings.Contains(ref, "@{") {
+		return fmt.Errorf("'%s' is not a valid ref", ref)
+	}
+	for _, part := range strings.Split(ref, "/") {
+		if strings.HasPrefix(part, ".") || strings.HasSuffix(part, ".lock") {
+			return fmt.Errorf("'%s' is not a valid ref", ref)
+		}
+	}
+	return nil
+}
+
+// validateCommit check url, ref and rev of commit before they are passed to nix or git
+func validateCommit(commit Commit) error {
+	if err := ValidateURL(commit.URL); err != nil {
+		return err
+	}
+	if err := ValidateRef(commit.Ref); err != nil {
+		return err
+	}
+	return ValidateRev(commit.Rev)
+}
+
+// RepoName return host and path of repo URL without credentials, scheme and `.git` suffix,
+// e.g. github.com/makerdao/dss-deploy-scripts, it is used where URL can't be shown as is (labels of metrics)
+func RepoName(repoURL string) string {
+	host, path := "", ""
+	if scpURLRegexp.MatchString(repoURL) {
+		host = urlHost(repoURL)
+		path = repoURL[strings.Index(repoURL, ":")+1:]
+	} else {
+		u, err := url.Parse(repoURL)
+		if err != nil {
+			return "unknown"
+		}
+		host, path = u.Hostname(), u.Path
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" {
+		return path
+	}
+	return strings.ToLower(host) + "/" + path
+}
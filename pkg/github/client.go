@@ -5,12 +5,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/makerdao/testchain-deployment/pkg/command"
+	"github.com/makerdao/testchain-deployment/pkg/git"
 )
 
 const (
@@ -19,19 +20,27 @@ const (
 
 //Client of github.com
 type Client struct {
-	cfg              Config
-	baseDir          string
-	commitListRegexp *regexp.Regexp
+	cfg        Config
+	baseDir    string
+	gitBackend git.Backend
+	auth       *git.AuthConfig
 }
 
 //NewClient init client
-func NewClient(cfg Config, baseDir string) *Client {
+func NewClient(cfg Config, baseDir string, gitBackend git.Backend, auth *git.AuthConfig) *Client {
 	return &Client{
-		cfg:     cfg,
-		baseDir: baseDir,
+		cfg:        cfg,
+		baseDir:    baseDir,
+		gitBackend: gitBackend,
+		auth:       auth,
 	}
 }
 
+//repoURL return url of repo for clone
+func (c *Client) repoURL() string {
+	return fmt.Sprintf(cloneTmplt, c.cfg.RepoOwner, c.cfg.RepoName)
+}
+
 //GetRepoName return name of repo for work
 func (c *Client) GetRepoPath() string {
 	return filepath.Join(c.baseDir, c.cfg.RepoName)
@@ -65,24 +74,34 @@ func (c *Client) CleanLoadingRepoIfExists(log *logrus.Entry) error {
 
 //CloneCmd is git clone command
 func (c *Client) CloneCmd(log *logrus.Entry) *command.Error {
+	env, err := c.auth.EnvVars(c.repoURL())
+	if err != nil {
+		return command.NewError(err, nil)
+	}
 	return command.New(
 		exec.Command(
 			"git",
 			"clone",
-			fmt.Sprintf(cloneTmplt, c.cfg.RepoOwner, c.cfg.RepoName),
+			c.repoURL(),
 			c.GetLoadingPath(),
 		),
 	).
 		WithDir(c.baseDir).
+		WithEnvVarsMap(env).
 		Run()
 }
 
 //UpdateSubmodulesCmd run update submodules
 func (c *Client) UpdateSubmodulesCmd(log *logrus.Entry) *command.Error {
+	env, err := c.auth.EnvVars(c.repoURL())
+	if err != nil {
+		return command.NewError(err, nil)
+	}
 	return command.New(
 		exec.Command("git", "submodule", "update", "--init", "--recursive"),
 	).
 		WithDir(c.GetLoadingPath()).
+		WithEnvVarsMap(env).
 		Run()
 }
 
@@ -118,55 +137,37 @@ type Commit struct {
 	Text   string `json:"text"`
 }
 
-const parseCommitRegexp = `(?m)^(.*)\|(.*)\|(.*)\|(.*)\|(.*)$`
-
+//GetCommitList return tags, branches and all commits of repo, newest first
 func (c *Client) GetCommitList(log *logrus.Entry) ([]Commit, *command.Error, error) {
-	if c.commitListRegexp == nil {
-		var err error
-		c.commitListRegexp, err = regexp.Compile(parseCommitRegexp)
-		if err != nil {
-			return nil, nil, err
-		}
-	}
-
-	cmd := command.New(
-		exec.Command("git", "log", "--all", "--pretty=format:%D|%H|%an <%ae>|%aI|%s"),
-	)
-	if err := cmd.WithDir(c.GetRepoPath()).Run(); err != nil {
-		return nil, err, nil
+	commits, err := c.gitBackend.ListCommits(c.GetRepoPath())
+	if err != nil {
+		return nil, nil, err
 	}
 
-	commitRes := c.commitListRegexp.FindAllStringSubmatch(cmd.Stdout.String(), -1)
 	tagList := make([]Commit, 0)
 	branchList := make([]Commit, 0)
-	commitList := make([]Commit, len(commitRes))
-	for i, cr := range commitRes {
-		makeCommit := func(cr []string, ref string) Commit {
+	commitList := make([]Commit, len(commits))
+	for i, ci := range commits {
+		makeCommit := func(ref string) Commit {
 			return Commit{
 				Ref:    ref,
-				Commit: cr[2],
-				Author: cr[3],
-				Date:   cr[4],
-				Text:   cr[5],
+				Commit: ci.Hash,
+				Author: ci.Author,
+				Date:   ci.Date.Format(time.RFC3339),
+				Text:   ci.Subject,
 			}
 		}
-		// Parse refs
-		refRes := strings.Split(cr[1], ", ")
-		for _, ref := range refRes {
-			if strings.HasPrefix(ref, "tag: ") {
+		for _, ref := range ci.Refs {
+			if strings.HasPrefix(ref, "refs/tags/") {
 				// If commit has a tag ref add to tag list
-				tagRef := fmt.Sprintf("tags/%s", strings.TrimPrefix(ref, "tag: "))
-				commit := makeCommit(cr, tagRef)
-				tagList = append(tagList, commit)
-			} else if strings.HasPrefix(ref, "origin/") {
+				tagList = append(tagList, makeCommit(strings.TrimPrefix(ref, "refs/")))
+			} else if strings.HasPrefix(ref, "refs/remotes/origin/") {
 				// If commit has a head ref add to branch list
-				branchRef := strings.TrimPrefix(ref, "origin/")
-				commit := makeCommit(cr, branchRef)
-				branchList = append(branchList, commit)
+				branchList = append(branchList, makeCommit(strings.TrimPrefix(ref, "refs/remotes/origin/")))
 			}
 		}
 		// Add to commit list
-		commitList[i] = makeCommit(cr, "")
+		commitList[i] = makeCommit("")
 	}
 	return append(tagList, append(branchList, commitList...)...), nil, nil
 }
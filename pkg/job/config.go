@@ -0,0 +1,119 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PoolConfig of deployment worker pool, zero for MaxPerRepo means no limit per repo
+type PoolConfig struct {
+	MaxConcurrent int
+	MaxQueue      int
+	MaxPerRepo    int
+}
+
+// Decode for envconfig
+func (c *PoolConfig) Decode(data string) error {
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.Split(p, "=")
+		if len(paramArr) != 2 {
+			return fmt.Errorf("bad param in part of Pool env '%s'", p)
+		}
+		v, err := strconv.Atoi(paramArr[1])
+		if err != nil {
+			return err
+		}
+		switch paramArr[0] {
+		case "maxConcurrent":
+			c.MaxConcurrent = v
+		case "maxQueue":
+			c.MaxQueue = v
+		case "maxPerRepo":
+			c.MaxPerRepo = v
+		default:
+			return fmt.Errorf("unknown param '%s' for part of Pool env", paramArr[0])
+		}
+	}
+
+	return nil
+}
+
+// Validate cfg after load
+func (c *PoolConfig) Validate() error {
+	if c.MaxConcurrent < 1 {
+		return errors.New("maxConcurrent of pool should be positive")
+	}
+	if c.MaxQueue < 0 || c.MaxPerRepo < 0 {
+		return errors.New("maxQueue and maxPerRepo of pool can't be negative")
+	}
+	return nil
+}
+
+// GetDefaultPoolConfig return default config for pool
+func GetDefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxConcurrent: 4,
+		MaxQueue:      50,
+		MaxPerRepo:    0,
+	}
+}
+
+// RegistryConfig of job registry, finished jobs over MaxFinished or older than MaxAgeHours are removed,
+// zero MaxAgeHours means no limit by age
+type RegistryConfig struct {
+	MaxFinished int
+	MaxAgeHours int
+}
+
+// Decode for envconfig
+func (c *RegistryConfig) Decode(data string) error {
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.Split(p, "=")
+		if len(paramArr) != 2 {
+			return fmt.Errorf("bad param in part of Jobs env '%s'", p)
+		}
+		v, err := strconv.Atoi(paramArr[1])
+		if err != nil {
+			return err
+		}
+		switch paramArr[0] {
+		case "maxFinished":
+			c.MaxFinished = v
+		case "maxAgeHours":
+			c.MaxAgeHours = v
+		default:
+			return fmt.Errorf("unknown param '%s' for part of Jobs env", paramArr[0])
+		}
+	}
+
+	return nil
+}
+
+// Validate cfg after load
+func (c *RegistryConfig) Validate() error {
+	if c.MaxFinished < 1 {
+		return errors.New("maxFinished of jobs should be positive")
+	}
+	if c.MaxAgeHours < 0 {
+		return errors.New("maxAgeHours of jobs can't be negative")
+	}
+	return nil
+}
+
+// GetDefaultRegistryConfig return default config for job registry
+func GetDefaultRegistryConfig() RegistryConfig {
+	return RegistryConfig{
+		MaxFinished: 1000,
+		MaxAgeHours: 168,
+	}
+}
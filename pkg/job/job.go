@@ -0,0 +1,48 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/git"
+)
+
+// State of job
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// IsFinished return true for final states
+func (s State) IsFinished() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCancelled
+}
+
+// ErrNotFound is returned when job with id is not registered
+var ErrNotFound = errors.New("job not found")
+
+// ErrAlreadyExists is returned when not finished job with the same id exists
+var ErrAlreadyExists = errors.New("job with the same id is not finished")
+
+// ErrFinished is returned when finished job is finished again
+var ErrFinished = errors.New("job is already finished")
+
+// Job is record about async operation, id of job is request id of operation
+type Job struct {
+	ID         string          `json:"id"`
+	Method     string          `json:"method"`
+	State      State           `json:"state"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	StartedAt  *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+	Commit     *git.Commit     `json:"commit,omitempty"`
+	Scenario   string          `json:"scenario,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      json.RawMessage `json:"error,omitempty"`
+}
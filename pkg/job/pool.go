@@ -0,0 +1,215 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrQueueFull is returned when task can't be started and queue has max depth
+var ErrQueueFull = errors.New("queue of jobs is full")
+
+// ErrPoolStopped is returned for tasks submitted after shutdown and is passed to drop func
+// of tasks which are still queued on shutdown
+var ErrPoolStopped = errors.New("pool is stopped")
+
+// ErrTaskCancelled is passed to drop func of queued task which is cancelled
+var ErrTaskCancelled = errors.New("queued task is cancelled")
+
+type task struct {
+	id string
+	// keys are distinct repos of task, task takes slot of each of them
+	keys []string
+	fn   func()
+	// drop is called instead of fn when task leaves queue without start
+	drop func(err error)
+}
+
+// Pool run tasks with limit of concurrent tasks in total and per key(repo),
+// tasks which can't be started are waiting in FIFO queue
+type Pool struct {
+	cfg          PoolConfig
+	mu           sync.Mutex
+	queue        []task
+	running      int
+	runningByKey map[string]int
+	stopped      bool
+}
+
+// NewPool init pool
+func NewPool(cfg PoolConfig) *Pool {
+	return &Pool{
+		cfg:          cfg,
+		queue:        make([]task, 0),
+		runningByKey: make(map[string]int),
+	}
+}
+
+// Submit start task or put it to queue, return position of task in queue,
+// zero position means task is started immediately. Drop func is optional, it is called with
+// ErrTaskCancelled or ErrPoolStopped when task is removed from queue without start
+func (p *Pool) Submit(id, key string, fn func(), drop func(err error)) (int, error) {
+	return p.SubmitKeys(id, []string{key}, fn, drop)
+}
+
+// SubmitKeys is Submit for task which uses several keys(repos), e.g. pipeline,
+// task is started only when each of its keys has free slot
+func (p *Pool) SubmitKeys(id string, keys []string, fn func(), drop func(err error)) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return 0, ErrPoolStopped
+	}
+	t := task{id: id, keys: uniqueKeys(keys), fn: fn, drop: drop}
+	// every queued task is blocked by limits, so starting new one doesn't break the order
+	if p.canStart(t) {
+		p.start(t)
+		return 0, nil
+	}
+	if len(p.queue) >= p.cfg.MaxQueue {
+		return 0, ErrQueueFull
+	}
+	p.queue = append(p.queue, t)
+	return len(p.queue), nil
+}
+
+// Cancel remove queued task by id and call its drop func with ErrTaskCancelled,
+// false is returned when task is not in queue, e.g. it is already started
+func (p *Pool) Cancel(id string) bool {
+	p.mu.Lock()
+	var dropped *task
+	for i := range p.queue {
+		if p.queue[i].id == id {
+			t := p.queue[i]
+			dropped = &t
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			break
+		}
+	}
+	p.mu.Unlock()
+	if dropped == nil {
+		return false
+	}
+	dropped.dropWith(ErrTaskCancelled)
+	return true
+}
+
+// QueueDepth return count of waiting tasks
+func (p *Pool) QueueDepth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.queue)
+}
+
+// Running return count of running tasks
+func (p *Pool) Running() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
+
+// Run pool, it has no background work
+func (p *Pool) Run(log *logrus.Entry) error {
+	return nil
+}
+
+// Shutdown stop accepting tasks and wait for running ones, queued tasks are not started,
+// their drop funcs are called with ErrPoolStopped
+func (p *Pool) Shutdown(ctx context.Context, log *logrus.Entry) error {
+	log.Debug("Start graceful shutdown pool")
+	defer log.Debug("Graceful shutdown pool: done")
+	p.mu.Lock()
+	p.stopped = true
+	dropped := p.queue
+	p.queue = make([]task, 0)
+	p.mu.Unlock()
+	if len(dropped) > 0 {
+		log.Warnf("Pool stopped with %d queued jobs", len(dropped))
+	}
+	for _, t := range dropped {
+		t.dropWith(ErrPoolStopped)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for p.Running() > 0 {
+		select {
+		case <-ctx.Done():
+			return errors.New("context cancelled, but jobs of pool not completed")
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (t task) dropWith(err error) {
+	if t.drop != nil {
+		t.drop(err)
+	}
+}
+
+func uniqueKeys(keys []string) []string {
+	res := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			res = append(res, key)
+		}
+	}
+	return res
+}
+
+func (p *Pool) canStart(t task) bool {
+	if p.running >= p.cfg.MaxConcurrent {
+		return false
+	}
+	if p.cfg.MaxPerRepo == 0 {
+		return true
+	}
+	for _, key := range t.keys {
+		if p.runningByKey[key] >= p.cfg.MaxPerRepo {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Pool) start(t task) {
+	p.running++
+	for _, key := range t.keys {
+		p.runningByKey[key]++
+	}
+	go func() {
+		defer p.done(t)
+		t.fn()
+	}()
+}
+
+func (p *Pool) done(t task) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running--
+	for _, key := range t.keys {
+		p.runningByKey[key]--
+		if p.runningByKey[key] == 0 {
+			delete(p.runningByKey, key)
+		}
+	}
+	if p.stopped {
+		return
+	}
+	// start first tasks in order which fit in limits
+	queue := p.queue[:0]
+	for _, q := range p.queue {
+		if p.canStart(q) {
+			p.start(q)
+			continue
+		}
+		queue = append(queue, q)
+	}
+	p.queue = queue
+}
@@ -0,0 +1,148 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPoolLimits(t *testing.T) {
+	pool := NewPool(PoolConfig{MaxConcurrent: 2, MaxQueue: 2, MaxPerRepo: 1})
+	release := make(chan struct{})
+	started := make(chan string, 10)
+	fn := func(id string) func() {
+		return func() {
+			started <- id
+			<-release
+		}
+	}
+
+	// Run
+	positions := make(map[string]int)
+	for _, s := range []struct{ id, repo string }{
+		{"a1", "a"},
+		{"a2", "a"}, // blocked by limit per repo
+		{"b1", "b"},
+		{"c1", "c"}, // blocked by max concurrent
+	} {
+		pos, err := pool.Submit(s.id, s.repo, fn(s.id), nil)
+		if err != nil {
+			t.Fatalf("Can't submit %s: %v", s.id, err)
+		}
+		positions[s.id] = pos
+	}
+	if _, err := pool.Submit("d1", "d", fn("d1"), nil); err != ErrQueueFull {
+		t.Errorf("Expected error %v, got %v", ErrQueueFull, err)
+	}
+
+	// Assertion
+	expected := map[string]int{"a1": 0, "a2": 1, "b1": 0, "c1": 2}
+	for id, pos := range expected {
+		if positions[id] != pos {
+			t.Errorf("Expected position %d for %s, got %d", pos, id, positions[id])
+		}
+	}
+	if pool.Running() != 2 || pool.QueueDepth() != 2 {
+		t.Errorf("Expected 2 running and 2 queued tasks, got %d and %d", pool.Running(), pool.QueueDepth())
+	}
+
+	// all tasks are finished after release
+	close(release)
+	done := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for len(done) < 4 {
+		select {
+		case id := <-started:
+			done[id] = true
+		case <-timeout:
+			t.Fatalf("Not all tasks started, started: %v", done)
+		}
+	}
+}
+
+func TestPoolSubmitKeys(t *testing.T) {
+	pool := NewPool(PoolConfig{MaxConcurrent: 3, MaxQueue: 2, MaxPerRepo: 1})
+	release := make(chan struct{})
+	started := make(chan string, 10)
+
+	// pipeline takes slot of each repo, repeated repo takes one slot
+	pos, err := pool.SubmitKeys("p1", []string{"a", "b", "a"}, func() {
+		started <- "p1"
+		<-release
+	}, nil)
+	if err != nil || pos != 0 {
+		t.Fatalf("Expected started pipeline, got position %d and error %v", pos, err)
+	}
+	if pos, err := pool.Submit("b1", "b", func() { started <- "b1" }, nil); err != nil || pos != 1 {
+		t.Errorf("Expected task queued by repo of second stage, got position %d and error %v", pos, err)
+	}
+	if pos, err := pool.Submit("c1", "c", func() { started <- "c1" }, nil); err != nil || pos != 0 {
+		t.Errorf("Expected started task of free repo, got position %d and error %v", pos, err)
+	}
+
+	close(release)
+	done := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for len(done) < 3 {
+		select {
+		case id := <-started:
+			done[id] = true
+		case <-timeout:
+			t.Fatalf("Not all tasks started, started: %v", done)
+		}
+	}
+}
+
+func TestPoolDropQueued(t *testing.T) {
+	log := logrus.WithField("component", "test")
+	pool := NewPool(PoolConfig{MaxConcurrent: 1, MaxQueue: 2})
+	release := make(chan struct{})
+	dropped := make(map[string]error)
+	drop := func(id string) func(err error) {
+		return func(err error) {
+			dropped[id] = err
+		}
+	}
+
+	if _, err := pool.Submit("running", "a", func() { <-release }, drop("running")); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"q1", "q2"} {
+		if _, err := pool.Submit(id, "a", func() { t.Errorf("Dropped task is started") }, drop(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// cancelled task frees its place in queue
+	if !pool.Cancel("q1") {
+		t.Error("Expected queued task to be cancelled")
+	}
+	if pool.Cancel("running") {
+		t.Error("Running task can't be cancelled in pool")
+	}
+	if pool.QueueDepth() != 1 {
+		t.Errorf("Expected 1 queued task after cancel, got %d", pool.QueueDepth())
+	}
+	if _, err := pool.Submit("q3", "a", func() { t.Errorf("Dropped task is started") }, drop("q3")); err != nil {
+		t.Errorf("Expected place in queue after cancel, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	if err := pool.Shutdown(context.Background(), log); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]error{"q1": ErrTaskCancelled, "q2": ErrPoolStopped, "q3": ErrPoolStopped}
+	if len(dropped) != len(expected) {
+		t.Errorf("Expected dropped tasks %v, got %v", expected, dropped)
+	}
+	for id, err := range expected {
+		if dropped[id] != err {
+			t.Errorf("Expected error %v for %s, got %v", err, id, dropped[id])
+		}
+	}
+}
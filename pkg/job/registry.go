@@ -0,0 +1,188 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StorageInterface for job registry
+type StorageInterface interface {
+	UpsertJob(log *logrus.Entry, job Job) error
+	GetJob(log *logrus.Entry, id string) (*Job, error)
+	ListJobs(log *logrus.Entry) ([]Job, error)
+	DeleteJobs(log *logrus.Entry, ids []string) error
+}
+
+// Registry keep state of async operations by request id,
+// it also owns contexts of not finished jobs for cancellation
+type Registry struct {
+	cfg         RegistryConfig
+	storage     StorageInterface
+	mu          sync.Mutex
+	cancelFuncs map[string]context.CancelFunc
+}
+
+// NewRegistry init registry
+func NewRegistry(cfg RegistryConfig, storage StorageInterface) *Registry {
+	return &Registry{
+		cfg:         cfg,
+		storage:     storage,
+		cancelFuncs: make(map[string]context.CancelFunc),
+	}
+}
+
+// Queue register new job in queued state,
+// returned context is done when job is cancelled
+func (r *Registry) Queue(log *logrus.Entry, job Job) (context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cancelFuncs[job.ID]; ok {
+		return nil, ErrAlreadyExists
+	}
+	job.State = StateQueued
+	job.CreatedAt = time.Now()
+	if err := r.storage.UpsertJob(log, job); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancelFuncs[job.ID] = cancel
+	return ctx, nil
+}
+
+// Start mark job as running, finished job can't be started
+func (r *Registry) Start(log *logrus.Entry, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, err := r.storage.GetJob(log, id)
+	if err != nil {
+		return err
+	}
+	if job.State.IsFinished() {
+		return ErrFinished
+	}
+	now := time.Now()
+	job.State = StateRunning
+	job.StartedAt = &now
+	return r.storage.UpsertJob(log, *job)
+}
+
+// Finish set final state of job with payload, payload is saved as result
+// for succeeded job and as error for others, finished jobs over limits of config are removed
+func (r *Registry) Finish(log *logrus.Entry, id string, state State, payload json.RawMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, err := r.storage.GetJob(log, id)
+	if err != nil {
+		return err
+	}
+	if job.State.IsFinished() {
+		return ErrFinished
+	}
+	if cancel, ok := r.cancelFuncs[id]; ok {
+		cancel()
+		delete(r.cancelFuncs, id)
+	}
+	now := time.Now()
+	job.State = state
+	job.FinishedAt = &now
+	if state == StateSucceeded {
+		job.Result = payload
+	} else {
+		job.Error = payload
+	}
+	if err := r.storage.UpsertJob(log, *job); err != nil {
+		return err
+	}
+	if err := r.prune(log); err != nil {
+		log.WithError(err).Error("Can't remove old finished jobs")
+	}
+	return nil
+}
+
+// Cancel not finished job, owner of job is responsible for finishing it with cancelled state,
+// state is checked under lock, so job which is finished concurrently is not cancelled
+func (r *Registry) Cancel(log *logrus.Entry, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, ok := r.cancelFuncs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	job, err := r.storage.GetJob(log, id)
+	if err != nil {
+		return err
+	}
+	if job.State.IsFinished() {
+		return ErrNotFound
+	}
+	cancel()
+	return nil
+}
+
+// Prune remove finished jobs over limits of config
+func (r *Registry) Prune(log *logrus.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.prune(log)
+}
+
+// prune remove finished jobs older than max age and oldest finished jobs over max count
+func (r *Registry) prune(log *logrus.Entry) error {
+	jobs, err := r.storage.ListJobs(log)
+	if err != nil {
+		return err
+	}
+	finished := make([]Job, 0, len(jobs))
+	for _, job := range jobs {
+		if job.State.IsFinished() && job.FinishedAt != nil {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.After(*finished[j].FinishedAt)
+	})
+	var ids []string
+	for i, job := range finished {
+		expired := r.cfg.MaxAgeHours > 0 && time.Since(*job.FinishedAt) > time.Duration(r.cfg.MaxAgeHours)*time.Hour
+		if i >= r.cfg.MaxFinished || expired {
+			ids = append(ids, job.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	log.Debugf("Remove %d finished jobs", len(ids))
+	return r.storage.DeleteJobs(log, ids)
+}
+
+// Get job by id
+func (r *Registry) Get(log *logrus.Entry, id string) (*Job, error) {
+	return r.storage.GetJob(log, id)
+}
+
+// List return jobs sorted by creation time, empty state or method means any
+func (r *Registry) List(log *logrus.Entry, state State, method string) ([]Job, error) {
+	jobs, err := r.storage.ListJobs(log)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Job, 0, len(jobs))
+	for _, job := range jobs {
+		if state != "" && job.State != state {
+			continue
+		}
+		if method != "" && job.Method != method {
+			continue
+		}
+		res = append(res, job)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return res, nil
+}
@@ -0,0 +1,182 @@
+package job
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type mockStorage struct {
+	jobs map[string]Job
+}
+
+func (s *mockStorage) UpsertJob(log *logrus.Entry, job Job) error {
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *mockStorage) GetJob(log *logrus.Entry, id string) (*Job, error) {
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+func (s *mockStorage) DeleteJobs(log *logrus.Entry, ids []string) error {
+	for _, id := range ids {
+		delete(s.jobs, id)
+	}
+	return nil
+}
+
+func (s *mockStorage) ListJobs(log *logrus.Entry) ([]Job, error) {
+	res := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		res = append(res, job)
+	}
+	return res, nil
+}
+
+func TestRegistryLifecycle(t *testing.T) {
+	log := logrus.WithField("component", "test")
+	registry := NewRegistry(GetDefaultRegistryConfig(), &mockStorage{jobs: make(map[string]Job)})
+
+	ctx, err := registry.Queue(log, Job{ID: "id1", Method: "Deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Queue(log, Job{ID: "id1", Method: "Deploy"}); err != ErrAlreadyExists {
+		t.Errorf("Expected error %v for duplicated job, got %v", ErrAlreadyExists, err)
+	}
+	if err := registry.Start(log, "id1"); err != nil {
+		t.Fatal(err)
+	}
+	job, err := registry.Get(log, "id1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != StateRunning || job.StartedAt == nil {
+		t.Errorf("Expected running job with start time, got %+v", job)
+	}
+
+	if err := registry.Cancel(log, "id1"); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() == nil {
+		t.Error("Context of job is not cancelled")
+	}
+	payload := json.RawMessage(`{"message":"cancelled"}`)
+	if err := registry.Finish(log, "id1", StateCancelled, payload); err != nil {
+		t.Fatal(err)
+	}
+	job, err = registry.Get(log, "id1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != StateCancelled || job.FinishedAt == nil || string(job.Error) != string(payload) {
+		t.Errorf("Expected cancelled job with error payload, got %+v", job)
+	}
+	if err := registry.Cancel(log, "id1"); err != ErrNotFound {
+		t.Errorf("Expected error %v for finished job, got %v", ErrNotFound, err)
+	}
+
+	// finished job can be replaced by new one with the same id
+	if _, err := registry.Queue(log, Job{ID: "id1", Method: "Deploy"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegistryList(t *testing.T) {
+	log := logrus.WithField("component", "test")
+	registry := NewRegistry(GetDefaultRegistryConfig(), &mockStorage{jobs: make(map[string]Job)})
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := registry.Queue(log, Job{ID: id, Method: "Deploy"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := registry.Finish(log, "b", StateSucceeded, json.RawMessage(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := registry.List(log, StateQueued, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "a" || jobs[1].ID != "c" {
+		t.Errorf("Expected queued jobs a and c in order of creation, got %+v", jobs)
+	}
+	jobs, err = registry.List(log, "", "Run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("Expected no jobs for Run method, got %+v", jobs)
+	}
+}
+
+func TestRegistryCancelFinished(t *testing.T) {
+	log := logrus.WithField("component", "test")
+	registry := NewRegistry(GetDefaultRegistryConfig(), &mockStorage{jobs: make(map[string]Job)})
+
+	ctx, err := registry.Queue(log, Job{ID: "id1", Method: "Deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Finish(log, "id1", StateSucceeded, json.RawMessage(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Cancel(log, "id1"); err != ErrNotFound {
+		t.Errorf("Expected error %v for succeeded job, got %v", ErrNotFound, err)
+	}
+	if err := registry.Finish(log, "id1", StateCancelled, nil); err != ErrFinished {
+		t.Errorf("Expected error %v for second finish, got %v", ErrFinished, err)
+	}
+	if err := registry.Start(log, "id1"); err != ErrFinished {
+		t.Errorf("Expected error %v for start of finished job, got %v", ErrFinished, err)
+	}
+	job, err := registry.Get(log, "id1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != StateSucceeded {
+		t.Errorf("Expected succeeded job, got %+v", job)
+	}
+	if ctx.Err() == nil {
+		t.Error("Context of finished job is not released")
+	}
+}
+
+func TestRegistryPrune(t *testing.T) {
+	log := logrus.WithField("component", "test")
+	storage := &mockStorage{jobs: make(map[string]Job)}
+	registry := NewRegistry(RegistryConfig{MaxFinished: 2, MaxAgeHours: 1}, storage)
+
+	old := time.Now().Add(-2 * time.Hour)
+	storage.jobs["old"] = Job{ID: "old", State: StateSucceeded, FinishedAt: &old}
+	for _, id := range []string{"a", "b", "c", "running"} {
+		if _, err := registry.Queue(log, Job{ID: id, Method: "Deploy"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := registry.Finish(log, id, StateSucceeded, json.RawMessage(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, id := range []string{"old", "a"} {
+		if _, err := registry.Get(log, id); err != ErrNotFound {
+			t.Errorf("Expected job %s to be removed, got %v", id, err)
+		}
+	}
+	for _, id := range []string{"b", "c", "running"} {
+		if _, err := registry.Get(log, id); err != nil {
+			t.Errorf("Expected job %s to be kept, got %v", id, err)
+		}
+	}
+}
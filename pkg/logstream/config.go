@@ -0,0 +1,63 @@
+package logstream
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Config of log streams
+type Config struct {
+	// BufferLines is count of last lines kept for late subscribers
+	BufferLines int
+	// MaxFinished is count of finished streams kept in memory
+	MaxFinished int
+}
+
+// Decode for envconfig
+func (c *Config) Decode(data string) error {
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.Split(p, "=")
+		if len(paramArr) != 2 {
+			return fmt.Errorf("bad param in part of Logs env '%s'", p)
+		}
+		v, err := strconv.Atoi(paramArr[1])
+		if err != nil {
+			return err
+		}
+		switch paramArr[0] {
+		case "bufferLines":
+			c.BufferLines = v
+		case "maxFinished":
+			c.MaxFinished = v
+		default:
+			return fmt.Errorf("unknown param '%s' for part of Logs env", paramArr[0])
+		}
+	}
+
+	return nil
+}
+
+// Validate cfg after load
+func (c *Config) Validate() error {
+	if c.BufferLines < 1 {
+		return errors.New("bufferLines of logs should be positive")
+	}
+	if c.MaxFinished < 0 {
+		return errors.New("maxFinished of logs can't be negative")
+	}
+	return nil
+}
+
+// GetDefaultConfig return default config for logstream pkg
+func GetDefaultConfig() Config {
+	return Config{
+		BufferLines: 1000,
+		MaxFinished: 50,
+	}
+}
@@ -0,0 +1,213 @@
+package logstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+	"github.com/sirupsen/logrus"
+)
+
+const subscriberBufferSize = 256
+
+// Publisher send line to external subscribers, for example NATS connection
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Line of command output
+type Line struct {
+	Seq    int       `json:"seq"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+}
+
+type stream struct {
+	lines       []Line
+	start       int
+	count       int
+	seq         int
+	subscribers map[chan Line]struct{}
+	writers     []*lineWriter
+	done        bool
+	// redactor hides secrets in lines before they are buffered and published, it is optional
+	redactor *secret.Redactor
+}
+
+func (s *stream) add(line Line) {
+	if s.count < len(s.lines) {
+		s.lines[(s.start+s.count)%len(s.lines)] = line
+		s.count++
+		return
+	}
+	s.lines[s.start] = line
+	s.start = (s.start + 1) % len(s.lines)
+}
+
+func (s *stream) backlog() []Line {
+	res := make([]Line, s.count)
+	for i := 0; i < s.count; i++ {
+		res[i] = s.lines[(s.start+i)%len(s.lines)]
+	}
+	return res
+}
+
+// Hub keeps output of running jobs by job id, publishes every line to NATS
+// and keeps ring buffer of last lines, so late subscribers can catch up
+type Hub struct {
+	cfg         Config
+	log         *logrus.Entry
+	publisher   Publisher
+	topicPrefix string
+	mu          sync.Mutex
+	streams     map[string]*stream
+	finished    []string
+}
+
+// NewHub init hub, publisher is optional
+func NewHub(log *logrus.Entry, cfg Config, publisher Publisher, topicPrefix string) *Hub {
+	return &Hub{
+		cfg:         cfg,
+		log:         log.WithField("component", "logstream"),
+		publisher:   publisher,
+		topicPrefix: topicPrefix,
+		streams:     make(map[string]*stream),
+	}
+}
+
+// Open start new stream for id, previous stream with the same id is dropped,
+// secrets are redacted in lines with redactor if it is not nil
+func (h *Hub) Open(id string, redactor *secret.Redactor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if prev, ok := h.streams[id]; ok {
+		h.finish(prev)
+	}
+	h.streams[id] = &stream{
+		lines:       make([]Line, h.cfg.BufferLines),
+		subscribers: make(map[chan Line]struct{}),
+		redactor:    redactor,
+	}
+}
+
+// Writer return writer which splits output by lines and publishes them to stream
+func (h *Hub) Writer(id, streamName string) io.Writer {
+	w := &lineWriter{hub: h, id: id, stream: streamName}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.streams[id]; ok {
+		s.writers = append(s.writers, w)
+	}
+	return w
+}
+
+// Close flush writers of stream and finish it, subscribers channels are closed
+func (h *Hub) Close(id string) {
+	h.mu.Lock()
+	s, ok := h.streams[id]
+	if !ok || s.done {
+		h.mu.Unlock()
+		return
+	}
+	writers := s.writers
+	h.mu.Unlock()
+	for _, w := range writers {
+		w.flush()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.finish(s)
+	h.finished = append(h.finished, id)
+	for len(h.finished) > h.cfg.MaxFinished {
+		oldest := h.finished[0]
+		h.finished = h.finished[1:]
+		if old, ok := h.streams[oldest]; ok && old.done {
+			delete(h.streams, oldest)
+		}
+	}
+}
+
+// Subscribe return buffered lines of stream and channel with next lines,
+// channel is closed when stream is finished, returned func must be called for unsubscribe
+func (h *Hub) Subscribe(id string) ([]Line, <-chan Line, func(), bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.streams[id]
+	if !ok {
+		return nil, nil, nil, false
+	}
+	ch := make(chan Line, subscriberBufferSize)
+	if s.done {
+		close(ch)
+		return s.backlog(), ch, func() {}, true
+	}
+	s.subscribers[ch] = struct{}{}
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+	return s.backlog(), ch, unsubscribe, true
+}
+
+func (h *Hub) publish(id, streamName, text string) {
+	h.mu.Lock()
+	s, ok := h.streams[id]
+	if !ok || s.done {
+		h.mu.Unlock()
+		return
+	}
+	if s.redactor != nil {
+		text = s.redactor.Redact(text)
+	}
+	s.seq++
+	line := Line{
+		Seq:    s.seq,
+		Stream: streamName,
+		Time:   time.Now(),
+		Text:   text,
+	}
+	s.add(line)
+	for ch := range s.subscribers {
+		select {
+		case ch <- line:
+		default:
+			// slow subscriber loses line, it can detect gap by seq
+		}
+	}
+	h.mu.Unlock()
+
+	if h.publisher == nil {
+		return
+	}
+	data, err := json.Marshal(line)
+	if err != nil {
+		h.log.WithError(err).Error("Can't marshal log line")
+		return
+	}
+	if err := h.publisher.Publish(h.subject(id), data); err != nil {
+		h.log.WithError(err).Warn("Can't publish log line")
+	}
+}
+
+func (h *Hub) finish(s *stream) {
+	s.done = true
+	// secrets are not kept after last line
+	s.redactor = nil
+	for ch := range s.subscribers {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (h *Hub) subject(id string) string {
+	return fmt.Sprintf("%s.DeployLog.%s", h.topicPrefix, id)
+}
@@ -0,0 +1,91 @@
+package logstream
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+	"github.com/sirupsen/logrus"
+)
+
+type mockPublisher struct {
+	subjects []string
+	data     []string
+}
+
+func (p *mockPublisher) Publish(subject string, data []byte) error {
+	p.subjects = append(p.subjects, subject)
+	p.data = append(p.data, string(data))
+	return nil
+}
+
+func TestHubBufferAndSubscribe(t *testing.T) {
+	publisher := &mockPublisher{}
+	hub := NewHub(logrus.WithField("component", "test"), Config{BufferLines: 3, MaxFinished: 1}, publisher, "Prefix")
+	hub.Open("id1", nil)
+	w := hub.Writer("id1", "stdout")
+
+	// Run
+	if _, err := fmt.Fprint(w, "line1\nline2\nli"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fmt.Fprint(w, "ne3\r\nline4\nrest"); err != nil {
+		t.Fatal(err)
+	}
+	backlog, lines, unsubscribe, ok := hub.Subscribe("id1")
+	if !ok {
+		t.Fatal("Stream not found")
+	}
+	defer unsubscribe()
+	hub.Close("id1")
+
+	// Assertion
+	expected := []string{"line2", "line3", "line4"}
+	if len(backlog) != len(expected) {
+		t.Fatalf("Expected %d buffered lines, got %+v", len(expected), backlog)
+	}
+	for i, line := range backlog {
+		if line.Text != expected[i] {
+			t.Errorf("Expected line %s, got %s", expected[i], line.Text)
+		}
+	}
+	line, ok := <-lines
+	if !ok || line.Text != "rest" || line.Seq != 5 {
+		t.Errorf("Expected flushed line 'rest' with seq 5, got %+v", line)
+	}
+	if _, ok := <-lines; ok {
+		t.Error("Channel is not closed after close of stream")
+	}
+	if len(publisher.subjects) != 5 || publisher.subjects[0] != "Prefix.DeployLog.id1" {
+		t.Errorf("Unexpected published subjects: %v", publisher.subjects)
+	}
+
+	// finished streams over limit are dropped
+	hub.Open("id2", nil)
+	hub.Close("id2")
+	if _, _, _, ok := hub.Subscribe("id1"); ok {
+		t.Error("Old finished stream is not dropped")
+	}
+}
+
+func TestHubRedact(t *testing.T) {
+	publisher := &mockPublisher{}
+	hub := NewHub(logrus.WithField("component", "test"), Config{BufferLines: 3, MaxFinished: 1}, publisher, "Prefix")
+	hub.Open("id1", secret.NewRedactor("topsecret"))
+	if _, err := fmt.Fprint(hub.Writer("id1", "stdout"), "password is topsecret\n"); err != nil {
+		t.Fatal(err)
+	}
+	backlog, _, unsubscribe, ok := hub.Subscribe("id1")
+	if !ok {
+		t.Fatal("Stream not found")
+	}
+	defer unsubscribe()
+
+	if len(backlog) != 1 || backlog[0].Text != "password is "+secret.Redacted {
+		t.Errorf("Expected redacted line, got %+v", backlog)
+	}
+	if len(publisher.data) != 1 || strings.Contains(publisher.data[0], "topsecret") {
+		t.Errorf("Secret is published: %v", publisher.data)
+	}
+}
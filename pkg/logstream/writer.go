@@ -0,0 +1,48 @@
+package logstream
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// maxLineLength limits buffer for output without new lines
+const maxLineLength = 64 * 1024
+
+// lineWriter is io.Writer which publishes every complete line to hub
+type lineWriter struct {
+	hub    *Hub
+	id     string
+	stream string
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.hub.publish(w.id, w.stream, strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) > maxLineLength {
+		w.hub.publish(w.id, w.stream, string(w.buf))
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// flush publish rest of output without new line
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.hub.publish(w.id, w.stream, string(w.buf))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,179 @@
+// Package metrics keeps prometheus metrics of service, they are exported on /metrics
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tcd"
+
+// Statuses of deployment
+const (
+	StatusStarted   = "started"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+	StatusCached    = "cached"
+)
+
+// CodeOK is code of successful rpc request
+const CodeOK = "ok"
+
+// Servers of rpc requests
+const (
+	ServerHTTP    = "http"
+	ServerNATS    = "nats"
+	ServerJSONRPC = "jsonrpc"
+	ServerGRPC    = "grpc"
+)
+
+// Results of operations
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Path of metrics endpoint
+const Path = "/metrics"
+
+// Registry has all metrics of service, go and process metrics are included
+var Registry = prometheus.NewRegistry()
+
+var (
+	deployments = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deployments_total",
+		Help:      "Count of deployments by repo, scenario and status.",
+	}, []string{"repo", "scenario", "status"})
+
+	deploymentDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "deployment_duration_seconds",
+		Help:      "Duration of finished deployments by repo, scenario and status.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 900, 1200, 1800, 2700, 3600},
+	}, []string{"repo", "scenario", "status"})
+
+	commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "git_command_duration_seconds",
+		Help:      "Duration of git and nix commands.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"command"})
+
+	commandFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "git_command_failures_total",
+		Help:      "Count of failed git and nix commands.",
+	}, []string{"command"})
+
+	rpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "Count of rpc requests by server, method and code of result (ok or code of error).",
+	}, []string{"server", "method", "code"})
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Duration of rpc requests by server and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"server", "method"})
+
+	callbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gateway_callbacks_total",
+		Help:      "Count of attempts to send result to gateway by transport, method and result.",
+	}, []string{"transport", "method", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		deployments,
+		deploymentDuration,
+		commandDuration,
+		commandFailures,
+		rpcRequests,
+		rpcDuration,
+		callbacks,
+	)
+}
+
+// DeploymentStarted count started deployment
+func DeploymentStarted(repo, scenario string) {
+	deployments.WithLabelValues(repo, scenario, StatusStarted).Inc()
+}
+
+// DeploymentFinished count finished deployment and observe its duration
+func DeploymentFinished(repo, scenario, status string, start time.Time) {
+	deployments.WithLabelValues(repo, scenario, status).Inc()
+	deploymentDuration.WithLabelValues(repo, scenario, status).Observe(time.Since(start).Seconds())
+}
+
+// ObserveCommand observe duration of git or nix command and count it if it is failed
+func ObserveCommand(command string, start time.Time, err error) {
+	commandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+	if err != nil {
+		commandFailures.WithLabelValues(command).Inc()
+	}
+}
+
+// ObserveRPC count rpc request and observe its duration, code is `ok` for successful request
+// and code of error otherwise
+func ObserveRPC(server, method string, serr *serror.Error, start time.Time) {
+	code := CodeOK
+	if serr != nil {
+		code = string(serr.Code)
+	}
+	rpcRequests.WithLabelValues(server, method, code).Inc()
+	rpcDuration.WithLabelValues(server, method).Observe(time.Since(start).Seconds())
+}
+
+// ObserveCallback count attempt to send result to gateway
+func ObserveCallback(transport, method string, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFailure
+	}
+	callbacks.WithLabelValues(transport, method, result).Inc()
+}
+
+// QueueSource is pool of deployments
+type QueueSource interface {
+	QueueDepth() int
+	Running() int
+}
+
+// RegisterQueue add gauges of queue depth and running deployments of pool
+func RegisterQueue(pool QueueSource) error {
+	queueDepth := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Count of deployments waiting in queue.",
+	}, func() float64 {
+		return float64(pool.QueueDepth())
+	})
+	running := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deployments_running",
+		Help:      "Count of running deployments.",
+	}, func() float64 {
+		return float64(pool.Running())
+	})
+	if err := Registry.Register(queueDepth); err != nil {
+		return err
+	}
+	return Registry.Register(running)
+}
+
+// Handler return http handler of metrics endpoint
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
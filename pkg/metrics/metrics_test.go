@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeQueue struct{}
+
+func (fakeQueue) QueueDepth() int { return 3 }
+func (fakeQueue) Running() int    { return 1 }
+
+func TestCounters(t *testing.T) {
+	start := time.Now()
+	DeploymentStarted("repo", "1")
+	DeploymentFinished("repo", "1", StatusFailed, start)
+	if v := testutil.ToFloat64(deployments.WithLabelValues("repo", "1", StatusFailed)); v != 1 {
+		t.Errorf("One failed deployment is expected, got %v", v)
+	}
+
+	ObserveCommand("ls-remote", start, nil)
+	ObserveCommand("ls-remote", start, errors.New("failed"))
+	if v := testutil.ToFloat64(commandFailures.WithLabelValues("ls-remote")); v != 1 {
+		t.Errorf("One failed command is expected, got %v", v)
+	}
+
+	ObserveRPC(ServerHTTP, "Run", nil, start)
+	ObserveRPC(ServerHTTP, "Run", serror.New(serror.ErrCodeBusy, "busy"), start)
+	if v := testutil.ToFloat64(rpcRequests.WithLabelValues(ServerHTTP, "Run", CodeOK)); v != 1 {
+		t.Errorf("One ok request is expected, got %v", v)
+	}
+	if v := testutil.ToFloat64(rpcRequests.WithLabelValues(ServerHTTP, "Run", serror.ErrCodeBusy)); v != 1 {
+		t.Errorf("One busy request is expected, got %v", v)
+	}
+
+	ObserveCallback("nats", "RunResult", errors.New("failed"))
+	if v := testutil.ToFloat64(callbacks.WithLabelValues("nats", "RunResult", ResultFailure)); v != 1 {
+		t.Errorf("One failed callback is expected, got %v", v)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	if err := RegisterQueue(fakeQueue{}); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	body := rec.Body.String()
+	for _, line := range []string{"tcd_queue_depth 3", "tcd_deployments_running 1", "go_goroutines"} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Metrics should contain %s", line)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Config of secret env vars
+type Config struct {
+	// Patterns of env var names which are always secret, e.g. *PASSWORD*
+	Patterns []string
+	// FilesDir is dir with mounted files which can be referenced in env vars with FileRefPrefix
+	FilesDir string
+}
+
+// Decode for envconfig, patterns are separated by comma
+func (c *Config) Decode(data string) error {
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.Split(p, "=")
+		if len(paramArr) != 2 {
+			return fmt.Errorf("bad param in part of Secrets env '%s'", p)
+		}
+		switch paramArr[0] {
+		case "patterns":
+			c.Patterns = make([]string, 0)
+			for _, pattern := range strings.Split(paramArr[1], ",") {
+				if pattern != "" {
+					c.Patterns = append(c.Patterns, pattern)
+				}
+			}
+		case "filesDir":
+			c.FilesDir = paramArr[1]
+		default:
+			return fmt.Errorf("unknown param '%s' for part of Secrets env", paramArr[0])
+		}
+	}
+
+	return nil
+}
+
+// Validate cfg after load
+func (c *Config) Validate() error {
+	for _, pattern := range c.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad secret pattern '%s': %s", pattern, err)
+		}
+	}
+	if c.FilesDir != "" {
+		fi, err := os.Stat(c.FilesDir)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("filesDir of secrets '%s' is not a dir", c.FilesDir)
+		}
+	}
+	return nil
+}
+
+// GetDefaultConfig return default config for secret pkg
+func GetDefaultConfig() Config {
+	return Config{
+		Patterns: []string{"*PASSWORD*", "*SECRET*", "*TOKEN*", "*PRIVATE_KEY*", "*KEYSTORE*"},
+	}
+}
+
+// IsSecretName check that name of env var matches one of patterns, case is ignored
+func (c *Config) IsSecretName(name string) bool {
+	name = strings.ToUpper(name)
+	for _, pattern := range c.Patterns {
+		if ok, _ := path.Match(strings.ToUpper(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
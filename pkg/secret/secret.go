@@ -0,0 +1,237 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces values of secrets
+const Redacted = "<redacted>"
+
+// FileRefPrefix mark value of env var as reference to file in FilesDir, e.g. secretfile:eth-password
+const FileRefPrefix = "secretfile:"
+
+// minLength is min length of value which is redacted in text,
+// shorter values would hide unrelated parts of output
+const minLength = 4
+
+// FileRefError is error of env var with reference to file
+type FileRefError struct {
+	Name string
+	Err  error
+}
+
+func (e *FileRefError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, e.Err)
+}
+
+// ResolveFiles replace references to files with content of files,
+// names of resolved env vars are returned, they are always secret
+func (c *Config) ResolveFiles(envVars map[string]string) (map[string]string, []string, error) {
+	res := make(map[string]string, len(envVars))
+	names := make([]string, 0)
+	for name, val := range envVars {
+		if !strings.HasPrefix(val, FileRefPrefix) {
+			res[name] = val
+			continue
+		}
+		if c.FilesDir == "" {
+			return nil, nil, &FileRefError{Name: name, Err: fmt.Errorf("secret files are not configured")}
+		}
+		fileName := strings.TrimPrefix(val, FileRefPrefix)
+		// only files in dir are allowed
+		if fileName == "" || fileName != filepath.Base(fileName) || fileName == "." || fileName == ".." {
+			return nil, nil, &FileRefError{Name: name, Err: fmt.Errorf("bad name of secret file '%s'", fileName)}
+		}
+		data, err := ioutil.ReadFile(filepath.Join(c.FilesDir, fileName))
+		if err != nil {
+			return nil, nil, &FileRefError{Name: name, Err: fmt.Errorf("can't read secret file '%s'", fileName)}
+		}
+		res[name] = strings.TrimRight(string(data), "\r\n")
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return res, names, nil
+}
+
+// Redactor replace values of secrets in text
+type Redactor struct {
+	// values are sorted from longest, so value which contains other one is replaced first
+	values    []string
+	maxLen    int
+	multiline bool
+}
+
+// NewRedactor init redactor, values shorter than minLength are ignored,
+// JSON escaped form of values is redacted too
+func NewRedactor(values ...string) *Redactor {
+	r := &Redactor{}
+	uniq := make(map[string]bool)
+	for _, val := range values {
+		if len(val) < minLength {
+			continue
+		}
+		uniq[val] = true
+		if escaped, err := json.Marshal(val); err == nil {
+			uniq[string(escaped[1:len(escaped)-1])] = true
+		}
+	}
+	for val := range uniq {
+		r.values = append(r.values, val)
+		if len(val) > r.maxLen {
+			r.maxLen = len(val)
+		}
+		if strings.Contains(val, "\n") {
+			r.multiline = true
+		}
+	}
+	sort.Slice(r.values, func(i, j int) bool {
+		if len(r.values[i]) != len(r.values[j]) {
+			return len(r.values[i]) > len(r.values[j])
+		}
+		return r.values[i] < r.values[j]
+	})
+	return r
+}
+
+// Redact replace secrets in string
+func (r *Redactor) Redact(s string) string {
+	for _, val := range r.values {
+		s = strings.Replace(s, val, Redacted, -1)
+	}
+	return s
+}
+
+// RedactBytes replace secrets in bytes, data is not changed
+func (r *Redactor) RedactBytes(data []byte) []byte {
+	if len(r.values) == 0 || data == nil {
+		return data
+	}
+	for _, val := range r.values {
+		data = bytes.Replace(data, []byte(val), []byte(Redacted), -1)
+	}
+	return data
+}
+
+// Writer return writer which redact secrets before writing to w, nil w discards output,
+// writer has to be flushed after last write
+func (r *Redactor) Writer(w io.Writer) *Writer {
+	return &Writer{w: w, r: r}
+}
+
+// Writer redact secrets in stream, tail which can be start of secret is kept until next write
+type Writer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	r   *Redactor
+	buf []byte
+}
+
+// Write redact and write data which can't be part of secret anymore
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = w.r.RedactBytes(append(w.buf, p...))
+	// secret can start only in last maxLen-1 bytes, single line secret can't cross new line
+	n := len(w.buf) - w.r.maxLen + 1
+	if !w.r.multiline {
+		if idx := bytes.LastIndexByte(w.buf, '\n'); idx+1 > n {
+			n = idx + 1
+		}
+	}
+	if n > len(w.buf) {
+		n = len(w.buf)
+	}
+	if n <= 0 {
+		return len(p), nil
+	}
+	if err := w.write(w.buf[:n]); err != nil {
+		return 0, err
+	}
+	w.buf = append([]byte{}, w.buf[n:]...)
+	return len(p), nil
+}
+
+// Flush write kept tail
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *Writer) write(data []byte) error {
+	if w.w == nil {
+		return nil
+	}
+	_, err := w.w.Write(data)
+	return err
+}
+
+// Keys of secret data in requests
+const (
+	envVarsKey = "envVars"
+	accountKey = "account"
+	secretKey  = "secret"
+)
+
+// RedactRequest replace values of all env vars, accounts and secrets (e.g. of webhook) in JSON request for logging,
+// names of env vars are kept, not JSON data is returned as is
+func RedactRequest(data []byte) []byte {
+	if !bytes.Contains(data, []byte(`"`+envVarsKey+`"`)) && !bytes.Contains(data, []byte(`"`+accountKey+`"`)) &&
+		!bytes.Contains(data, []byte(`"`+secretKey+`"`)) {
+		return data
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var req interface{}
+	if err := dec.Decode(&req); err != nil {
+		return data
+	}
+	var res bytes.Buffer
+	enc := json.NewEncoder(&res)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactEnvVars(req)); err != nil {
+		return data
+	}
+	return bytes.TrimSuffix(res.Bytes(), []byte("\n"))
+}
+
+func redactEnvVars(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if _, ok := item.(map[string]interface{}); ok && key == accountKey {
+				v[key] = Redacted
+				continue
+			}
+			if _, ok := item.(string); ok && key == secretKey {
+				v[key] = Redacted
+				continue
+			}
+			if envVars, ok := item.(map[string]interface{}); ok && key == envVarsKey {
+				for name := range envVars {
+					envVars[name] = Redacted
+				}
+				continue
+			}
+			v[key] = redactEnvVars(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactEnvVars(item)
+		}
+	}
+	return val
+}
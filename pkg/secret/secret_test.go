@@ -0,0 +1,107 @@
+package secret
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsSecretName(t *testing.T) {
+	cfg := GetDefaultConfig()
+	for _, name := range []string{"ETH_PASSWORD", "eth_password", "ETH_KEYSTORE", "GITHUB_TOKEN", "ETH_PRIVATE_KEY"} {
+		if !cfg.IsSecretName(name) {
+			t.Errorf("%s must be secret", name)
+		}
+	}
+	for _, name := range []string{"ETH_FROM", "ETH_RPC_URL", "ETH_GAS"} {
+		if cfg.IsSecretName(name) {
+			t.Errorf("%s must not be secret", name)
+		}
+	}
+}
+
+func TestRedactorWriter(t *testing.T) {
+	r := NewRedactor("s3cr3t-password", "x", `{"key":"val"}`)
+	if res := r.Redact(`pass s3cr3t-password, x, json "{\"key\":\"val\"}"`); res != `pass <redacted>, x, json "<redacted>"` {
+		t.Errorf("Unexpected redacted text: %s", res)
+	}
+
+	var out bytes.Buffer
+	w := r.Writer(&out)
+	// secret is split between writes
+	for _, chunk := range []string{"line 1\nuse s3cr", "3t-pass", "word now\n", "last s3cr3t-"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Contains(out.String(), "s3cr") {
+		t.Errorf("Part of secret is written: %q", out.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "line 1\nuse <redacted> now\nlast s3cr3t-" {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+}
+
+func TestRedactRequest(t *testing.T) {
+	req := `{"method":"DeployPipeline","data":{"stages":[{"id":"a","envVars":{"ETH_PASSWORD":"pass","ETH_GAS":"7000000"}}]}}`
+	res := string(RedactRequest([]byte(req)))
+	if strings.Contains(res, "pass\"") || strings.Contains(res, "7000000") {
+		t.Errorf("Values of env vars must be redacted: %s", res)
+	}
+	if !strings.Contains(res, `"ETH_PASSWORD":"<redacted>"`) || !strings.Contains(res, `"method":"DeployPipeline"`) {
+		t.Errorf("Names of env vars and other fields must be kept: %s", res)
+	}
+	res = string(RedactRequest([]byte(`{"account":{"privateKey":"0x4c08"},"repoUrl":"https://github.com/makerdao/dss-deploy-scripts"}`)))
+	if res != `{"account":"<redacted>","repoUrl":"https://github.com/makerdao/dss-deploy-scripts"}` {
+		t.Errorf("Account must be redacted: %s", res)
+	}
+	res = string(RedactRequest([]byte(`{"method":"RegisterWebhook","data":{"url":"http://localhost","secret":"s3cret"}}`)))
+	if strings.Contains(res, "s3cret") {
+		t.Errorf("Secret must be redacted: %s", res)
+	}
+	if res := string(RedactRequest([]byte("not json envVars"))); res != "not json envVars" {
+		t.Errorf("Not json must be returned as is: %s", res)
+	}
+}
+
+func TestResolveFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "eth-password"), []byte("pass123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{FilesDir: dir}
+	envVars, names, err := cfg.ResolveFiles(map[string]string{
+		"ETH_PASSWORD": FileRefPrefix + "eth-password",
+		"ETH_FROM":     "0x01",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envVars["ETH_PASSWORD"] != "pass123" || envVars["ETH_FROM"] != "0x01" {
+		t.Errorf("Unexpected env vars: %v", envVars)
+	}
+	if len(names) != 1 || names[0] != "ETH_PASSWORD" {
+		t.Errorf("Unexpected names of resolved env vars: %v", names)
+	}
+
+	for _, ref := range []string{"../eth-password", "missing", ""} {
+		_, _, err := cfg.ResolveFiles(map[string]string{"ETH_PASSWORD": FileRefPrefix + ref})
+		if refErr, ok := err.(*FileRefError); !ok || refErr.Name != "ETH_PASSWORD" {
+			t.Errorf("Reference '%s' must fail, got %v", ref, err)
+		}
+	}
+	if _, _, err := (&Config{}).ResolveFiles(map[string]string{"ETH_PASSWORD": FileRefPrefix + "eth-password"}); err == nil {
+		t.Errorf("Reference must fail without files dir")
+	}
+}
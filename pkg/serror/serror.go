@@ -9,6 +9,7 @@ const (
 	ErrCodeInternalError = "internalError"
 	ErrCodeBadRequest    = "badRequest"
 	ErrCodeNotFound      = "notFound"
+	ErrCodeBusy          = "busy"
 )
 
 //Error business error
@@ -0,0 +1,143 @@
+package grpc
+
+import (
+	"context"
+	"io"
+
+	"github.com/makerdao/testchain-deployment/pkg/deploy"
+	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/service/methods"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Client is typed client of gRPC server, errors of methods are statuses with codes from Code
+type Client struct {
+	conn gogrpc.ClientConnInterface
+}
+
+// NewClient init client on connection, content subtype of JSON codec is set by client for each call
+func NewClient(conn gogrpc.ClientConnInterface) *Client {
+	return &Client{conn: conn}
+}
+
+// Invoke call method by name, id is request id (id of job for async methods),
+// it is generated by server if it is empty
+func (c *Client) Invoke(ctx context.Context, method, id string, req, res interface{}) error {
+	if req == nil {
+		req = struct{}{}
+	}
+	return c.conn.Invoke(c.outgoing(ctx, id), methodPath(method), req, res, gogrpc.CallContentSubtype(CodecName))
+}
+
+// GetRefs return remote refs of GIT repo
+func (c *Client) GetRefs(ctx context.Context, req methods.GetRefsRequest) ([]git.Ref, error) {
+	var res []git.Ref
+	if err := c.Invoke(ctx, "GetRefs", "", req, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetManifest return deploy manifest of commit
+func (c *Client) GetManifest(ctx context.Context, req methods.GetManifestReq) (*deploy.Manifest, error) {
+	var res deploy.Manifest
+	if err := c.Invoke(ctx, "GetManifest", "", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Deploy start deployment with id, progress and result are available by WatchDeployment and GetJob
+func (c *Client) Deploy(ctx context.Context, id string, req methods.DeployRequest) (*methods.DeployResponse, error) {
+	var res methods.DeployResponse
+	if err := c.Invoke(ctx, "Deploy", id, req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetJob return state and result of async operation
+func (c *Client) GetJob(ctx context.Context, id string) (*job.Job, error) {
+	var res job.Job
+	if err := c.Invoke(ctx, "GetJob", "", methods.GetJobRequest{ID: id}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ListJobs return async operations filtered by state and method
+func (c *Client) ListJobs(ctx context.Context, req methods.ListJobsRequest) ([]job.Job, error) {
+	var res []job.Job
+	if err := c.Invoke(ctx, "ListJobs", "", req, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// WatchDeployment open stream of output and result of deployment with id
+func (c *Client) WatchDeployment(ctx context.Context, id string) (*Watcher, error) {
+	desc := &gogrpc.StreamDesc{StreamName: WatchDeploymentMethod, ServerStreams: true}
+	stream, err := c.conn.NewStream(c.outgoing(ctx, ""), desc, methodPath(WatchDeploymentMethod), gogrpc.CallContentSubtype(CodecName))
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(&WatchDeploymentRequest{ID: id}); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &Watcher{stream: stream}, nil
+}
+
+// Watcher receive events of WatchDeployment
+type Watcher struct {
+	stream gogrpc.ClientStream
+}
+
+// Recv return next event, io.EOF is returned after event with result
+func (w *Watcher) Recv() (*Event, error) {
+	var event Event
+	if err := w.stream.RecvMsg(&event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Wait skip lines of output and return job with result
+func (w *Watcher) Wait() (*job.Job, error) {
+	for {
+		event, err := w.Recv()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if event.Type == EventTypeResult {
+			return event.Job, nil
+		}
+	}
+}
+
+// outgoing add id of request and trace context of span in ctx to metadata
+func (c *Client) outgoing(ctx context.Context, id string) context.Context {
+	var kv []string
+	if id != "" {
+		kv = append(kv, RequestIDKey, id)
+	}
+	for key, val := range tracing.Inject(ctx) {
+		kv = append(kv, key, val)
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+func methodPath(method string) string {
+	return "/" + ServiceName + "/" + method
+}
@@ -0,0 +1,44 @@
+package grpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// CodecName is content subtype of messages, content type is application/grpc+json
+const CodecName = "json"
+
+// codec is registered for content subtype, so server still chooses codec by content type of request
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
+
+// Codec encode messages of service as JSON, so messages are the same as data of HTTP and NATS methods
+// and service doesn't need generated protobuf code
+type Codec struct{}
+
+// Marshal value to JSON, raw message is sent as is
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	if raw, ok := v.(json.RawMessage); ok {
+		return raw, nil
+	}
+	return json.Marshal(v)
+}
+
+// Unmarshal JSON to value, empty message leaves value untouched
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if raw, ok := v.(*json.RawMessage); ok {
+		*raw = append((*raw)[0:0], data...)
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Name of codec
+func (Codec) Name() string {
+	return CodecName
+}
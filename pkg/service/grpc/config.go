@@ -0,0 +1,54 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Config of gRPC server
+type Config struct {
+	Port int
+}
+
+// Decode for envconfig
+func (c *Config) Decode(data string) error {
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.Split(p, "=")
+		if len(paramArr) != 2 {
+			return fmt.Errorf("bad param in part of GRPC env '%s'", p)
+		}
+		switch paramArr[0] {
+		case "port":
+			v, err := strconv.Atoi(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.Port = v
+		default:
+			return fmt.Errorf("unknown param '%s' for part of GRPC env", paramArr[0])
+		}
+	}
+
+	return nil
+}
+
+// Validate cfg after load
+func (c *Config) Validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return errors.New("port of gRPC server should be from 1 to 65535")
+	}
+	return nil
+}
+
+// GetDefaultConfig return default config for grpc pkg
+func GetDefaultConfig() Config {
+	return Config{
+		Port: 5002,
+	}
+}
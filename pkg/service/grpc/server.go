@@ -0,0 +1,242 @@
+// Package grpc serve rpc methods over gRPC with JSON messages, deployment is watched
+// with server streaming WatchDeployment instead of polling and callbacks
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/makerdao/testchain-deployment/pkg/service/protocol"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	"github.com/sirupsen/logrus"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceName is full name of gRPC service, method path is /<ServiceName>/<Method>
+const ServiceName = "testchain.deployment.Deployment"
+
+// RequestIDKey is metadata key with id of request, it is id of job for async methods,
+// id is generated when request has no id and it is returned in header
+const RequestIDKey = "request-id"
+
+// Code return gRPC status code for code of service error
+func Code(code serror.ErrCode) codes.Code {
+	switch code {
+	case serror.ErrCodeBadRequest:
+		return codes.InvalidArgument
+	case serror.ErrCodeNotFound:
+		return codes.NotFound
+	case serror.ErrCodeBusy:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// Server is gRPC server with unary method for each rpc method and WatchDeployment stream
+type Server struct {
+	cfg      Config
+	log      *logrus.Entry
+	server   *gogrpc.Server
+	names    []string
+	methods  map[string]protocol.HandlerMethod
+	logs     LogSource
+	jobs     JobSource
+	stopOnce sync.Once
+	stopping chan struct{}
+}
+
+// New init server, methods have to be added before run
+func New(log *logrus.Entry, cfg Config, logs LogSource, jobs JobSource) *Server {
+	return &Server{
+		cfg:      cfg,
+		log:      log.WithField("component", "grpcServer"),
+		server:   gogrpc.NewServer(),
+		methods:  make(map[string]protocol.HandlerMethod),
+		logs:     logs,
+		jobs:     jobs,
+		stopping: make(chan struct{}),
+	}
+}
+
+// AddMethod add unary method for name
+func (s *Server) AddMethod(name string, methodFunc protocol.HandlerMethod) error {
+	if _, ok := s.methods[name]; ok || name == WatchDeploymentMethod {
+		return errors.New("method with name already exists")
+	}
+	s.names = append(s.names, name)
+	s.methods[name] = methodFunc
+	s.log.Infof("gRPC method added: %s", name)
+	return nil
+}
+
+// Run listen port from config and serve requests
+func (s *Server) Run(log *logrus.Entry) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.cfg.Port))
+	if err != nil {
+		return err
+	}
+	log.Infof("gRPC server listens %s", lis.Addr())
+	return s.Serve(lis)
+}
+
+// Serve requests from listener, it returns when server is stopped
+func (s *Server) Serve(lis net.Listener) error {
+	s.server.RegisterService(s.serviceDesc(), s)
+	return s.server.Serve(lis)
+}
+
+// Shutdown stop server for new requests and wait running requests,
+// watchers of deployments are finished immediately
+func (s *Server) Shutdown(ctx context.Context, log *logrus.Entry) error {
+	log.Debug("Start graceful shutdown gRPC server")
+	defer log.Debug("Graceful shutdown gRPC server: done")
+	s.stopOnce.Do(func() { close(s.stopping) })
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return fmt.Errorf("context cancelled, gRPC server is stopped without waiting requests")
+	}
+}
+
+func (s *Server) serviceDesc() *gogrpc.ServiceDesc {
+	desc := &gogrpc.ServiceDesc{
+		ServiceName: ServiceName,
+		HandlerType: (*interface{})(nil),
+		Streams: []gogrpc.StreamDesc{
+			{
+				StreamName:    WatchDeploymentMethod,
+				Handler:       s.watchDeploymentHandler,
+				ServerStreams: true,
+			},
+		},
+	}
+	for _, name := range s.names {
+		desc.Methods = append(desc.Methods, gogrpc.MethodDesc{
+			MethodName: name,
+			Handler:    s.getMethodHandler(name, s.methods[name]),
+		})
+	}
+	return desc
+}
+
+func (s *Server) getMethodHandler(name string, methodFunc protocol.HandlerMethod) gogrpc.MethodHandler {
+	return func(
+		srv interface{},
+		ctx context.Context,
+		dec func(interface{}) error,
+		interceptor gogrpc.UnaryServerInterceptor,
+	) (interface{}, error) {
+		var req json.RawMessage
+		if err := dec(&req); err != nil {
+			return nil, err
+		}
+		return s.call(ctx, name, methodFunc, req)
+	}
+}
+
+// call method with id and trace context from metadata, error of method is returned as status
+func (s *Server) call(
+	ctx context.Context,
+	name string,
+	methodFunc protocol.HandlerMethod,
+	req json.RawMessage,
+) (json.RawMessage, error) {
+	ctx, id, err := s.startRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log := s.log.WithField("method", name).WithField("id", id)
+	params := bytes.TrimSpace(req)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		params = []byte("{}")
+	}
+	if params[0] != '{' {
+		return nil, status.Error(codes.InvalidArgument, "Request should be object")
+	}
+
+	ctx, span := tracing.StartRPC(ctx, metrics.ServerGRPC, name, id)
+	log = tracing.Log(ctx, log)
+	log.WithField("data", string(secret.RedactRequest(params))).Debug("Request data")
+	start := time.Now()
+	var res []byte
+	var serr *serror.Error
+	func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				serr = serror.New(serror.ErrCodeInternalError, fmt.Sprintf("Unexpected internal error, %+v", rec))
+			}
+		}()
+		res, serr = methodFunc(ctx, log, id, params)
+	}()
+	metrics.ObserveRPC(metrics.ServerGRPC, name, serr, start)
+	tracing.EndRPC(span, serr)
+
+	if serr != nil {
+		log.WithField("code", serr.Code).Debug("Response error")
+		return nil, statusError(serr)
+	}
+	if len(res) == 0 {
+		res = []byte("{}")
+	}
+	return res, nil
+}
+
+// startRequest return ctx with remote span from metadata and id of request,
+// generated id is sent to client in header
+func (s *Server) startRequest(ctx context.Context) (context.Context, string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	fields := make(map[string]string, len(md))
+	for key, vals := range md {
+		if len(vals) != 0 {
+			fields[key] = vals[0]
+		}
+	}
+	id := fields[RequestIDKey]
+	if id == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, "", status.Error(codes.Internal, "Can't generate id of request")
+		}
+		id = hex.EncodeToString(buf)
+	}
+	if err := gogrpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id)); err != nil {
+		s.log.WithError(err).Debug("Can't set id of request to header")
+	}
+	return tracing.Extract(ctx, fields), id, nil
+}
+
+// statusError convert error of service to status, errors from list are added to message
+func statusError(serr *serror.Error) error {
+	msg := serr.Detail
+	if len(serr.ErrorList) != 0 {
+		list := make([]string, 0, len(serr.ErrorList))
+		for _, err := range serr.ErrorList {
+			list = append(list, err.Error())
+		}
+		msg = fmt.Sprintf("%s: %s", msg, strings.Join(list, "; "))
+	}
+	return status.Error(Code(serr.Code), msg)
+}
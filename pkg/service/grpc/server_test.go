@@ -0,0 +1,190 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/logstream"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeJobs struct {
+	mu   sync.Mutex
+	jobs map[string]job.Job
+}
+
+func (f *fakeJobs) Get(log *logrus.Entry, id string) (*job.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	j, ok := f.jobs[id]
+	if !ok {
+		return nil, job.ErrNotFound
+	}
+	return &j, nil
+}
+
+func (f *fakeJobs) set(j job.Job) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jobs[j.ID] = j
+}
+
+func newTestServer(t *testing.T, hub *logstream.Hub, jobs *fakeJobs) (*Server, *Client) {
+	log := logrus.NewEntry(logrus.New())
+	serv := New(log, GetDefaultConfig(), hub, jobs)
+	if err := serv.AddMethod("Echo", func(ctx context.Context, log *logrus.Entry, id string, data []byte) ([]byte, *serror.Error) {
+		res, err := json.Marshal(map[string]interface{}{"id": id, "data": json.RawMessage(data)})
+		if err != nil {
+			return nil, serror.NewMarshalRespErr(err)
+		}
+		return res, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := serv.AddMethod("Busy", func(ctx context.Context, log *logrus.Entry, id string, data []byte) ([]byte, *serror.Error) {
+		return nil, serror.New(serror.ErrCodeBusy, "Too many deployments in progress")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := serv.AddMethod(WatchDeploymentMethod, nil); err == nil {
+		t.Errorf("Expected error for name of stream method")
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		if err := serv.Serve(lis); err != nil {
+			t.Errorf("Serve: %s", err)
+		}
+	}()
+	conn, err := gogrpc.NewClient(
+		"passthrough:///bufnet",
+		gogrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		if err := serv.Shutdown(context.Background(), log); err != nil {
+			t.Errorf("Shutdown: %s", err)
+		}
+	})
+	return serv, NewClient(conn)
+}
+
+func TestServerMethods(t *testing.T) {
+	_, client := newTestServer(t, logstream.NewHub(logrus.NewEntry(logrus.New()), logstream.GetDefaultConfig(), nil, ""), &fakeJobs{})
+	ctx := context.Background()
+
+	var res struct {
+		ID   string          `json:"id"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := client.Invoke(ctx, "Echo", "req-1", map[string]int{"a": 1}, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "req-1" || string(res.Data) != `{"a":1}` {
+		t.Errorf("Unexpected response: %s %s", res.ID, res.Data)
+	}
+
+	if err := client.Invoke(ctx, "Echo", "", nil, &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.ID) != 32 || string(res.Data) != `{}` {
+		t.Errorf("Expected generated id and empty data, got: %s %s", res.ID, res.Data)
+	}
+
+	err := client.Invoke(ctx, "Busy", "", nil, &res)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted, got: %v", err)
+	}
+	err = client.Invoke(ctx, "Unknown", "", nil, &res)
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Expected Unimplemented, got: %v", err)
+	}
+
+	// codec is chosen by content subtype, it isn't forced for all requests
+	if _, ok := encoding.GetCodec(CodecName).(Codec); !ok {
+		t.Errorf("Codec %s must be registered", CodecName)
+	}
+}
+
+func TestWatchDeployment(t *testing.T) {
+	watchPollInterval = 10 * time.Millisecond
+	log := logrus.NewEntry(logrus.New())
+	hub := logstream.NewHub(log, logstream.GetDefaultConfig(), nil, "")
+	jobs := &fakeJobs{jobs: make(map[string]job.Job)}
+	_, client := newTestServer(t, hub, jobs)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// queued job has no output yet
+	jobs.set(job.Job{ID: "d1", Method: "Deploy", State: job.StateQueued})
+	watcher, err := client.WatchDeployment(ctx, "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.Open("d1", nil)
+	if _, err := io.WriteString(hub.Writer("d1", "stdout"), "first\nsecond\n"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"first", "second"} {
+		event, err := watcher.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != EventTypeLog || event.Line == nil || event.Line.Text != expected {
+			t.Errorf("Expected line %s, got: %+v", expected, event)
+		}
+	}
+	jobs.set(job.Job{ID: "d1", Method: "Deploy", State: job.StateSucceeded, Result: json.RawMessage(`{"ok":true}`)})
+	hub.Close("d1")
+	event, err := watcher.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EventTypeResult || event.Job == nil || event.Job.State != job.StateSucceeded {
+		t.Errorf("Expected result, got: %+v", event)
+	}
+	if _, err := watcher.Recv(); err != io.EOF {
+		t.Errorf("Expected end of stream, got: %v", err)
+	}
+
+	// finished job without output returns only result
+	jobs.set(job.Job{ID: "d2", Method: "Deploy", State: job.StateFailed})
+	watcher, err = client.WatchDeployment(ctx, "d2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := watcher.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.ID != "d2" || j.State != job.StateFailed {
+		t.Errorf("Unexpected job: %+v", j)
+	}
+
+	watcher, err = client.WatchDeployment(ctx, "unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watcher.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got: %v", err)
+	}
+}
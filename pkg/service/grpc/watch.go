@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/logstream"
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	"github.com/sirupsen/logrus"
+	gogrpc "google.golang.org/grpc"
+)
+
+// WatchDeploymentMethod is name of server streaming method
+const WatchDeploymentMethod = "WatchDeployment"
+
+// Types of events of WatchDeployment
+const (
+	EventTypeLog    = "log"
+	EventTypeResult = "result"
+)
+
+// watchPollInterval is interval of checks of queued job, its logs are opened when job is started
+var watchPollInterval = 500 * time.Millisecond
+
+// LogSource return buffered and next lines of deployment output, see logstream.Hub
+type LogSource interface {
+	Subscribe(id string) ([]logstream.Line, <-chan logstream.Line, func(), bool)
+}
+
+// JobSource return job by id, see job.Registry
+type JobSource interface {
+	Get(log *logrus.Entry, id string) (*job.Job, error)
+}
+
+// WatchDeploymentRequest request data, id is request id of deployment
+type WatchDeploymentRequest struct {
+	ID string `json:"id"`
+}
+
+// Event of WatchDeployment: line of output or job with result, result is the last event
+type Event struct {
+	Type string          `json:"type"`
+	Line *logstream.Line `json:"line,omitempty"`
+	Job  *job.Job        `json:"job,omitempty"`
+}
+
+func (s *Server) watchDeploymentHandler(srv interface{}, stream gogrpc.ServerStream) error {
+	var req WatchDeploymentRequest
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+	ctx, id, err := s.startRequest(stream.Context())
+	if err != nil {
+		return err
+	}
+	log := s.log.WithField("method", WatchDeploymentMethod).WithField("id", id).WithField("jobId", req.ID)
+	ctx, span := tracing.StartRPC(ctx, metrics.ServerGRPC, WatchDeploymentMethod, id)
+	log = tracing.Log(ctx, log)
+	start := time.Now()
+	serr := s.watch(ctx, log, stream, req.ID)
+	metrics.ObserveRPC(metrics.ServerGRPC, WatchDeploymentMethod, serr, start)
+	tracing.EndRPC(span, serr)
+	if serr != nil {
+		log.WithField("code", serr.Code).Debug("Response error")
+		return statusError(serr)
+	}
+	return nil
+}
+
+// watch send lines of output of job while it runs and job when it is finished,
+// queued job is checked until its output is opened
+func (s *Server) watch(ctx context.Context, log *logrus.Entry, stream gogrpc.ServerStream, id string) *serror.Error {
+	if id == "" {
+		return serror.New(serror.ErrCodeBadRequest, "Id of deployment is required")
+	}
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		backlog, lines, unsubscribe, ok := s.logs.Subscribe(id)
+		if ok {
+			defer unsubscribe()
+			return s.sendLines(ctx, log, stream, id, backlog, lines)
+		}
+		j, serr := s.getJob(log, id)
+		if serr != nil {
+			return serr
+		}
+		if j.State.IsFinished() {
+			return s.sendResult(log, stream, j)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.stopping:
+			return serror.New(serror.ErrCodeBusy, "Server is stopping, watch deployment later")
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) sendLines(
+	ctx context.Context,
+	log *logrus.Entry,
+	stream gogrpc.ServerStream,
+	id string,
+	backlog []logstream.Line,
+	lines <-chan logstream.Line,
+) *serror.Error {
+	for i := range backlog {
+		if err := stream.SendMsg(&Event{Type: EventTypeLog, Line: &backlog[i]}); err != nil {
+			log.WithError(err).Debug("Can't send log line")
+			return nil
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.stopping:
+			return serror.New(serror.ErrCodeBusy, "Server is stopping, watch deployment later")
+		case line, ok := <-lines:
+			if !ok {
+				// output is closed after job is finished, so job has final state
+				j, serr := s.getJob(log, id)
+				if serr != nil {
+					return serr
+				}
+				return s.sendResult(log, stream, j)
+			}
+			if err := stream.SendMsg(&Event{Type: EventTypeLog, Line: &line}); err != nil {
+				log.WithError(err).Debug("Can't send log line")
+				return nil
+			}
+		}
+	}
+}
+
+func (s *Server) sendResult(log *logrus.Entry, stream gogrpc.ServerStream, j *job.Job) *serror.Error {
+	if err := stream.SendMsg(&Event{Type: EventTypeResult, Job: j}); err != nil {
+		log.WithError(err).Debug("Can't send result")
+	}
+	return nil
+}
+
+func (s *Server) getJob(log *logrus.Entry, id string) (*job.Job, *serror.Error) {
+	j, err := s.jobs.Get(log, id)
+	if err == job.ErrNotFound {
+		return nil, serror.New(serror.ErrCodeNotFound, fmt.Sprintf("Job not found: %s", id))
+	}
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeInternalError, "Can't get job", err)
+	}
+	return j, nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/deploy"
+	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	shttp "github.com/makerdao/testchain-deployment/pkg/service/http"
+	gonats "github.com/nats-io/go-nats"
+	"github.com/sirupsen/logrus"
+)
+
+// firstUpdater run first update of deployment scripts in background,
+// service is not ready until it is finished, error of it stops service
+type firstUpdater struct {
+	component *deploy.Component
+	mu        sync.Mutex
+	done      bool
+}
+
+func (u *firstUpdater) Run(log *logrus.Entry) error {
+	if err := u.component.FirstUpdate(log); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	u.done = true
+	u.mu.Unlock()
+	log.Info("First update is finished")
+	return nil
+}
+
+func (u *firstUpdater) Shutdown(ctx context.Context, log *logrus.Entry) error {
+	return nil
+}
+
+// Done return true when first update is finished
+func (u *firstUpdater) Done() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.done
+}
+
+// Status is state of service for /status endpoint
+type Status struct {
+	FirstUpdateDone bool       `json:"firstUpdateDone"`
+	Update          bool       `json:"update"`
+	Run             bool       `json:"run"`
+	TagHash         string     `json:"tagHash"`
+	UpdatedAt       *time.Time `json:"updatedAt,omitempty"`
+	NATSConnected   bool       `json:"natsConnected"`
+	Registered      bool       `json:"registered"`
+	RunningJobs     []job.Job  `json:"runningJobs"`
+	QueuedJobs      int        `json:"queuedJobs"`
+	// UndeliveredResults is count of results which are still sent to gateway
+	UndeliveredResults int `json:"undeliveredResults"`
+}
+
+// health collect state of components for health endpoints
+type health struct {
+	log         *logrus.Entry
+	storage     storageBackend
+	firstUpdate *firstUpdater
+	natsConn    *gonats.Conn
+	registrator *gateway.Registrator
+	dispatcher  *gateway.Dispatcher
+	jobRegistry *job.Registry
+	pool        *job.Pool
+}
+
+// checks return readiness checks
+func (h *health) checks() []shttp.Check {
+	return []shttp.Check{
+		{Name: "firstUpdate", Fn: func() error {
+			if !h.firstUpdate.Done() {
+				return errors.New("first update is in progress")
+			}
+			return nil
+		}},
+		{Name: "nats", Fn: func() error {
+			if !h.natsConn.IsConnected() {
+				return errors.New("NATS is not connected")
+			}
+			return nil
+		}},
+		{Name: "gateway", Fn: func() error {
+			if h.registrator.Enabled() && !h.registrator.Registered() {
+				return errors.New("instance is not registered on gateway")
+			}
+			return nil
+		}},
+	}
+}
+
+// status collect state of service
+func (h *health) status() (interface{}, error) {
+	tagHash, err := h.storage.GetTagHash(h.log)
+	if err != nil {
+		return nil, err
+	}
+	updatedAt, err := h.storage.GetUpdatedAt()
+	if err != nil {
+		return nil, err
+	}
+	running, err := h.jobRegistry.List(h.log, job.StateRunning, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Status{
+		FirstUpdateDone:    h.firstUpdate.Done(),
+		Update:             h.storage.GetUpdate(),
+		Run:                h.storage.GetRun(),
+		TagHash:            tagHash,
+		UpdatedAt:          updatedAt,
+		NATSConnected:      h.natsConn.IsConnected(),
+		Registered:         h.registrator.Registered(),
+		RunningJobs:        running,
+		QueuedJobs:         h.pool.QueueDepth(),
+		UndeliveredResults: h.dispatcher.Pending(),
+	}, nil
+}
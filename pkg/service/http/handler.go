@@ -6,14 +6,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
+	"github.com/makerdao/testchain-deployment/pkg/secret"
 	"github.com/makerdao/testchain-deployment/pkg/serror"
 	"github.com/makerdao/testchain-deployment/pkg/service/protocol"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
 	"github.com/sirupsen/logrus"
 )
 
 //HandlerMethod - method will be handled from Main http handler
-type HandlerMethod func(log *logrus.Entry, ID string, requestBytes []byte) (response []byte, error *serror.Error)
+type HandlerMethod = protocol.HandlerMethod
 
 //Handler is main handler for rpc over http
 type Handler struct {
@@ -64,7 +68,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err := r.Body.Close(); err != nil {
 		log.WithError(err).Error("Can't close body after reading")
 	}
-	log.WithField("data", string(reqBytes)).Trace("Request")
+	log.WithField("data", string(secret.RedactRequest(reqBytes))).Trace("Request")
 
 	var req *protocol.Request
 	if err := json.Unmarshal(reqBytes, &req); err != nil {
@@ -72,8 +76,12 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		writeResp(log, w, resp)
 		return
 	}
+	ctx := tracing.ExtractHTTP(r.Context(), r.Header)
+	if !tracing.HasSpan(ctx) {
+		ctx = tracing.Extract(ctx, req.TraceContext)
+	}
 	log = log.WithField("method", req.Method)
-	log.WithField("data", string(req.Data)).Debug("Request data")
+	log.WithField("data", string(secret.RedactRequest(req.Data))).Debug("Request data")
 	if _, ok := h.methods[req.Method]; !ok {
 		resp := prepareErrRespBytes(
 			serror.New(serror.ErrCodeNotFound, fmt.Sprintf("Unknown method: %s", req.Method)),
@@ -82,7 +90,12 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respData, serr := h.methods[req.Method](log, req.ID, req.Data)
+	ctx, span := tracing.StartRPC(ctx, metrics.ServerHTTP, req.Method, req.ID)
+	log = tracing.Log(ctx, log)
+	start := time.Now()
+	respData, serr := h.methods[req.Method](ctx, log, req.ID, req.Data)
+	metrics.ObserveRPC(metrics.ServerHTTP, req.Method, serr, start)
+	tracing.EndRPC(span, serr)
 	if serr != nil {
 		resp := prepareErrRespBytes(serr)
 		writeResp(log, w, resp)
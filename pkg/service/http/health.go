@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+//Paths of health endpoints
+const (
+	HealthPath = "/healthz"
+	ReadyPath  = "/readyz"
+	StatusPath = "/status"
+)
+
+//Check is named readiness check, nil error means that check is passed
+type Check struct {
+	Name string
+	Fn   func() error
+}
+
+//HealthHandler answer ok while process is alive
+type HealthHandler struct{}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+//ReadyHandler answer ok when all checks are passed, 503 with failed checks otherwise
+type ReadyHandler struct {
+	checks []Check
+}
+
+//NewReadyHandler init handler
+func NewReadyHandler(checks ...Check) *ReadyHandler {
+	return &ReadyHandler{checks: checks}
+}
+
+func (h *ReadyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	res := make(map[string]string, len(h.checks))
+	for _, check := range h.checks {
+		if err := check.Fn(); err != nil {
+			status = http.StatusServiceUnavailable
+			res[check.Name] = err.Error()
+			continue
+		}
+		res[check.Name] = "ok"
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"ready":  status == http.StatusOK,
+		"checks": res,
+	})
+}
+
+//StatusHandler return JSON with state of service
+type StatusHandler struct {
+	log    *logrus.Entry
+	status func() (interface{}, error)
+}
+
+//NewStatusHandler init handler, status is collected on each request
+func NewStatusHandler(log *logrus.Entry, status func() (interface{}, error)) *StatusHandler {
+	return &StatusHandler{
+		log:    log.WithField("component", "httpStatus"),
+		status: status,
+	}
+}
+
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Expected http method GET", http.StatusMethodNotAllowed)
+		return
+	}
+	res, err := h.status()
+	if err != nil {
+		h.log.WithError(err).Error("Can't collect status")
+		http.Error(w, "Can't collect status", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
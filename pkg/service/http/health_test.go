@@ -0,0 +1,41 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyHandler(t *testing.T) {
+	ready := false
+	handler := NewReadyHandler(
+		Check{Name: "nats", Fn: func() error { return nil }},
+		Check{Name: "firstUpdate", Fn: func() error {
+			if !ready {
+				return errors.New("first update is in progress")
+			}
+			return nil
+		}},
+	)
+	get := func() (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadyPath, nil))
+		var res map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, res
+	}
+
+	code, res := get()
+	checks, _ := res["checks"].(map[string]interface{})
+	if code != http.StatusServiceUnavailable || res["ready"] != false || checks["firstUpdate"] != "first update is in progress" {
+		t.Errorf("Not ready is expected, got %d %v", code, res)
+	}
+	ready = true
+	if code, res := get(); code != http.StatusOK || res["ready"] != true {
+		t.Errorf("Ready is expected, got %d %v", code, res)
+	}
+}
@@ -0,0 +1,292 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
+	"github.com/makerdao/testchain-deployment/pkg/secret"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+//JSONRPCPath is path of JSON-RPC 2.0 endpoint
+const JSONRPCPath = "/jsonrpc"
+
+//JSONRPCVersion is value of jsonrpc field
+const JSONRPCVersion = "2.0"
+
+//Codes of JSON-RPC errors, codes from -32000 to -32099 are used for errors of methods
+const (
+	JSONRPCCodeParseError     = -32700
+	JSONRPCCodeInvalidRequest = -32600
+	JSONRPCCodeMethodNotFound = -32601
+	JSONRPCCodeInvalidParams  = -32602
+	JSONRPCCodeInternalError  = -32603
+	JSONRPCCodeNotFound       = -32004
+	JSONRPCCodeBusy           = -32005
+)
+
+//JSONRPCCode return code of JSON-RPC error for code of service error
+func JSONRPCCode(code serror.ErrCode) int {
+	switch code {
+	case serror.ErrCodeBadRequest:
+		return JSONRPCCodeInvalidParams
+	case serror.ErrCodeNotFound:
+		return JSONRPCCodeNotFound
+	case serror.ErrCodeBusy:
+		return JSONRPCCodeBusy
+	default:
+		return JSONRPCCodeInternalError
+	}
+}
+
+//JSONRPCRequest is request of JSON-RPC 2.0, request without id is notification
+type JSONRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	// TraceContext is W3C trace context, it is used when headers have no trace context
+	TraceContext map[string]string `json:"traceContext,omitempty"`
+}
+
+//JSONRPCResponse is response of JSON-RPC 2.0
+type JSONRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+//JSONRPCError is error object of JSON-RPC 2.0, data is error of service if it is known
+type JSONRPCError struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *serror.Error `json:"data,omitempty"`
+}
+
+//JSONRPCHandler serve JSON-RPC 2.0 with methods of rpc handler, batch requests and notifications are supported
+type JSONRPCHandler struct {
+	log     *logrus.Entry
+	handler *Handler
+	// async is names of methods which start job, id of job is added to their result
+	async map[string]bool
+}
+
+//NewJSONRPCHandler init handler, methods are taken from rpc handler
+func NewJSONRPCHandler(handler *Handler, asyncMethods ...string) *JSONRPCHandler {
+	async := make(map[string]bool, len(asyncMethods))
+	for _, name := range asyncMethods {
+		async[name] = true
+	}
+	return &JSONRPCHandler{
+		log:     handler.log.WithField("component", "jsonrpcServer"),
+		handler: handler,
+		async:   async,
+	}
+}
+
+func (h *JSONRPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := h.log
+	if r.Method != http.MethodPost {
+		http.Error(w, "Expected http method POST", http.StatusMethodNotAllowed)
+		return
+	}
+	reqBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusOK, newJSONRPCError(nil, JSONRPCCodeParseError, "Can't read request body"))
+		return
+	}
+	if err := r.Body.Close(); err != nil {
+		log.WithError(err).Error("Can't close body after reading")
+	}
+	log.WithField("data", string(secret.RedactRequest(reqBytes))).Trace("Request")
+
+	ctx := tracing.ExtractHTTP(r.Context(), r.Header)
+	trimmed := bytes.TrimSpace(reqBytes)
+	if len(trimmed) != 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			writeJSON(w, http.StatusOK, newJSONRPCError(nil, JSONRPCCodeParseError, "Body is not valid json"))
+			return
+		}
+		if len(batch) == 0 {
+			writeJSON(w, http.StatusOK, newJSONRPCError(nil, JSONRPCCodeInvalidRequest, "Batch is empty"))
+			return
+		}
+		responses := make([]*JSONRPCResponse, 0, len(batch))
+		for _, item := range batch {
+			if resp := h.serve(ctx, log, item); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		// nothing is returned for batch of notifications
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+
+	resp := h.serve(ctx, log, trimmed)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+//serve call method for single request, nil is returned for notification
+func (h *JSONRPCHandler) serve(ctx context.Context, log *logrus.Entry, data []byte) (resp *JSONRPCResponse) {
+	var req JSONRPCRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return newJSONRPCError(nil, JSONRPCCodeParseError, "Body is not valid json")
+		}
+		return newJSONRPCError(nil, JSONRPCCodeInvalidRequest, "Request is not valid JSON-RPC 2.0 request")
+	}
+	notification := len(req.ID) == 0
+	if req.JSONRPC != JSONRPCVersion || req.Method == "" {
+		return newJSONRPCError(req.ID, JSONRPCCodeInvalidRequest, "Request is not valid JSON-RPC 2.0 request")
+	}
+	if err := validateRequestID(req.ID); err != nil {
+		return newJSONRPCError(nil, JSONRPCCodeInvalidRequest, err.Error())
+	}
+	// id of request is chosen by client and is used only in response,
+	// so id of job is generated and clients can't collide with each other
+	id, err := newJobID()
+	if err != nil {
+		return newJSONRPCError(req.ID, JSONRPCCodeInternalError, "Can't generate id of job")
+	}
+	log = log.WithField("method", req.Method)
+	methodFunc, ok := h.handler.methods[req.Method]
+	if !ok {
+		if notification {
+			return nil
+		}
+		return newJSONRPCError(req.ID, JSONRPCCodeMethodNotFound, fmt.Sprintf("Unknown method: %s", req.Method))
+	}
+	params := bytes.TrimSpace(req.Params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		params = []byte("{}")
+	}
+	if params[0] != '{' {
+		if notification {
+			return nil
+		}
+		return newJSONRPCError(req.ID, JSONRPCCodeInvalidParams, "Params should be object")
+	}
+
+	if !tracing.HasSpan(ctx) {
+		ctx = tracing.Extract(ctx, req.TraceContext)
+	}
+	ctx, span := tracing.StartRPC(ctx, metrics.ServerJSONRPC, req.Method, id)
+	log = tracing.Log(ctx, log)
+	log.WithField("data", string(secret.RedactRequest(params))).Debug("Request data")
+	start := time.Now()
+	var res []byte
+	var serr *serror.Error
+	func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				serr = serror.New(serror.ErrCodeInternalError, fmt.Sprintf("Unexpected internal error, %+v", rec))
+			}
+		}()
+		res, serr = methodFunc(ctx, log, id, params)
+	}()
+	metrics.ObserveRPC(metrics.ServerJSONRPC, req.Method, serr, start)
+	tracing.EndRPC(span, serr)
+
+	if notification {
+		return nil
+	}
+	if serr != nil {
+		log.WithField("code", serr.Code).Debug("Response error")
+		return &JSONRPCResponse{
+			JSONRPC: JSONRPCVersion,
+			Error: &JSONRPCError{
+				Code:    JSONRPCCode(serr.Code),
+				Message: serr.Detail,
+				Data:    serr,
+			},
+			ID: req.ID,
+		}
+	}
+	if len(res) == 0 {
+		res = []byte("null")
+	}
+	if h.async[req.Method] {
+		res = withJobID(res, id)
+	}
+	return &JSONRPCResponse{
+		JSONRPC: JSONRPCVersion,
+		Result:  res,
+		ID:      req.ID,
+	}
+}
+
+//validateRequestID check that id of request is string, integer or null
+func validateRequestID(id json.RawMessage) error {
+	if len(id) == 0 || bytes.Equal(id, []byte("null")) {
+		return nil
+	}
+	var val interface{}
+	dec := json.NewDecoder(bytes.NewReader(id))
+	dec.UseNumber()
+	if err := dec.Decode(&val); err != nil {
+		return err
+	}
+	switch v := val.(type) {
+	case string:
+		return nil
+	case json.Number:
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err != nil {
+			return fmt.Errorf("id should be string or integer")
+		}
+		return nil
+	default:
+		return fmt.Errorf("id should be string or integer")
+	}
+}
+
+//newJobID return random id which is used as id of operation
+func newJobID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+//withJobID add `jobId` to result object, result which is not an object is returned as is
+func withJobID(res []byte, id string) []byte {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(res, &obj); err != nil || obj == nil {
+		return res
+	}
+	obj["jobId"], _ = json.Marshal(id)
+	withID, err := json.Marshal(obj)
+	if err != nil {
+		return res
+	}
+	return withID
+}
+
+func newJSONRPCError(id json.RawMessage, code int, message string) *JSONRPCResponse {
+	return &JSONRPCResponse{
+		JSONRPC: JSONRPCVersion,
+		Error:   &JSONRPCError{Code: code, Message: message},
+		ID:      id,
+	}
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestJSONRPCHandler(t *testing.T) (*JSONRPCHandler, func() []string) {
+	var mu sync.Mutex
+	ids := make([]string, 0)
+	handler := NewHandler(logrus.NewEntry(logrus.New()))
+	if err := handler.AddMethod("Echo", func(ctx context.Context, log *logrus.Entry, id string, data []byte) ([]byte, *serror.Error) {
+		mu.Lock()
+		ids = append(ids, id)
+		mu.Unlock()
+		return data, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.AddMethod("Queue", func(ctx context.Context, log *logrus.Entry, id string, data []byte) ([]byte, *serror.Error) {
+		mu.Lock()
+		ids = append(ids, id)
+		mu.Unlock()
+		return []byte(`{"queuePosition":0}`), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.AddMethod("Busy", func(ctx context.Context, log *logrus.Entry, id string, data []byte) ([]byte, *serror.Error) {
+		return nil, serror.New(serror.ErrCodeBusy, "Too many deployments in progress")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return NewJSONRPCHandler(handler, "Queue"), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return ids
+	}
+}
+
+func postJSONRPC(h http.Handler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, JSONRPCPath, strings.NewReader(body)))
+	return rec
+}
+
+func TestJSONRPCHandler(t *testing.T) {
+	h, calledIDs := newTestJSONRPCHandler(t)
+	for _, tc := range []struct {
+		body     string
+		expected string
+	}{
+		{`{"jsonrpc":"2.0","method":"Echo","params":{"a":1},"id":"req-1"}`, `{"jsonrpc":"2.0","result":{"a":1},"id":"req-1"}`},
+		{`{"jsonrpc":"2.0","method":"Echo","id":7}`, `{"jsonrpc":"2.0","result":{},"id":7}`},
+		{`{"jsonrpc":"2.0","method":"Echo","params":{},"id":null}`, `{"jsonrpc":"2.0","result":{},"id":null}`},
+		{`{"jsonrpc":"2.0","method":"Busy","id":1}`, `{"jsonrpc":"2.0","error":{"code":-32005,"message":"Too many deployments in progress","data":{"code":"busy","detail":"Too many deployments in progress","errorList":[]}},"id":1}`},
+		{`{"jsonrpc":"2.0","method":"Unknown","id":1}`, `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Unknown method: Unknown"},"id":1}`},
+		{`{"jsonrpc":"2.0","method":"Echo","params":[1],"id":1}`, `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Params should be object"},"id":1}`},
+		{`{"method":"Echo","id":1}`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Request is not valid JSON-RPC 2.0 request"},"id":1}`},
+		{`{"jsonrpc":"2.0","method":"Echo","id":1.5}`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"id should be string or integer"},"id":null}`},
+		{`{"jsonrpc":"2.0",`, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Body is not valid json"},"id":null}`},
+		{`[]`, `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Batch is empty"},"id":null}`},
+	} {
+		rec := postJSONRPC(h, tc.body)
+		if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != tc.expected {
+			t.Errorf("Unexpected response for %s: %d %s", tc.body, rec.Code, rec.Body.String())
+		}
+	}
+	// id of job is generated by server, id of request is used only in response
+	if ids := calledIDs(); len(ids) != 3 || len(ids[0]) != 32 || ids[0] == ids[1] || ids[1] == ids[2] {
+		t.Errorf("Unexpected ids of jobs: %v", ids)
+	}
+}
+
+func TestJSONRPCHandlerJobID(t *testing.T) {
+	h, calledIDs := newTestJSONRPCHandler(t)
+	var ids []string
+	for i := 0; i < 2; i++ {
+		// clients use the same id of request, but get different jobs
+		rec := postJSONRPC(h, `{"jsonrpc":"2.0","method":"Queue","params":{},"id":1}`)
+		var resp struct {
+			Result struct {
+				JobID         string `json:"jobId"`
+				QueuePosition *int   `json:"queuePosition"`
+			} `json:"result"`
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if string(resp.ID) != "1" || resp.Result.QueuePosition == nil || len(resp.Result.JobID) != 32 {
+			t.Fatalf("Unexpected response: %s", rec.Body.String())
+		}
+		ids = append(ids, resp.Result.JobID)
+	}
+	if called := calledIDs(); ids[0] == ids[1] || strings.Join(called, ",") != strings.Join(ids, ",") {
+		t.Errorf("Id of job in result must be id of called method, got %v, called %v", ids, called)
+	}
+}
+
+func TestJSONRPCHandlerBatch(t *testing.T) {
+	h, calledIDs := newTestJSONRPCHandler(t)
+	rec := postJSONRPC(h, `[
+		{"jsonrpc":"2.0","method":"Echo","params":{"a":1},"id":1},
+		{"jsonrpc":"2.0","method":"Echo","params":{"b":2}},
+		{"jsonrpc":"2.0","method":"Busy","id":2},
+		1
+	]`)
+	var responses []JSONRPCResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("Responses for requests with id and invalid request are expected, got %s", rec.Body.String())
+	}
+	if string(responses[0].ID) != "1" || string(responses[0].Result) != `{"a":1}` {
+		t.Errorf("Unexpected response: %+v", responses[0])
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != JSONRPCCodeBusy {
+		t.Errorf("Busy error is expected, got %+v", responses[1])
+	}
+	if responses[2].Error == nil || responses[2].Error.Code != JSONRPCCodeInvalidRequest {
+		t.Errorf("Invalid request error is expected, got %+v", responses[2])
+	}
+	if len(calledIDs()) != 2 {
+		t.Errorf("Notification must be handled, got %v", calledIDs())
+	}
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","method":"Echo"}`,
+		`[{"jsonrpc":"2.0","method":"Echo"},{"jsonrpc":"2.0","method":"Unknown"}]`,
+	} {
+		if rec := postJSONRPC(h, body); rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+			t.Errorf("No content is expected for notifications %s, got %d %s", body, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/makerdao/testchain-deployment/pkg/logstream"
+	"github.com/sirupsen/logrus"
+)
+
+//LogsPath is prefix of path for logs of deployment, id of deployment is the rest of path
+const LogsPath = "/logs/"
+
+//LogsHandler stream output of deployment as server sent events
+type LogsHandler struct {
+	log *logrus.Entry
+	hub *logstream.Hub
+}
+
+//NewLogsHandler init handler
+func NewLogsHandler(log *logrus.Entry, hub *logstream.Hub) *LogsHandler {
+	return &LogsHandler{
+		log: log.WithField("component", "httpLogs"),
+		hub: hub,
+	}
+}
+
+func (h *LogsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, LogsPath)
+	log := h.log.WithField("id", id)
+	if r.Method != http.MethodGet {
+		http.Error(w, "Expected http method GET", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	backlog, lines, unsubscribe, ok := h.hub.Subscribe(id)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Logs not found: %s", id), http.StatusNotFound)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, line := range backlog {
+		if err := writeEvent(w, "log", line); err != nil {
+			log.WithError(err).Debug("Can't write log line")
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line, ok := <-lines:
+			if !ok {
+				if err := writeEvent(w, "end", struct{}{}); err != nil {
+					log.WithError(err).Debug("Can't write end of logs")
+				}
+				flusher.Flush()
+				return
+			}
+			if err := writeEvent(w, "log", line); err != nil {
+				log.WithError(err).Debug("Can't write log line")
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, data interface{}) error {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, dataBytes)
+	return err
+}
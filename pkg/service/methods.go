@@ -0,0 +1,195 @@
+package service
+
+import (
+	"github.com/makerdao/testchain-deployment/pkg/deploy"
+	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/service/methods"
+	"github.com/makerdao/testchain-deployment/pkg/service/registry"
+	"github.com/makerdao/testchain-deployment/pkg/webhook"
+)
+
+// emptyResponse is response of methods which return `{}`
+type emptyResponse struct{}
+
+// newRegistry register all rpc methods, every transport takes methods from registry
+func newRegistry(m *methods.Methods) (*registry.Registry, error) {
+	reg := registry.New()
+	list := []registry.Method{
+		{
+			Name:        "GetRefs",
+			Handler:     m.GetRefs,
+			Mode:        registry.ModeSync,
+			Description: "Return remote refs of GIT repo",
+			Request:     methods.GetRefsRequest{},
+			Response:    []git.Ref{},
+		},
+		{
+			Name:        "GetManifest",
+			Handler:     m.GetManifest,
+			Mode:        registry.ModeSync,
+			Description: "Return deploy manifest of commit",
+			Request:     methods.GetManifestReq{},
+			Response:    deploy.Manifest{},
+		},
+		{
+			Name:        "Deploy",
+			Handler:     m.Deploy,
+			Mode:        registry.ModeAsync,
+			Description: "Run scenario of repo in pool, result is sent to gateway",
+			Request:     methods.DeployRequest{},
+			Response:    methods.DeployResponse{},
+		},
+		{
+			Name:        "DeployPipeline",
+			Handler:     m.DeployPipeline,
+			Mode:        registry.ModeAsync,
+			Description: "Run stages of pipeline in dependency order, result is sent to gateway",
+			Request:     methods.DeployPipelineRequest{},
+			Response:    methods.DeployResponse{},
+		},
+		{
+			Name:        "CancelDeployment",
+			Handler:     m.CancelDeployment,
+			Mode:        registry.ModeSync,
+			Description: "Cancel queued or running deployment",
+			Request:     methods.CancelDeploymentRequest{},
+			Response:    emptyResponse{},
+		},
+		{
+			Name:        "GetJob",
+			Handler:     m.GetJob,
+			Mode:        registry.ModeSync,
+			Description: "Return state and result of async operation",
+			Request:     methods.GetJobRequest{},
+			Response:    job.Job{},
+		},
+		{
+			Name:        "ListJobs",
+			Handler:     m.ListJobs,
+			Mode:        registry.ModeSync,
+			Description: "Return async operations filtered by state and method",
+			Request:     methods.ListJobsRequest{},
+			Response:    []job.Job{},
+		},
+		{
+			Name:        "GetCacheStatus",
+			Handler:     m.GetCacheStatus,
+			Mode:        registry.ModeSync,
+			Description: "Return state of cache of GIT repos",
+			Response:    git.CacheStatus{},
+		},
+		{
+			Name:        "PurgeCache",
+			Handler:     m.PurgeCache,
+			Mode:        registry.ModeSync,
+			Description: "Remove repos from cache",
+			Request:     methods.PurgeCacheRequest{},
+			Response:    methods.PurgeCacheResponse{},
+		},
+		{
+			Name:        "ListUndelivered",
+			Handler:     m.ListUndelivered,
+			Mode:        registry.ModeSync,
+			Description: "Return results which are not delivered to gateway yet",
+			Response:    []gateway.Delivery{},
+		},
+		{
+			Name:        "RedeliverResult",
+			Handler:     m.RedeliverResult,
+			Mode:        registry.ModeSync,
+			Description: "Send undelivered result to gateway again",
+			Request:     methods.RedeliverResultRequest{},
+			Response:    emptyResponse{},
+		},
+		{
+			Name:        "RegisterWebhook",
+			Handler:     m.RegisterWebhook,
+			Mode:        registry.ModeSync,
+			Description: "Register webhook for lifecycle events of jobs",
+			Request:     methods.RegisterWebhookRequest{},
+			Response:    webhook.Hook{},
+		},
+		{
+			Name:        "UnregisterWebhook",
+			Handler:     m.UnregisterWebhook,
+			Mode:        registry.ModeSync,
+			Description: "Remove webhook",
+			Request:     methods.UnregisterWebhookRequest{},
+			Response:    emptyResponse{},
+		},
+		{
+			Name:        "ListWebhooks",
+			Handler:     m.ListWebhooks,
+			Mode:        registry.ModeSync,
+			Description: "Return registered webhooks",
+			Response:    []webhook.Hook{},
+		},
+		{
+			Name:        "ListMethods",
+			Handler:     reg.ListMethods,
+			Mode:        registry.ModeSync,
+			Description: "Return methods with schemas of request and response data",
+			Response:    []registry.MethodInfo{},
+		},
+		// TODO: remove deprecated methods
+		{
+			Name:        "GetInfo",
+			Handler:     m.GetInfo,
+			Mode:        registry.ModeSync,
+			Description: "Return steps and hash of tag of deployment scripts",
+			Response:    methods.GetInfoResponse{},
+			Deprecated:  true,
+		},
+		{
+			Name:        "GetResult",
+			Handler:     m.GetResult,
+			Mode:        registry.ModeSync,
+			Description: "Return result of last run",
+			Response:    deploy.ResultModel{},
+			Deprecated:  true,
+		},
+		{
+			Name:        "GetCommitList",
+			Handler:     m.GetCommitList,
+			Mode:        registry.ModeSync,
+			Description: "Return commits of deployment scripts",
+			Response:    methods.GetCommitListResponse{},
+			Deprecated:  true,
+		},
+		{
+			Name:        "Run",
+			Handler:     m.Run,
+			Mode:        registry.ModeAsync,
+			Description: "Run step of deployment scripts, result is sent to gateway",
+			Request:     methods.RunRequest{},
+			Response:    emptyResponse{},
+			Deprecated:  true,
+		},
+		{
+			Name:        "UpdateSource",
+			Handler:     m.Update,
+			Mode:        registry.ModeAsync,
+			Description: "Update deployment scripts, result is sent to gateway",
+			Request:     methods.UpdateRequest{},
+			Response:    emptyResponse{},
+			Deprecated:  true,
+		},
+		{
+			Name:        "Checkout",
+			Handler:     m.Checkout,
+			Mode:        registry.ModeAsync,
+			Description: "Checkout deployment scripts to commit, result is sent to gateway",
+			Request:     methods.CommitRequest{},
+			Response:    emptyResponse{},
+			Deprecated:  true,
+		},
+	}
+	for _, method := range list {
+		if err := reg.Add(method); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
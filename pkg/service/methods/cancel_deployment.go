@@ -0,0 +1,52 @@
+package methods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+)
+
+//CancelDeploymentRequest request data
+type CancelDeploymentRequest struct {
+	ID string `json:"id"`
+}
+
+//CancelDeployment stop running Deploy, DeployPipeline or Run by its request ID,
+//result with type cancelled will be sent to gateway by stopped operation
+func (m *Methods) CancelDeployment(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	var req CancelDeploymentRequest
+	if err := json.Unmarshal(requestBytes, &req); err != nil {
+		return nil, serror.NewUnmarshalReqErr(err)
+	}
+
+	j, err := m.jobRegistry.Get(log, req.ID)
+	if err == job.ErrNotFound {
+		return nil, serror.New(serror.ErrCodeNotFound, fmt.Sprintf("Deployment not found: %s", req.ID))
+	}
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeInternalError, "Can't get job", err)
+	}
+	if j.Method != "Deploy" && j.Method != "DeployPipeline" && j.Method != "Run" {
+		return nil, serror.New(serror.ErrCodeBadRequest, fmt.Sprintf("Job %s is not a deployment", req.ID))
+	}
+	// queued deployment is removed from pool and is finished by it
+	if m.pool.Cancel(req.ID) {
+		log.Infof("Queued deployment %s cancelled", req.ID)
+		return []byte(`{}`), nil
+	}
+	if err := m.jobRegistry.Cancel(log, req.ID); err != nil {
+		return nil, serror.New(serror.ErrCodeNotFound, fmt.Sprintf("Running deployment not found: %s", req.ID))
+	}
+	log.Infof("Deployment %s cancelled", req.ID)
+
+	return []byte(`{}`), nil
+}
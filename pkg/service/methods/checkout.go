@@ -1,24 +1,33 @@
 package methods
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/job"
 	"github.com/makerdao/testchain-deployment/pkg/serror"
 )
 
 type CommitRequest struct {
 	Commit string `json:"commit"`
+	// Callback overrides target of result
+	Callback *gateway.Callback `json:"callback,omitempty"`
 }
 
 //Checkout to commit if it possible
 func (m *Methods) Checkout(
+	ctx context.Context,
 	log *logrus.Entry,
 	id string,
 	requestBytes []byte,
 ) (response []byte, error *serror.Error) {
+	if err := m.checkFirstUpdate(); err != nil {
+		return nil, err
+	}
 	if m.storage.GetUpdate() {
 		return nil, serror.New(serror.ErrCodeInternalError, "Deploy script updating in progress")
 	}
@@ -29,18 +38,35 @@ func (m *Methods) Checkout(
 	if err := json.Unmarshal(requestBytes, &req); err != nil {
 		return nil, serror.New(serror.ErrCodeBadRequest, "Can't decode request")
 	}
+	if err := m.validateCallback(req.Callback); err != nil {
+		return nil, err
+	}
+	ctx, err := m.queueJob(ctx, log, job.Job{
+		ID:     id,
+		Method: "Checkout",
+		Commit: &git.Commit{Rev: req.Commit},
+	})
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Can't register checkout", err)
+	}
 	go func(id string) {
+		ctx, span := m.startJob(ctx, log, id)
+		defer span.End()
 		resultReq := &gateway.CheckoutResultRequest{
-			ID: id,
+			ID:       id,
+			Callback: req.Callback,
 		}
+		defer func() {
+			m.finishJob(ctx, log, id, string(resultReq.Type), resultReq.Result)
+		}()
 		if err := m.deployComponent.Checkout(log, req.Commit); err != nil {
-			if err := m.gatewayClient.CheckoutResult(log, resultReq.SetErr(err)); err != nil {
+			if err := m.dispatcher.CheckoutResult(ctx, log, resultReq.SetErr(err)); err != nil {
 				log.WithError(err).Error("Can't send request with result of run to gateway with error")
 			}
 			return
 		}
 		resultReq.Type = gateway.CheckoutResultRequestTypeOK
-		if err := m.gatewayClient.CheckoutResult(log, resultReq); err != nil {
+		if err := m.dispatcher.CheckoutResult(ctx, log, resultReq); err != nil {
 			log.WithError(err).Error("Can't send request with result of run to gateway")
 		}
 	}(id)
@@ -0,0 +1,54 @@
+package methods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+)
+
+//ListUndelivered return results which are not delivered to gateway yet, including dead ones
+func (m *Methods) ListUndelivered(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	resBytes, err := json.Marshal(m.dispatcher.List())
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
+
+	return resBytes, nil
+}
+
+//RedeliverResultRequest request data
+type RedeliverResultRequest struct {
+	Key string `json:"key"`
+}
+
+//RedeliverResult send undelivered result to gateway again
+func (m *Methods) RedeliverResult(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	var req RedeliverResultRequest
+	if err := json.Unmarshal(requestBytes, &req); err != nil {
+		return nil, serror.NewUnmarshalReqErr(err)
+	}
+
+	err := m.dispatcher.Redeliver(req.Key)
+	if err == gateway.ErrDeliveryNotFound {
+		return nil, serror.New(serror.ErrCodeNotFound, fmt.Sprintf("Undelivered result not found: %s", req.Key))
+	}
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeInternalError, "Can't schedule redelivery", err)
+	}
+
+	return []byte(`{}`), nil
+}
@@ -1,11 +1,14 @@
 package methods
 
 import (
+	"context"
 	"encoding/json"
+	"strconv"
 
 	"github.com/makerdao/testchain-deployment/pkg/deploy"
 	"github.com/makerdao/testchain-deployment/pkg/gateway"
 	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/job"
 	"github.com/makerdao/testchain-deployment/pkg/serror"
 	"github.com/sirupsen/logrus"
 )
@@ -16,11 +19,27 @@ type DeployRequest struct {
 	RepoRef    string            `json:"repoRef"`
 	RepoRev    string            `json:"repoRev"`
 	ScenarioNr int               `json:"scenarioNr"`
+	ScenarioID string            `json:"scenarioId"` // used instead of ScenarioNr if set
 	EnvVars    map[string]string `json:"envVars"`
+	// Account is provisioned for deployment and set to ETH_KEYSTORE, ETH_PASSWORD and ETH_FROM
+	Account *deploy.AccountModel `json:"account,omitempty"`
+	// SnapshotID is id of chain snapshot, it is part of key of result cache
+	SnapshotID string `json:"snapshotId,omitempty"`
+	// NoCache run deployment even if result is cached
+	NoCache bool `json:"noCache,omitempty"`
+	// Callback overrides target of result
+	Callback *gateway.Callback `json:"callback,omitempty"`
 }
 
-//Run deployment async and return ok if it possible
+//DeployResponse response data
+type DeployResponse struct {
+	// QueuePosition is zero when deployment started immediately
+	QueuePosition int `json:"queuePosition"`
+}
+
+//Run deployment async in pool and return position in queue if it possible
 func (m *Methods) Deploy(
+	ctx context.Context,
 	log *logrus.Entry,
 	id string,
 	requestBytes []byte,
@@ -29,22 +48,72 @@ func (m *Methods) Deploy(
 	if err := json.Unmarshal(requestBytes, &req); err != nil {
 		return nil, serror.NewUnmarshalReqErr(err)
 	}
+	if err := m.validateCallback(req.Callback); err != nil {
+		return nil, err
+	}
+
+	commit := git.Commit{
+		URL: req.RepoURL,
+		Ref: req.RepoRef,
+		Rev: req.RepoRev,
+	}
+	deployment := deploy.Deployment{
+		Commit:        commit,
+		ScenarioID:    req.ScenarioID,
+		ScenarioNr:    req.ScenarioNr,
+		DeployEnvVars: req.EnvVars,
+		Account:       req.Account,
+		SnapshotID:    req.SnapshotID,
+		NoCache:       req.NoCache,
+	}
+	// pre-flight check, so bad request doesn't wait in queue
+	if errs := m.deployer.Validate(log, deployment); len(errs) != 0 {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Invalid deployment request", errs...)
+	}
+
+	scenario := req.ScenarioID
+	if scenario == "" {
+		scenario = strconv.Itoa(req.ScenarioNr)
+	}
+	ctx, err := m.queueJob(ctx, log, job.Job{
+		ID:       id,
+		Method:   "Deploy",
+		Commit:   &commit,
+		Scenario: scenario,
+	})
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Can't register deployment", err)
+	}
 
-	go func(id string, req DeployRequest) {
+	// closure doesn't capture request, so secrets of env vars and account are released after run
+	callback := req.Callback
+	position, err := m.pool.Submit(id, req.RepoURL, func() {
+		ctx, span := m.startJob(ctx, log, id)
+		defer span.End()
+		m.logHub.Open(id, m.deployer.OutputRedactor(deployment.DeployEnvVars, deployment.Account))
+		defer m.logHub.Close(id)
 		resultReq := &gateway.RunResultRequest{
-			ID: id,
-		}
-		deployment := deploy.Deployment{
-			Commit: git.Commit{
-				URL: req.RepoURL,
-				Ref: req.RepoRef,
-				Rev: req.RepoRev,
-			},
-			ScenarioNr:    req.ScenarioNr,
-			DeployEnvVars: req.EnvVars,
+			ID:       id,
+			Callback: callback,
 		}
+		defer func() {
+			m.finishJob(ctx, log, id, string(resultReq.Type), resultReq.Result)
+		}()
+		deployment.Stdout = m.logHub.Writer(id, "stdout")
+		deployment.Stderr = m.logHub.Writer(id, "stderr")
+		deployment.OnStage = m.onStage(log, id)
 
-		res, resErr := deploy.Deploy(log, deployment)
+		res, resErr := m.deployer.Deploy(ctx, log, deployment)
+		// secrets are not kept in memory after run
+		deployment.DeployEnvVars = nil
+		deployment.Account = nil
+		// finished operation is not reported as cancelled if cancel came after it
+		if resErr != nil && ctx.Err() == context.Canceled {
+			if err := m.dispatcher.RunResult(ctx, log, resultReq.SetCancelled()); err != nil {
+				log.WithError(err).Error("Can't send request with cancel of deployment to gateway")
+			}
+			return
+		}
 		if resErr != nil {
 			resultReq.Type = gateway.RunResultRequestTypeErr
 			errResBytes, err := json.Marshal(resErr)
@@ -52,7 +121,7 @@ func (m *Methods) Deploy(
 				log.WithError(err).Error("Can't marshal error for deploy result")
 			}
 			resultReq.Result = errResBytes
-			if err := m.gatewayClient.RunResult(log, resultReq); err != nil {
+			if err := m.dispatcher.RunResult(ctx, log, resultReq); err != nil {
 				log.WithError(err).Error("Can't send request with result of deployment to gateway with error")
 			}
 			return
@@ -64,10 +133,23 @@ func (m *Methods) Deploy(
 		}
 		resultReq.Type = gateway.RunResultRequestTypeOK
 		resultReq.Result = resBytes
-		if err := m.gatewayClient.RunResult(log, resultReq); err != nil {
+		if err := m.dispatcher.RunResult(ctx, log, resultReq); err != nil {
 			log.WithError(err).Error("Can't send request with result of run to gateway")
 		}
-	}(id, req)
+	}, m.dropJob(ctx, log, id, callback))
+	if err != nil {
+		m.finishJob(ctx, log, id, gateway.RunResultRequestTypeErr, gateway.ErrorMessage(err))
+		if err == job.ErrQueueFull {
+			return nil, serror.New(serror.ErrCodeBusy, "Too many deployments in progress, try later", err)
+		}
+		return nil, serror.New(serror.ErrCodeInternalError, "Can't start deployment", err)
+	}
+	log.Infof("Deployment %s accepted, position in queue: %d", id, position)
+
+	resBytes, err := json.Marshal(DeployResponse{QueuePosition: position})
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
 
-	return []byte(`{}`), nil
+	return resBytes, nil
 }
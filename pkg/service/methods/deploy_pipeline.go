@@ -0,0 +1,126 @@
+package methods
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/makerdao/testchain-deployment/pkg/deploy"
+	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+)
+
+//DeployPipelineRequest request data
+type DeployPipelineRequest struct {
+	Stages []deploy.PipelineStage `json:"stages"`
+	// Callback overrides target of result
+	Callback *gateway.Callback `json:"callback,omitempty"`
+}
+
+//DeployPipeline run stages of pipeline async in pool one by one in dependency order,
+//result with status of each stage is sent to gateway
+func (m *Methods) DeployPipeline(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	var req DeployPipelineRequest
+	if err := json.Unmarshal(requestBytes, &req); err != nil {
+		return nil, serror.NewUnmarshalReqErr(err)
+	}
+	if err := m.validateCallback(req.Callback); err != nil {
+		return nil, err
+	}
+	if len(req.Stages) == 0 {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Pipeline has no stages")
+	}
+	stages, err := deploy.SortPipelineStages(req.Stages)
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Bad stages of pipeline", err)
+	}
+	// pre-flight check of each stage, so bad request doesn't wait in queue
+	if errs := m.deployer.ValidatePipeline(log, stages); len(errs) != 0 {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Invalid pipeline request", errs...)
+	}
+
+	ctx, err = m.queueJob(ctx, log, job.Job{
+		ID:     id,
+		Method: "DeployPipeline",
+	})
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Can't register deployment", err)
+	}
+
+	// closure doesn't capture request, so secrets of env vars and account are released after run
+	callback := req.Callback
+	// pipeline takes slot of repo of each stage
+	repos := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		repos = append(repos, stage.Commit.URL)
+	}
+	position, err := m.pool.SubmitKeys(id, repos, func() {
+		ctx, span := m.startJob(ctx, log, id)
+		defer span.End()
+		m.logHub.Open(id, m.deployer.PipelineOutputRedactor(stages))
+		defer m.logHub.Close(id)
+		resultReq := &gateway.RunResultRequest{
+			ID:       id,
+			Callback: callback,
+		}
+		defer func() {
+			m.finishJob(ctx, log, id, string(resultReq.Type), resultReq.Result)
+		}()
+
+		res, err := m.deployer.DeployPipeline(ctx, log, stages,
+			m.logHub.Writer(id, "stdout"), m.logHub.Writer(id, "stderr"), m.onStage(log, id))
+		// secrets are not kept in memory after run
+		for i := range stages {
+			stages[i].EnvVars = nil
+			stages[i].Account = nil
+		}
+		// finished operation is not reported as cancelled if cancel came after it
+		if (err != nil || !res.Succeeded()) && ctx.Err() == context.Canceled {
+			if err := m.dispatcher.RunResult(ctx, log, resultReq.SetCancelled()); err != nil {
+				log.WithError(err).Error("Can't send request with cancel of pipeline to gateway")
+			}
+			return
+		}
+		if err != nil {
+			if err := m.dispatcher.RunResult(ctx, log, resultReq.SetErr(err)); err != nil {
+				log.WithError(err).Error("Can't send request with result of pipeline to gateway with error")
+			}
+			return
+		}
+
+		resBytes, err := json.Marshal(res)
+		if err != nil {
+			log.WithError(err).Error("Can't marshal pipeline result")
+		}
+		// result with stages is sent in both cases, so gateway knows which stages are done
+		resultReq.Type = gateway.RunResultRequestTypeOK
+		if !res.Succeeded() {
+			resultReq.Type = gateway.RunResultRequestTypeErr
+		}
+		resultReq.Result = resBytes
+		if err := m.dispatcher.RunResult(ctx, log, resultReq); err != nil {
+			log.WithError(err).Error("Can't send request with result of pipeline to gateway")
+		}
+	}, m.dropJob(ctx, log, id, callback))
+	if err != nil {
+		m.finishJob(ctx, log, id, gateway.RunResultRequestTypeErr, gateway.ErrorMessage(err))
+		if err == job.ErrQueueFull {
+			return nil, serror.New(serror.ErrCodeBusy, "Too many deployments in progress, try later", err)
+		}
+		return nil, serror.New(serror.ErrCodeInternalError, "Can't start pipeline", err)
+	}
+	log.Infof("Pipeline %s accepted, position in queue: %d", id, position)
+
+	resBytes, err := json.Marshal(DeployResponse{QueuePosition: position})
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
+
+	return resBytes, nil
+}
@@ -1,6 +1,7 @@
 package methods
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/sirupsen/logrus"
@@ -15,6 +16,7 @@ type GetCommitListResponse struct {
 
 //GetCommitList source if it possible
 func (m *Methods) GetCommitList(
+	ctx context.Context,
 	log *logrus.Entry,
 	id string,
 	requestBytes []byte,
@@ -1,6 +1,7 @@
 package methods
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 
@@ -18,6 +19,7 @@ type GetInfoResponse struct {
 
 //GetInfo return info about steps and commit's hash of tag
 func (m *Methods) GetInfo(
+	ctx context.Context,
 	log *logrus.Entry,
 	ID string,
 	requestBytes []byte,
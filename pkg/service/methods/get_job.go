@@ -0,0 +1,44 @@
+package methods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+)
+
+//GetJobRequest request data
+type GetJobRequest struct {
+	ID string `json:"id"`
+}
+
+//GetJob return state and result of async operation by its request ID
+func (m *Methods) GetJob(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	var req GetJobRequest
+	if err := json.Unmarshal(requestBytes, &req); err != nil {
+		return nil, serror.NewUnmarshalReqErr(err)
+	}
+
+	j, err := m.jobRegistry.Get(log, req.ID)
+	if err == job.ErrNotFound {
+		return nil, serror.New(serror.ErrCodeNotFound, fmt.Sprintf("Job not found: %s", req.ID))
+	}
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeInternalError, "Can't get job", err)
+	}
+
+	resBytes, err := json.Marshal(j)
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
+
+	return resBytes, nil
+}
@@ -1,11 +1,10 @@
 package methods
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 
-	"github.com/makerdao/testchain-deployment/pkg/deploy"
 	"github.com/makerdao/testchain-deployment/pkg/git"
 	"github.com/makerdao/testchain-deployment/pkg/serror"
 	"github.com/sirupsen/logrus"
@@ -16,6 +15,7 @@ type GetManifestReq = git.Commit
 
 // Return remote refs for a GIT repo URL
 func (m *Methods) GetManifest(
+	ctx context.Context,
 	log *logrus.Entry,
 	id string,
 	requestBytes []byte,
@@ -25,13 +25,7 @@ func (m *Methods) GetManifest(
 		return nil, serror.NewUnmarshalReqErr(err)
 	}
 
-	repoPath, repoErr := git.GetRepoPath(req)
-	if repoErr != nil {
-		return nil, serror.New(serror.ErrCodeInternalError,
-			fmt.Sprintf("Couldn't get path to repo: %s %s", req.URL, req.Rev),
-			repoErr)
-	}
-	manifest, manifestErr := deploy.ReadManifestFile(ioutil.ReadFile, repoPath)
+	manifest, manifestErr := m.deployer.GetManifest(log, req)
 	if manifestErr != nil {
 		return nil, serror.New(serror.ErrCodeInternalError,
 			fmt.Sprintf("Couldn't get manifest file for: %s %s", req.URL, req.Rev),
@@ -1,6 +1,7 @@
 package methods
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -16,17 +17,20 @@ type GetRefsRequest struct {
 
 // Return remote refs for a GIT repo URL
 func (m *Methods) GetRefs(
+	ctx context.Context,
 	log *logrus.Entry,
 	id string,
 	requestBytes []byte,
 ) (response []byte, error *serror.Error) {
 	var req GetRefsRequest
-	var res []git.Commit
 	if err := json.Unmarshal(requestBytes, &req); err != nil {
 		return nil, serror.NewUnmarshalReqErr(err)
 	}
 
-	res, resErr := git.GetRefs(req.URL)
+	if err := git.ValidateURL(req.URL); err != nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Bad repo url", err)
+	}
+	res, resErr := m.gitBackend.ListRemoteRefs(req.URL)
 	if resErr != nil {
 		return nil, serror.New(serror.ErrCodeInternalError,
 			fmt.Sprintf("Couldn't get refs for: %s", req.URL),
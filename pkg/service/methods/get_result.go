@@ -1,6 +1,7 @@
 package methods
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/makerdao/testchain-deployment/pkg/serror"
@@ -9,6 +10,7 @@ import (
 
 //GetInfo return info about steps and commit's hash of tag
 func (m *Methods) GetResult(
+	ctx context.Context,
 	log *logrus.Entry,
 	ID string,
 	requestBytes []byte,
@@ -0,0 +1,68 @@
+package methods
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+)
+
+//PurgeCacheRequest request data
+type PurgeCacheRequest struct {
+	// URL of repo, whole cache is purged if empty
+	URL string `json:"url"`
+}
+
+//PurgeCacheResponse response data
+type PurgeCacheResponse struct {
+	Removed int `json:"removed"`
+}
+
+//GetCacheStatus return mirrors and worktrees of GIT cache
+func (m *Methods) GetCacheStatus(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	if m.gitCache == nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "GIT cache is disabled")
+	}
+
+	resBytes, err := json.Marshal(m.gitCache.Status())
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
+
+	return resBytes, nil
+}
+
+//PurgeCache remove not used mirrors and worktrees of repo or all of them
+func (m *Methods) PurgeCache(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	if m.gitCache == nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "GIT cache is disabled")
+	}
+	var req PurgeCacheRequest
+	if err := json.Unmarshal(requestBytes, &req); err != nil {
+		return nil, serror.NewUnmarshalReqErr(err)
+	}
+
+	removed, err := m.gitCache.Purge(log, req.URL)
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeInternalError, "Can't purge GIT cache", err)
+	}
+	log.Infof("Removed %d entries from GIT cache", removed)
+
+	resBytes, err := json.Marshal(PurgeCacheResponse{Removed: removed})
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
+
+	return resBytes, nil
+}
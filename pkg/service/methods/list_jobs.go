@@ -0,0 +1,43 @@
+package methods
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+)
+
+//ListJobsRequest request data, empty fields mean no filter
+type ListJobsRequest struct {
+	State  job.State `json:"state"`
+	Method string    `json:"method"`
+}
+
+//ListJobs return list of async operations sorted by creation time
+func (m *Methods) ListJobs(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	var req ListJobsRequest
+	if len(requestBytes) > 0 {
+		if err := json.Unmarshal(requestBytes, &req); err != nil {
+			return nil, serror.NewUnmarshalReqErr(err)
+		}
+	}
+
+	jobs, err := m.jobRegistry.List(log, req.State, req.Method)
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeInternalError, "Can't get list of jobs", err)
+	}
+
+	resBytes, err := json.Marshal(jobs)
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
+
+	return resBytes, nil
+}
@@ -1,11 +1,22 @@
 package methods
 
 import (
+	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/makerdao/testchain-deployment/pkg/deploy"
 	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/logstream"
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	"github.com/makerdao/testchain-deployment/pkg/webhook"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //StorageInterface for methods
@@ -22,14 +33,149 @@ type StorageInterface interface {
 type Methods struct {
 	storage         StorageInterface
 	deployComponent *deploy.Component
-	gatewayClient   *gateway.Client
+	deployer        *deploy.Deployer
+	gitCache        *git.Cache
+	gitBackend      git.Backend
+	dispatcher      *gateway.Dispatcher
+	jobRegistry     *job.Registry
+	pool            *job.Pool
+	logHub          *logstream.Hub
+	notifier        *webhook.Notifier
+	firstUpdateDone func() bool
 }
 
 //NewMethods init methods
 func NewMethods(
 	storage StorageInterface,
 	deployComponent *deploy.Component,
-	gatewayClient *gateway.Client,
+	deployer *deploy.Deployer,
+	gitCache *git.Cache,
+	gitBackend git.Backend,
+	dispatcher *gateway.Dispatcher,
+	jobRegistry *job.Registry,
+	pool *job.Pool,
+	logHub *logstream.Hub,
+	notifier *webhook.Notifier,
+	firstUpdateDone func() bool,
 ) *Methods {
-	return &Methods{storage: storage, deployComponent: deployComponent, gatewayClient: gatewayClient}
+	return &Methods{
+		storage:         storage,
+		deployComponent: deployComponent,
+		deployer:        deployer,
+		gitCache:        gitCache,
+		gitBackend:      gitBackend,
+		dispatcher:      dispatcher,
+		jobRegistry:     jobRegistry,
+		pool:            pool,
+		logHub:          logHub,
+		notifier:        notifier,
+		firstUpdateDone: firstUpdateDone,
+	}
+}
+
+//checkFirstUpdate return busy error until first update of deploy scripts is finished
+func (m *Methods) checkFirstUpdate() *serror.Error {
+	if m.firstUpdateDone != nil && !m.firstUpdateDone() {
+		return serror.New(serror.ErrCodeBusy, "First update of deploy scripts is in progress")
+	}
+	return nil
+}
+
+//validateCallback check callback from request with allowed hosts, nil callback is valid
+func (m *Methods) validateCallback(callback *gateway.Callback) *serror.Error {
+	if callback == nil {
+		return nil
+	}
+	if err := m.dispatcher.ValidateCallback(callback); err != nil {
+		return serror.New(serror.ErrCodeBadRequest, "Bad callback", err)
+	}
+	return nil
+}
+
+//queueJob register job and notify webhooks about it, returned context is cancelled with job
+//and has span of request
+func (m *Methods) queueJob(ctx context.Context, log *logrus.Entry, j job.Job) (context.Context, error) {
+	jobCtx, err := m.jobRegistry.Queue(log, j)
+	if err != nil {
+		return nil, err
+	}
+	m.notifier.Notify(log, webhook.Event{Type: webhook.EventQueued, JobID: j.ID, Method: j.Method})
+	return tracing.WithSpanOf(jobCtx, ctx), nil
+}
+
+//startJob mark job as running and start span of job, error is only logged because job is already accepted
+func (m *Methods) startJob(ctx context.Context, log *logrus.Entry, id string) (context.Context, trace.Span) {
+	if err := m.jobRegistry.Start(log, id); err != nil {
+		log.WithError(err).Error("Can't mark job as running")
+	}
+	m.notifyJob(log, id, webhook.Event{Type: webhook.EventStarted})
+	name := "job"
+	if j, err := m.jobRegistry.Get(log, id); err == nil {
+		name = "job " + j.Method
+	}
+	return tracing.Start(ctx, name, trace.WithAttributes(attribute.String("job.id", id)))
+}
+
+//dropJob return callback for task of pool which is removed from queue without start,
+//job is finished as cancelled by request or as failed on shutdown and result is sent to gateway
+func (m *Methods) dropJob(ctx context.Context, log *logrus.Entry, id string, callback *gateway.Callback) func(err error) {
+	return func(err error) {
+		resultReq := &gateway.RunResultRequest{
+			ID:       id,
+			Callback: callback,
+		}
+		if err == job.ErrTaskCancelled {
+			resultReq.SetCancelled()
+		} else {
+			resultReq.SetErr(err)
+		}
+		if err := m.dispatcher.RunResult(ctx, log, resultReq); err != nil {
+			log.WithError(err).Error("Can't send request with result of dropped job to gateway")
+		}
+		m.finishJob(ctx, log, id, string(resultReq.Type), resultReq.Result)
+	}
+}
+
+//onStage return callback which notify webhooks about finished stage of deployment
+func (m *Methods) onStage(log *logrus.Entry, id string) func(stage string) {
+	return func(stage string) {
+		m.notifyJob(log, id, webhook.Event{Type: webhook.EventStage, Stage: stage})
+	}
+}
+
+//notifyJob send event about job to webhooks, method is taken from registry
+func (m *Methods) notifyJob(log *logrus.Entry, id string, event webhook.Event) {
+	event.JobID = id
+	if j, err := m.jobRegistry.Get(log, id); err == nil {
+		event.Method = j.Method
+	}
+	m.notifier.Notify(log, event)
+}
+
+//finishJob save final state of job by type of result which is sent to gateway,
+//state is set to span in ctx
+func (m *Methods) finishJob(ctx context.Context, log *logrus.Entry, id string, resultType string, result json.RawMessage) {
+	state := job.StateFailed
+	switch resultType {
+	case gateway.RunResultRequestTypeOK:
+		state = job.StateSucceeded
+	case gateway.RunResultRequestTypeCancelled:
+		state = job.StateCancelled
+	}
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("job.state", string(state)))
+	if state == job.StateFailed {
+		span.SetStatus(codes.Error, "job failed")
+	}
+	if err := m.jobRegistry.Finish(log, id, state, result); err != nil {
+		log.WithError(err).Error("Can't save result of job")
+	}
+	event := webhook.Event{Type: webhook.EventFailed, Result: result}
+	switch state {
+	case job.StateSucceeded:
+		event.Type = webhook.EventSucceeded
+	case job.StateCancelled:
+		event.Type = webhook.EventCancelled
+	}
+	m.notifyJob(log, id, event)
 }
@@ -1,9 +1,12 @@
 package methods
 
 import (
+	"context"
 	"encoding/json"
+	"strconv"
 
 	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/job"
 	"github.com/makerdao/testchain-deployment/pkg/serror"
 	"github.com/sirupsen/logrus"
 )
@@ -12,10 +15,13 @@ import (
 type RunRequest struct {
 	StepID  int               `json:"stepId"`
 	EnvVars map[string]string `json:"envVars"`
+	// Callback overrides target of result
+	Callback *gateway.Callback `json:"callback,omitempty"`
 }
 
 //Run deployment async and return ok if it possible
 func (m *Methods) Run(
+	ctx context.Context,
 	log *logrus.Entry,
 	id string,
 	requestBytes []byte,
@@ -24,7 +30,13 @@ func (m *Methods) Run(
 	if err := json.Unmarshal(requestBytes, &req); err != nil {
 		return nil, serror.NewUnmarshalReqErr(err)
 	}
+	if err := m.validateCallback(req.Callback); err != nil {
+		return nil, err
+	}
 
+	if err := m.checkFirstUpdate(); err != nil {
+		return nil, err
+	}
 	if m.storage.GetUpdate() {
 		return nil, serror.New(serror.ErrCodeInternalError, "Deploy script updating in progress")
 	}
@@ -32,18 +44,50 @@ func (m *Methods) Run(
 		return nil, serror.New(serror.ErrCodeInternalError, "Deploy script running in progress")
 	}
 
+	ctx, err := m.queueJob(ctx, log, job.Job{
+		ID:       id,
+		Method:   "Run",
+		Scenario: strconv.Itoa(req.StepID),
+	})
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Can't register run", err)
+	}
+
 	go func(id string, req RunRequest) {
+		ctx, span := m.startJob(ctx, log, id)
+		defer span.End()
+		m.logHub.Open(id, m.deployer.OutputRedactor(req.EnvVars, nil))
+		defer m.logHub.Close(id)
 		resultReq := &gateway.RunResultRequest{
-			ID: id,
+			ID:       id,
+			Callback: req.Callback,
 		}
-		if resErr := m.deployComponent.RunScenario(log, req.StepID, req.EnvVars); resErr != nil {
+		defer func() {
+			m.finishJob(ctx, log, id, string(resultReq.Type), resultReq.Result)
+		}()
+		resErr := m.deployComponent.RunScenario(
+			ctx,
+			log,
+			req.StepID,
+			req.EnvVars,
+			m.logHub.Writer(id, "stdout"),
+			m.logHub.Writer(id, "stderr"),
+		)
+		// finished operation is not reported as cancelled if cancel came after it
+		if resErr != nil && ctx.Err() == context.Canceled {
+			if err := m.dispatcher.RunResult(ctx, log, resultReq.SetCancelled()); err != nil {
+				log.WithError(err).Error("Can't send request with cancel of run to gateway")
+			}
+			return
+		}
+		if resErr != nil {
 			resultReq.Type = gateway.RunResultRequestTypeErr
 			errResBytes, err := json.Marshal(resErr)
 			if err != nil {
 				log.WithError(err).Error("Can't marshal error for run result")
 			}
 			resultReq.Result = errResBytes
-			if err := m.gatewayClient.RunResult(log, resultReq); err != nil {
+			if err := m.dispatcher.RunResult(ctx, log, resultReq); err != nil {
 				log.WithError(err).Error("Can't send request with result of run to gateway with error")
 			}
 			return
@@ -56,7 +100,7 @@ func (m *Methods) Run(
 				log.WithError(err).Error("Can't marshal error for read result")
 			}
 			resultReq.Result = errResBytes
-			if err := m.gatewayClient.RunResult(log, resultReq); err != nil {
+			if err := m.dispatcher.RunResult(ctx, log, resultReq); err != nil {
 				log.WithError(err).Error("Can't send request with result of run to gateway with error")
 			}
 			return
@@ -67,7 +111,7 @@ func (m *Methods) Run(
 		}
 		resultReq.Type = gateway.RunResultRequestTypeOK
 		resultReq.Result = resBytes
-		if err := m.gatewayClient.RunResult(log, resultReq); err != nil {
+		if err := m.dispatcher.RunResult(ctx, log, resultReq); err != nil {
 			log.WithError(err).Error("Can't send request with result of run to gateway")
 		}
 	}(id, req)
@@ -1,17 +1,40 @@
 package methods
 
 import (
+	"context"
+	"encoding/json"
+
 	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/job"
 	"github.com/makerdao/testchain-deployment/pkg/serror"
 	"github.com/sirupsen/logrus"
 )
 
+//UpdateRequest request data, all fields are optional
+type UpdateRequest struct {
+	// Callback overrides target of result
+	Callback *gateway.Callback `json:"callback,omitempty"`
+}
+
 //Update source if it possible
 func (m *Methods) Update(
+	ctx context.Context,
 	log *logrus.Entry,
 	id string,
 	requestBytes []byte,
 ) (response []byte, error *serror.Error) {
+	var req UpdateRequest
+	if len(requestBytes) != 0 {
+		if err := json.Unmarshal(requestBytes, &req); err != nil {
+			return nil, serror.NewUnmarshalReqErr(err)
+		}
+	}
+	if err := m.validateCallback(req.Callback); err != nil {
+		return nil, err
+	}
+	if err := m.checkFirstUpdate(); err != nil {
+		return nil, err
+	}
 	if m.storage.GetUpdate() {
 		return nil, serror.New(serror.ErrCodeInternalError, "Deploy script updating in progress")
 	}
@@ -19,20 +42,31 @@ func (m *Methods) Update(
 		return nil, serror.New(serror.ErrCodeInternalError, "Deploy script running in progress")
 	}
 
+	ctx, err := m.queueJob(ctx, log, job.Job{ID: id, Method: "UpdateSource"})
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Can't register update", err)
+	}
+
 	log.Debugf("Update source process started with request Id %s", id)
 
 	go func(id string) {
+		ctx, span := m.startJob(ctx, log, id)
+		defer span.End()
 		resultReq := &gateway.UpdateResultRequest{
-			ID: id,
+			ID:       id,
+			Callback: req.Callback,
 		}
+		defer func() {
+			m.finishJob(ctx, log, id, string(resultReq.Type), resultReq.Result)
+		}()
 		if err := m.deployComponent.UpdateSource(log); err != nil {
-			if err := m.gatewayClient.UpdateResult(log, resultReq.SetErr(err)); err != nil {
+			if err := m.dispatcher.UpdateResult(ctx, log, resultReq.SetErr(err)); err != nil {
 				log.WithError(err).Error("Can't send request with result of run to gateway with error")
 			}
 			return
 		}
 		resultReq.Type = gateway.UpdateResultRequestTypeOK
-		if err := m.gatewayClient.UpdateResult(log, resultReq); err != nil {
+		if err := m.dispatcher.UpdateResult(ctx, log, resultReq); err != nil {
 			log.WithError(err).Error("Can't send request with result of run to gateway")
 		}
 	}(id)
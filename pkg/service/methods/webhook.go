@@ -0,0 +1,90 @@
+package methods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/makerdao/testchain-deployment/pkg/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+//RegisterWebhookRequest request data
+type RegisterWebhookRequest struct {
+	URL string `json:"url"`
+	// Secret is key of HMAC signature of payload, optional
+	Secret string `json:"secret"`
+	// Events is filter of events, all events are sent if it is empty
+	Events []string `json:"events"`
+}
+
+//RegisterWebhook add webhook for lifecycle events of jobs and return it with id
+func (m *Methods) RegisterWebhook(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	var req RegisterWebhookRequest
+	if err := json.Unmarshal(requestBytes, &req); err != nil {
+		return nil, serror.NewUnmarshalReqErr(err)
+	}
+
+	hook, err := m.notifier.Register(webhook.Hook{URL: req.URL, Secret: req.Secret, Events: req.Events})
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeBadRequest, "Can't register webhook", err)
+	}
+	log.Infof("Webhook %s is registered for %s", hook.ID, hook.URL)
+
+	resBytes, err := json.Marshal(hook)
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
+
+	return resBytes, nil
+}
+
+//UnregisterWebhookRequest request data
+type UnregisterWebhookRequest struct {
+	ID string `json:"id"`
+}
+
+//UnregisterWebhook remove webhook by id
+func (m *Methods) UnregisterWebhook(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	var req UnregisterWebhookRequest
+	if err := json.Unmarshal(requestBytes, &req); err != nil {
+		return nil, serror.NewUnmarshalReqErr(err)
+	}
+
+	err := m.notifier.Unregister(req.ID)
+	if err == webhook.ErrHookNotFound {
+		return nil, serror.New(serror.ErrCodeNotFound, fmt.Sprintf("Webhook not found: %s", req.ID))
+	}
+	if err != nil {
+		return nil, serror.New(serror.ErrCodeInternalError, "Can't unregister webhook", err)
+	}
+	log.Infof("Webhook %s is unregistered", req.ID)
+
+	return []byte(`{}`), nil
+}
+
+//ListWebhooks return registered webhooks without secrets
+func (m *Methods) ListWebhooks(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	resBytes, err := json.Marshal(m.notifier.List())
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
+
+	return resBytes, nil
+}
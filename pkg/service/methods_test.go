@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/makerdao/testchain-deployment/pkg/service/methods"
+	"github.com/makerdao/testchain-deployment/pkg/service/registry"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewRegistry(t *testing.T) {
+	reg, err := newRegistry(methods.NewMethods(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resBytes, serr := reg.ListMethods(context.Background(), logrus.NewEntry(logrus.New()), "1", nil)
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	var res []registry.MethodInfo
+	if err := json.Unmarshal(resBytes, &res); err != nil {
+		t.Fatal(err)
+	}
+	infos := make(map[string]registry.MethodInfo)
+	for _, info := range res {
+		infos[info.Name] = info
+	}
+	deploy := infos["Deploy"]
+	if deploy.Mode != registry.ModeAsync || deploy.Deprecated || deploy.RequestSchema == nil ||
+		deploy.RequestSchema.Properties["repoUrl"] == nil || deploy.RequestSchema.Properties["callback"] == nil {
+		t.Errorf("Unexpected Deploy method: %+v", deploy)
+	}
+	if hook := infos["RegisterWebhook"]; hook.ResponseSchema == nil || hook.ResponseSchema.Properties["secret"] != nil {
+		t.Errorf("Secret of webhook must not be in response: %+v", hook.ResponseSchema)
+	}
+	for _, name := range []string{"GetInfo", "GetResult", "GetCommitList", "Run", "UpdateSource", "Checkout"} {
+		if !infos[name].Deprecated {
+			t.Errorf("Method %s must be deprecated", name)
+		}
+	}
+	if _, ok := infos["ListMethods"]; !ok {
+		t.Error("ListMethods must be registered")
+	}
+}
+
+func TestDeployMethodsWaitFirstUpdate(t *testing.T) {
+	m := methods.NewMethods(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, func() bool { return false })
+	log := logrus.NewEntry(logrus.New())
+	for name, method := range map[string]func(context.Context, *logrus.Entry, string, []byte) ([]byte, *serror.Error){
+		"Run":          m.Run,
+		"UpdateSource": m.Update,
+		"Checkout":     m.Checkout,
+	} {
+		if _, serr := method(context.Background(), log, "1", []byte(`{}`)); serr == nil || serr.Code != serror.ErrCodeBusy {
+			t.Errorf("Method %s must be busy until first update is finished, got %+v", name, serr)
+		}
+	}
+}
@@ -8,14 +8,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
+	"github.com/makerdao/testchain-deployment/pkg/secret"
 	"github.com/makerdao/testchain-deployment/pkg/serror"
 	"github.com/makerdao/testchain-deployment/pkg/service/protocol"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
 	natsio "github.com/nats-io/go-nats"
 	"github.com/sirupsen/logrus"
 )
 
 //HandlerMethod - method will be subscribed to topics
-type HandlerMethod func(log *logrus.Entry, ID string, requestBytes []byte) (response []byte, error *serror.Error)
+type HandlerMethod = protocol.HandlerMethod
 
 type Server struct {
 	log          *logrus.Entry
@@ -27,9 +30,10 @@ type Server struct {
 
 func New(log *logrus.Entry, cfg *Config) *Server {
 	return &Server{
-		log:         log,
-		cfg:         cfg,
-		syncMethods: make(map[string]HandlerMethod),
+		log:          log,
+		cfg:          cfg,
+		syncMethods:  make(map[string]HandlerMethod),
+		asyncMethods: make(map[string]HandlerMethod),
 	}
 }
 
@@ -67,7 +71,7 @@ func (s *Server) Run(log *logrus.Entry) error {
 	for name, methodFunc := range s.syncMethods {
 		log.Infof("Register sync method %s", name)
 		topic := fmt.Sprintf("%s.%s.*", s.cfg.TopicPrefix, name)
-		_, err := s.conn.QueueSubscribe(topic, s.cfg.GroupName, s.getSyncMsgHandler(methodFunc))
+		_, err := s.conn.QueueSubscribe(topic, s.cfg.GroupName, s.getSyncMsgHandler(name, methodFunc))
 		if err != nil {
 			return err
 		}
@@ -82,7 +86,7 @@ func (s *Server) Run(log *logrus.Entry) error {
 	for name, methodFunc := range s.asyncMethods {
 		log.Infof("Register async method %s", name)
 		topic := fmt.Sprintf("%s.%s.*", s.cfg.TopicPrefix, name)
-		_, err := s.conn.QueueSubscribe(topic, s.cfg.GroupName, s.getAsyncMsgHandler(methodFunc))
+		_, err := s.conn.QueueSubscribe(topic, s.cfg.GroupName, s.getAsyncMsgHandler(name, methodFunc))
 		if err != nil {
 			return err
 		}
@@ -97,7 +101,7 @@ func (s *Server) Run(log *logrus.Entry) error {
 	return nil
 }
 
-func (s *Server) getAsyncMsgHandler(methodFunc HandlerMethod) natsio.MsgHandler {
+func (s *Server) getAsyncMsgHandler(name string, methodFunc HandlerMethod) natsio.MsgHandler {
 	return func(msg *natsio.Msg) {
 		log := s.log
 		defer func() {
@@ -114,9 +118,13 @@ func (s *Server) getAsyncMsgHandler(methodFunc HandlerMethod) natsio.MsgHandler
 		}()
 		topicParts := strings.Split(msg.Subject, ".")
 		reqID := topicParts[len(topicParts)-1]
-		log = log.WithField("topic", msg.Subject)
-		log.WithField("data", string(msg.Data)).Info("Request")
-		_, sErr := methodFunc(log, reqID, msg.Data)
+		ctx, span := tracing.StartRPC(extractTraceContext(msg.Data), metrics.ServerNATS, name, reqID)
+		log = tracing.Log(ctx, log.WithField("topic", msg.Subject))
+		log.WithField("data", string(secret.RedactRequest(msg.Data))).Info("Request")
+		start := time.Now()
+		_, sErr := methodFunc(ctx, log, reqID, msg.Data)
+		metrics.ObserveRPC(metrics.ServerNATS, name, sErr, start)
+		tracing.EndRPC(span, sErr)
 		if sErr != nil {
 			errBytes := prepareErrRespBytes(sErr)
 			log.WithField("data", string(errBytes)).Error("Response error")
@@ -129,7 +137,7 @@ func (s *Server) getAsyncMsgHandler(methodFunc HandlerMethod) natsio.MsgHandler
 	}
 }
 
-func (s *Server) getSyncMsgHandler(methodFunc HandlerMethod) natsio.MsgHandler {
+func (s *Server) getSyncMsgHandler(name string, methodFunc HandlerMethod) natsio.MsgHandler {
 	return func(msg *natsio.Msg) {
 		log := s.log
 		defer func() {
@@ -146,9 +154,13 @@ func (s *Server) getSyncMsgHandler(methodFunc HandlerMethod) natsio.MsgHandler {
 		}()
 		topicParts := strings.Split(msg.Subject, ".")
 		reqID := topicParts[len(topicParts)-1]
-		log = log.WithField("topic", msg.Subject)
-		log.WithField("data", string(msg.Data)).Info("Request")
-		res, sErr := methodFunc(log, reqID, msg.Data)
+		ctx, span := tracing.StartRPC(extractTraceContext(msg.Data), metrics.ServerNATS, name, reqID)
+		log = tracing.Log(ctx, log.WithField("topic", msg.Subject))
+		log.WithField("data", string(secret.RedactRequest(msg.Data))).Info("Request")
+		start := time.Now()
+		res, sErr := methodFunc(ctx, log, reqID, msg.Data)
+		metrics.ObserveRPC(metrics.ServerNATS, name, sErr, start)
+		tracing.EndRPC(span, sErr)
 		if sErr != nil {
 			errBytes := prepareErrRespBytes(sErr)
 			log.WithField("data", string(errBytes)).Error("Response error")
@@ -201,3 +213,15 @@ func prepareErrRespBytes(serr *serror.Error) []byte {
 	}
 	return resBytes
 }
+
+//extractTraceContext return context with remote span from `traceContext` field of request,
+//NATS messages have no headers, so W3C trace context is passed in data
+func extractTraceContext(data []byte) context.Context {
+	var req struct {
+		TraceContext map[string]string `json:"traceContext"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return context.Background()
+	}
+	return tracing.Extract(context.Background(), req.TraceContext)
+}
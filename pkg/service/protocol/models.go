@@ -1,16 +1,23 @@
 package protocol
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
 )
 
+//HandlerMethod is rpc method, it is called by all transports with id and data of request
+type HandlerMethod func(ctx context.Context, log *logrus.Entry, ID string, requestBytes []byte) (response []byte, error *serror.Error)
+
 //Request wrapper for rpc
 type Request struct {
 	ID     string          `json:"id"`
 	Method string          `json:"method"`
 	Data   json.RawMessage `json:"data"`
+	// TraceContext is W3C trace context (traceparent, tracestate), it is used when headers have no trace context
+	TraceContext map[string]string `json:"traceContext,omitempty"`
 }
 
 //ResponseType is type for rpc response
@@ -0,0 +1,135 @@
+// Package registry keeps rpc methods of service with their metadata,
+// all transports (HTTP, JSON-RPC, NATS) register methods from it
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/makerdao/testchain-deployment/pkg/service/protocol"
+	"github.com/sirupsen/logrus"
+)
+
+// Mode is how result of method is delivered
+type Mode string
+
+const (
+	// ModeSync method returns result in response
+	ModeSync Mode = "sync"
+	// ModeAsync method returns only acceptance, result is sent to gateway when operation is finished
+	ModeAsync Mode = "async"
+)
+
+// Method is rpc method with metadata
+type Method struct {
+	Name    string
+	Handler protocol.HandlerMethod
+	Mode    Mode
+	// Description is short help of method
+	Description string
+	// Request and Response are zero values of types of request and response data, they are used for schemas,
+	// nil means that method has no data
+	Request  interface{}
+	Response interface{}
+	// Deprecated method is kept for compatibility and will be removed
+	Deprecated bool
+}
+
+// Validate method before registration
+func (m Method) Validate() error {
+	if m.Name == "" {
+		return errors.New("name of method is required")
+	}
+	if m.Handler == nil {
+		return fmt.Errorf("handler of method %s is required", m.Name)
+	}
+	if m.Mode != ModeSync && m.Mode != ModeAsync {
+		return fmt.Errorf("mode of method %s can be only %s or %s", m.Name, ModeSync, ModeAsync)
+	}
+	return nil
+}
+
+// MethodInfo is description of method returned by ListMethods
+type MethodInfo struct {
+	Name           string  `json:"name"`
+	Mode           Mode    `json:"mode"`
+	Description    string  `json:"description,omitempty"`
+	Deprecated     bool    `json:"deprecated"`
+	RequestSchema  *Schema `json:"requestSchema,omitempty"`
+	ResponseSchema *Schema `json:"responseSchema,omitempty"`
+}
+
+// Registry keeps methods in order of registration
+type Registry struct {
+	methods []Method
+	index   map[string]int
+}
+
+// New init empty registry
+func New() *Registry {
+	return &Registry{
+		index: make(map[string]int),
+	}
+}
+
+// Add register method, name of method has to be unique
+func (r *Registry) Add(m Method) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if _, ok := r.index[m.Name]; ok {
+		return fmt.Errorf("method with name %s already exists", m.Name)
+	}
+	r.index[m.Name] = len(r.methods)
+	r.methods = append(r.methods, m)
+	return nil
+}
+
+// Get return method by name
+func (r *Registry) Get(name string) (Method, bool) {
+	i, ok := r.index[name]
+	if !ok {
+		return Method{}, false
+	}
+	return r.methods[i], true
+}
+
+// List return methods in order of registration
+func (r *Registry) List() []Method {
+	res := make([]Method, len(r.methods))
+	copy(res, r.methods)
+	return res
+}
+
+// Info return descriptions of methods with schemas in order of registration
+func (r *Registry) Info() []MethodInfo {
+	res := make([]MethodInfo, 0, len(r.methods))
+	for _, m := range r.methods {
+		res = append(res, MethodInfo{
+			Name:           m.Name,
+			Mode:           m.Mode,
+			Description:    m.Description,
+			Deprecated:     m.Deprecated,
+			RequestSchema:  SchemaOf(m.Request),
+			ResponseSchema: SchemaOf(m.Response),
+		})
+	}
+	return res
+}
+
+// ListMethods is rpc method which return descriptions of registered methods
+func (r *Registry) ListMethods(
+	ctx context.Context,
+	log *logrus.Entry,
+	id string,
+	requestBytes []byte,
+) (response []byte, error *serror.Error) {
+	resBytes, err := json.Marshal(r.Info())
+	if err != nil {
+		return nil, serror.NewMarshalRespErr(err)
+	}
+	return resBytes, nil
+}
@@ -0,0 +1,107 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+)
+
+type node struct {
+	Name     string  `json:"name"`
+	Children []*node `json:"children,omitempty"`
+}
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type sample struct {
+	base
+	Count    int               `json:"count"`
+	Ratio    float64           `json:"ratio"`
+	Enabled  *bool             `json:"enabled,omitempty"`
+	EnvVars  map[string]string `json:"envVars"`
+	Tags     []string          `json:"tags"`
+	Data     json.RawMessage   `json:"data"`
+	At       time.Time         `json:"at"`
+	Tree     node              `json:"tree"`
+	Secret   string            `json:"-"`
+	NoTag    string
+	internal string
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema := SchemaOf(sample{})
+	if schema.SchemaURI != JSONSchemaDraft || schema.Type != "object" {
+		t.Fatalf("Unexpected schema: %+v", schema)
+	}
+	props := schema.Properties
+	for name, typ := range map[string]string{
+		"id": "string", "count": "integer", "ratio": "number", "enabled": "boolean",
+		"envVars": "object", "tags": "array", "data": "", "at": "string", "tree": "object", "NoTag": "string",
+	} {
+		if props[name] == nil || props[name].Type != typ {
+			t.Errorf("Property %s of type '%s' is expected, got %+v", name, typ, props[name])
+		}
+	}
+	for _, name := range []string{"Secret", "internal", "base"} {
+		if _, ok := props[name]; ok {
+			t.Errorf("Property %s is not expected", name)
+		}
+	}
+	if props["envVars"].AdditionalProperties.Type != "string" || props["tags"].Items.Type != "string" ||
+		props["at"].Format != "date-time" {
+		t.Errorf("Unexpected schemas of map, slice or time: %+v", props)
+	}
+	// recursive type is described as any value
+	children := props["tree"].Properties["children"]
+	if children.Type != "array" || children.Items.Type != "" {
+		t.Errorf("Unexpected schema of recursive type: %+v", children)
+	}
+	if SchemaOf(nil) != nil {
+		t.Error("No schema is expected for method without data")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	handler := func(ctx context.Context, log *logrus.Entry, id string, data []byte) ([]byte, *serror.Error) {
+		return []byte(`{}`), nil
+	}
+	reg := New()
+	if err := reg.Add(Method{Name: "Deploy", Handler: handler, Mode: ModeAsync, Request: sample{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Add(Method{Name: "GetInfo", Handler: handler, Mode: ModeSync, Deprecated: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []Method{
+		{Name: "Deploy", Handler: handler, Mode: ModeSync},
+		{Name: "NoHandler", Mode: ModeSync},
+		{Name: "NoMode", Handler: handler},
+		{Handler: handler, Mode: ModeSync},
+	} {
+		if err := reg.Add(m); err == nil {
+			t.Errorf("Method %s must not be registered", m.Name)
+		}
+	}
+	if m, ok := reg.Get("Deploy"); !ok || m.Mode != ModeAsync {
+		t.Errorf("Deploy method is expected, got %+v", m)
+	}
+
+	resBytes, serr := reg.ListMethods(context.Background(), logrus.NewEntry(logrus.New()), "1", nil)
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	var res []MethodInfo
+	if err := json.Unmarshal(resBytes, &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Name != "Deploy" || res[0].RequestSchema == nil || res[0].ResponseSchema != nil ||
+		res[1].Name != "GetInfo" || !res[1].Deprecated {
+		t.Errorf("Unexpected methods: %s", resBytes)
+	}
+}
@@ -10,12 +10,20 @@ import (
 	"github.com/makerdao/testchain-deployment/pkg/config"
 	"github.com/makerdao/testchain-deployment/pkg/deploy"
 	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/makerdao/testchain-deployment/pkg/git"
 	"github.com/makerdao/testchain-deployment/pkg/github"
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/makerdao/testchain-deployment/pkg/logstream"
+	"github.com/makerdao/testchain-deployment/pkg/metrics"
+	sgrpc "github.com/makerdao/testchain-deployment/pkg/service/grpc"
 	shttp "github.com/makerdao/testchain-deployment/pkg/service/http"
 	"github.com/makerdao/testchain-deployment/pkg/service/methods"
 	"github.com/makerdao/testchain-deployment/pkg/service/nats"
+	"github.com/makerdao/testchain-deployment/pkg/service/registry"
 	"github.com/makerdao/testchain-deployment/pkg/storage"
 	"github.com/makerdao/testchain-deployment/pkg/system"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	"github.com/makerdao/testchain-deployment/pkg/webhook"
 	gonats "github.com/nats-io/go-nats"
 	"github.com/sirupsen/logrus"
 )
@@ -32,139 +40,208 @@ func Run(log *logrus.Entry, cfg *config.Config) error {
 		return err
 	}
 
+	tracer, err := tracing.New(cfg.Tracing)
+	if err != nil {
+		return err
+	}
 	gatewayClient := gateway.NewClient(cfg.Gateway, natsConn, cfg.NATS)
-	gatewayRegistrator := gateway.NewRegistrator(cfg.Gateway, gatewayClient, cfg.Host, cfg.Port)
-	githubClient := github.NewClient(cfg.Github, cfg.Deploy.DeploymentDirPath)
-	inMemStorage := storage.NewInMemory()
-	deployComponent := deploy.New(cfg.Deploy, githubClient, inMemStorage)
-	methodsComponent := methods.NewMethods(inMemStorage, deployComponent, gatewayClient)
+	dispatcher, err := gateway.NewDispatcher(log, cfg.Gateway, gatewayClient)
+	if err != nil {
+		return err
+	}
+	gitBackend, err := git.NewBackend(cfg.Git.Backend, &cfg.GitAuth)
+	if err != nil {
+		return err
+	}
+	githubClient := github.NewClient(cfg.Github, cfg.Deploy.DeploymentDirPath, gitBackend, &cfg.GitAuth)
+	store, err := newStorage(log, cfg.Storage)
+	if err != nil {
+		return err
+	}
+	resolver, gitCache, err := git.NewResolver(log, cfg.Git, &cfg.GitAuth)
+	if err != nil {
+		return err
+	}
+	executors := deploy.NewExecutors(cfg.Deploy)
+	deployer, err := deploy.NewDeployer(cfg.Deploy, resolver, cfg.Secrets, executors)
+	if err != nil {
+		return err
+	}
+	deployComponent := deploy.New(cfg.Deploy, githubClient, store, executors)
+	jobRegistry := job.NewRegistry(cfg.Jobs, store)
+	// jobs loaded from file are pruned by limits of config
+	if err := jobRegistry.Prune(log); err != nil {
+		return err
+	}
+	pool := job.NewPool(cfg.Pool)
+	logHub := logstream.NewHub(log, cfg.Logs, natsConn, cfg.NATS.TopicPrefix)
+	notifier := webhook.NewNotifier(cfg.Webhooks, cfg.Gateway.CallbackHosts)
+	// first update runs in background, so health endpoints are available while it runs,
+	// instance is registered on gateway and accepts deploy methods only after it
+	firstUpdate := &firstUpdater{component: deployComponent}
+	gatewayRegistrator := gateway.NewRegistrator(cfg.Gateway, gatewayClient, cfg.Host, cfg.Port, firstUpdate.Done)
+	methodsComponent := methods.NewMethods(
+		store,
+		deployComponent,
+		deployer,
+		gitCache,
+		gitBackend,
+		dispatcher,
+		jobRegistry,
+		pool,
+		logHub,
+		notifier,
+		firstUpdate.Done,
+	)
+	reg, err := newRegistry(methodsComponent)
+	if err != nil {
+		return err
+	}
+
+	healthComponent := &health{
+		log:         log,
+		storage:     store,
+		firstUpdate: firstUpdate,
+		natsConn:    natsConn,
+		registrator: gatewayRegistrator,
+		dispatcher:  dispatcher,
+		jobRegistry: jobRegistry,
+		pool:        pool,
+	}
 
-	if err := deployComponent.FirstUpdate(log); err != nil {
+	// http server is used for endpoints except rpc, in NATS mode it is optional
+	mux := http.NewServeMux()
+	mux.Handle(shttp.LogsPath, shttp.NewLogsHandler(log, logHub))
+	mux.Handle(shttp.HealthPath, &shttp.HealthHandler{})
+	mux.Handle(shttp.ReadyPath, shttp.NewReadyHandler(healthComponent.checks()...))
+	mux.Handle(shttp.StatusPath, shttp.NewStatusHandler(log, healthComponent.status))
+	if err := metrics.RegisterQueue(pool); err != nil {
 		return err
 	}
+	mux.Handle(metrics.Path, metrics.Handler())
 
 	log.Infof("Used %s server", cfg.Server)
-	var serv system.RunnerShutdowner
+	runners := []system.RunnerShutdowner{firstUpdate, gatewayRegistrator, dispatcher, pool, notifier}
+	if gitCache != nil {
+		runners = append(runners, gitCache)
+	}
 	switch cfg.Server {
 	case "HTTP":
-		var err error
-		serv, err = httpServConfigure(log, cfg.Port, methodsComponent, inMemStorage)
+		handler, err := httpHandlerConfigure(log, reg)
 		if err != nil {
 			return err
 		}
+		// rpc endpoints are mounted only in HTTP mode, other modes serve methods with own server
+		mux.Handle("/rpc", handler)
+		mux.Handle(shttp.JSONRPCPath, shttp.NewJSONRPCHandler(handler, asyncMethods(reg)...))
 	case "NATS":
-		var err error
-		serv, err = natsServConfigure(log, cfg.NATS, methodsComponent)
+		serv, err := natsServConfigure(log, cfg.NATS, reg)
+		if err != nil {
+			return err
+		}
+		runners = append(runners, serv)
+	case "GRPC":
+		serv, err := grpcServConfigure(log, cfg.GRPC, reg, logHub, jobRegistry)
 		if err != nil {
 			return err
 		}
+		runners = append(runners, serv)
 	default:
-		return errors.New("server can be only HTTP or NATS")
+		return errors.New("server can be only HTTP, NATS or GRPC")
+	}
+	// NATS mode doesn't open http port unless it is asked
+	if cfg.Server != "NATS" || cfg.HTTPWithNATS {
+		runners = append(runners, &HTTPServer{
+			Storage: store,
+			Server: http.Server{
+				Addr:    fmt.Sprintf(":%d", cfg.Port),
+				Handler: mux,
+			},
+		})
 	}
 
 	// operator for async group work and correct shutdown
-	operator := system.NewOperator(
-		log,
-		serv,
-		gatewayRegistrator,
-	)
+	operator := system.NewOperator(log, runners...)
 	signals := system.NewSignals(operator.GetErrCh())
 	operator.Run()
 
-	return signals.Wait(log, operator)
+	err = signals.Wait(log, operator)
+	// spans are exported when all components are stopped
+	if err := tracer.Shutdown(context.Background(), log); err != nil {
+		log.WithError(err).Error("Can't export spans")
+	}
+	return err
 }
 
-func natsServConfigure(log *logrus.Entry, cfg nats.Config, methodsComponent *methods.Methods) (*nats.Server, error) {
-	n := nats.New(log, &cfg)
+// storageBackend is storage used by all components of service
+type storageBackend interface {
+	deploy.StorageInterface
+	job.StorageInterface
+	GetStepList(log *logrus.Entry) ([]deploy.StepModel, error)
+	GetUpdatedAt() (*time.Time, error)
+}
 
-	// TODO: remove
-	if err := n.AddSyncMethod("GetInfo", methodsComponent.GetInfo); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := n.AddSyncMethod("GetResult", methodsComponent.GetResult); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := n.AddSyncMethod("GetCommitList", methodsComponent.GetCommitList); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := n.AddAsyncMethod("Run", methodsComponent.Run); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := n.AddAsyncMethod("UpdateSource", methodsComponent.Update); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := n.AddAsyncMethod("Checkout", methodsComponent.Checkout); err != nil {
-		return nil, err
-	}
-	if err := n.AddSyncMethod("GetRefs", methodsComponent.GetRefs); err != nil {
-		return nil, err
-	}
-	if err := n.AddSyncMethod("GetManifest", methodsComponent.GetManifest); err != nil {
-		return nil, err
+func newStorage(log *logrus.Entry, cfg storage.Config) (storageBackend, error) {
+	log.Infof("Used %s storage", cfg.Type)
+	switch cfg.Type {
+	case "memory":
+		return storage.NewInMemory(), nil
+	case "file":
+		return storage.NewFile(log.WithField("component", "storage"), cfg.Path)
+	default:
+		return nil, errors.New("storage can be only memory or file")
 	}
-	if err := n.AddAsyncMethod("Deploy", methodsComponent.Deploy); err != nil {
-		return nil, err
+}
+
+func natsServConfigure(log *logrus.Entry, cfg nats.Config, reg *registry.Registry) (*nats.Server, error) {
+	n := nats.New(log, &cfg)
+	for _, method := range reg.List() {
+		add := n.AddSyncMethod
+		if method.Mode == registry.ModeAsync {
+			add = n.AddAsyncMethod
+		}
+		if err := add(method.Name, method.Handler); err != nil {
+			return nil, err
+		}
 	}
 	return n, nil
 }
 
-func httpServConfigure(
+func grpcServConfigure(
 	log *logrus.Entry,
-	port int,
-	methodsComponent *methods.Methods,
-	storage storage.Storage,
-) (*HTTPServer, error) {
-	// register methods in handler
-	handler := shttp.NewHandler(log)
-	// TODO: remove
-	if err := handler.AddMethod("GetInfo", methodsComponent.GetInfo); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := handler.AddMethod("Run", methodsComponent.Run); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := handler.AddMethod("UpdateSource", methodsComponent.Update); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := handler.AddMethod("GetResult", methodsComponent.GetResult); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := handler.AddMethod("GetCommitList", methodsComponent.GetCommitList); err != nil {
-		return nil, err
-	}
-	// TODO: remove
-	if err := handler.AddMethod("Checkout", methodsComponent.Checkout); err != nil {
-		return nil, err
-	}
-	if err := handler.AddMethod("GetRefs", methodsComponent.GetRefs); err != nil {
-		return nil, err
+	cfg sgrpc.Config,
+	reg *registry.Registry,
+	logs sgrpc.LogSource,
+	jobs sgrpc.JobSource,
+) (*sgrpc.Server, error) {
+	serv := sgrpc.New(log, cfg, logs, jobs)
+	for _, method := range reg.List() {
+		if err := serv.AddMethod(method.Name, method.Handler); err != nil {
+			return nil, err
+		}
 	}
-	if err := handler.AddMethod("GetManifest", methodsComponent.GetManifest); err != nil {
-		return nil, err
+	return serv, nil
+}
+
+func httpHandlerConfigure(log *logrus.Entry, reg *registry.Registry) (*shttp.Handler, error) {
+	handler := shttp.NewHandler(log)
+	for _, method := range reg.List() {
+		if err := handler.AddMethod(method.Name, method.Handler); err != nil {
+			return nil, err
+		}
 	}
-	if err := handler.AddMethod("Deploy", methodsComponent.Deploy); err != nil {
-		return nil, err
+	return handler, nil
+}
+
+// asyncMethods return names of methods which start job
+func asyncMethods(reg *registry.Registry) []string {
+	res := make([]string, 0)
+	for _, method := range reg.List() {
+		if method.Mode == registry.ModeAsync {
+			res = append(res, method.Name)
+		}
 	}
-	// init and run http server
-	mux := http.NewServeMux()
-	mux.Handle("/rpc", handler)
-
-	return &HTTPServer{
-		Storage: storage,
-		Server: http.Server{
-			Addr:    fmt.Sprintf(":%d", port),
-			Handler: mux,
-		},
-	}, nil
+	return res
 }
 
 type HTTPServer struct {
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Config of storage
+type Config struct {
+	Type string
+	Path string
+}
+
+// Decode for envconfig
+func (c *Config) Decode(data string) error {
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.Split(p, "=")
+		if len(paramArr) != 2 {
+			return fmt.Errorf("bad param in part of Storage env '%s'", p)
+		}
+		switch paramArr[0] {
+		case "type":
+			c.Type = paramArr[1]
+		case "path":
+			c.Path = paramArr[1]
+		default:
+			return fmt.Errorf("unknown param '%s' for part of Storage env", paramArr[0])
+		}
+	}
+
+	return nil
+}
+
+// Validate cfg after load
+func (c *Config) Validate() error {
+	if c.Type != "memory" && c.Type != "file" {
+		return errors.New("storage type can be only 'memory' or 'file'")
+	}
+	if c.Type == "file" && c.Path == "" {
+		return errors.New("path is required for file storage")
+	}
+	return nil
+}
+
+// GetDefaultConfig return default config for storage pkg
+func GetDefaultConfig() Config {
+	return Config{
+		Type: "memory",
+		Path: "/var/lib/testchain-deployment/storage.json",
+	}
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/deploy"
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/sirupsen/logrus"
+)
+
+//File is in memory storage which saves snapshot of data to json file after every change,
+//so manifest, tag hash, update time and jobs survive restart
+type File struct {
+	*InMemory
+	path   string
+	fileMu sync.Mutex
+}
+
+type fileSnapshot struct {
+	HasHash     bool            `json:"hasHash"`
+	HasManifest bool            `json:"hasManifest"`
+	Hash        string          `json:"hash"`
+	Manifest    deploy.Manifest `json:"manifest"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	Jobs        []job.Job       `json:"jobs"`
+}
+
+//NewFile init storage and load data from file if it exists,
+//not finished jobs from previous run are marked as failed
+func NewFile(log *logrus.Entry, path string) (*File, error) {
+	s := &File{
+		InMemory: NewInMemory(),
+		path:     path,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		log.Infof("Storage file %s not exists, start with empty storage", path)
+		return s, s.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot fileSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+
+	s.hasHash = snapshot.HasHash
+	s.hasManifest = snapshot.HasManifest
+	s.hash = snapshot.Hash
+	s.manifest = snapshot.Manifest
+	s.updatedAt = snapshot.UpdatedAt
+	now := time.Now()
+	for _, j := range snapshot.Jobs {
+		if !j.State.IsFinished() {
+			log.Warnf("Job %s was interrupted by restart, mark it as failed", j.ID)
+			j.State = job.StateFailed
+			j.FinishedAt = &now
+			j.Error = json.RawMessage(`{"message":"interrupted by service restart"}`)
+		}
+		s.jobs[j.ID] = j
+	}
+	log.Infof("Storage loaded from %s, jobs: %d", path, len(s.jobs))
+
+	return s, s.save()
+}
+
+func (s *File) UpsertManifest(log *logrus.Entry, manifest deploy.Manifest) error {
+	if err := s.InMemory.UpsertManifest(log, manifest); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *File) SetTagHash(log *logrus.Entry, hash string) error {
+	if err := s.InMemory.SetTagHash(log, hash); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *File) SetUpdatedAtNow() error {
+	if err := s.InMemory.SetUpdatedAtNow(); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *File) UpsertJob(log *logrus.Entry, j job.Job) error {
+	if err := s.InMemory.UpsertJob(log, j); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *File) DeleteJobs(log *logrus.Entry, ids []string) error {
+	if err := s.InMemory.DeleteJobs(log, ids); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+//save write snapshot to temp file and rename it, so file is never half written,
+//file and dir are synced, so snapshot survives crash of host
+func (s *File) save() error {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+
+	s.mu.Lock()
+	snapshot := fileSnapshot{
+		HasHash:     s.hasHash,
+		HasManifest: s.hasManifest,
+		Hash:        s.hash,
+		Manifest:    s.manifest,
+		UpdatedAt:   s.updatedAt,
+		Jobs:        make([]job.Job, 0, len(s.jobs)),
+	}
+	for _, j := range s.jobs {
+		snapshot.Jobs = append(snapshot.Jobs, j)
+	}
+	s.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := writeFileSync(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(s.path))
+}
+
+//writeFileSync write data to file and flush it to disk
+func writeFileSync(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//syncDir flush entries of dir to disk, so rename is durable
+func syncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
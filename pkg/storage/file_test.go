@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makerdao/testchain-deployment/pkg/deploy"
+	"github.com/makerdao/testchain-deployment/pkg/job"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFileRestore(t *testing.T) {
+	log := logrus.WithField("component", "test")
+	dir, err := ioutil.TempDir("", "storage-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state", "storage.json")
+
+	// Setup
+	s, err := NewFile(log, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertManifest(log, deploy.Manifest{Name: "TestManifest"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetTagHash(log, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertJob(log, job.Job{ID: "done", State: job.StateSucceeded}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertJob(log, job.Job{ID: "running", State: job.StateRunning}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run
+	restored, err := NewFile(log, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assertion
+	if !restored.HasData() {
+		t.Fatal("Restored storage has no data")
+	}
+	manifest, err := restored.GetManifest(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Name != "TestManifest" {
+		t.Errorf("Restored manifest name doesn't match: %s", manifest.Name)
+	}
+	if hash, _ := restored.GetTagHash(log); hash != "hash" {
+		t.Errorf("Restored tag hash doesn't match: %s", hash)
+	}
+	done, err := restored.GetJob(log, "done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.State != job.StateSucceeded {
+		t.Errorf("Finished job state changed after restore: %s", done.State)
+	}
+	interrupted, err := restored.GetJob(log, "running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interrupted.State != job.StateFailed || interrupted.FinishedAt == nil {
+		t.Errorf("Interrupted job is not marked as failed: %+v", interrupted)
+	}
+}
+
+func TestFileDeleteJobs(t *testing.T) {
+	log := logrus.WithField("component", "test")
+	dir, err := ioutil.TempDir("", "storage-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "storage.json")
+
+	s, err := NewFile(log, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := s.UpsertJob(log, job.Job{ID: id, State: job.StateSucceeded}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.DeleteJobs(log, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewFile(log, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restored.GetJob(log, "a"); err != job.ErrNotFound {
+		t.Errorf("Expected deleted job to be removed from file, got %v", err)
+	}
+	if _, err := restored.GetJob(log, "b"); err != nil {
+		t.Errorf("Expected job b to be restored, got %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected temp file to be renamed, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/makerdao/testchain-deployment/pkg/deploy"
+	"github.com/makerdao/testchain-deployment/pkg/job"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,11 +21,14 @@ type InMemory struct {
 	manifest    deploy.Manifest
 	mu          sync.Mutex
 	updatedAt   time.Time
+	jobs        map[string]job.Job
 }
 
 //NewInMemory init storaga
 func NewInMemory() *InMemory {
-	return &InMemory{}
+	return &InMemory{
+		jobs: make(map[string]job.Job),
+	}
 }
 
 func (s *InMemory) UpsertManifest(log *logrus.Entry, manifest deploy.Manifest) error {
@@ -125,3 +129,39 @@ func (s *InMemory) SetUpdatedAtNow() error {
 func (s *InMemory) GetUpdatedAt() (*time.Time, error) {
 	return &s.updatedAt, nil
 }
+
+func (s *InMemory) UpsertJob(log *logrus.Entry, j job.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[j.ID] = j
+	return nil
+}
+
+func (s *InMemory) GetJob(log *logrus.Entry, id string) (*job.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, job.ErrNotFound
+	}
+	return &j, nil
+}
+
+func (s *InMemory) ListJobs(log *logrus.Entry) ([]job.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]job.Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		res = append(res, j)
+	}
+	return res, nil
+}
+
+func (s *InMemory) DeleteJobs(log *logrus.Entry, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.jobs, id)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Exporters of spans
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Config of tracing
+type Config struct {
+	// Exporter is none, otlp or stdout, tracing is disabled with none
+	Exporter string
+	// Endpoint is host:port of OTLP collector with http receiver
+	Endpoint string
+	// Insecure disables TLS for collector
+	Insecure    bool
+	ServiceName string
+	// SampleRatio is part of new traces which are sampled, incoming sampled traces are always sampled
+	SampleRatio float64
+}
+
+// Decode for envconfig
+func (c *Config) Decode(data string) error {
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.Split(p, "=")
+		if len(paramArr) != 2 {
+			return fmt.Errorf("bad param in part of Tracing env '%s'", p)
+		}
+		switch paramArr[0] {
+		case "exporter":
+			c.Exporter = paramArr[1]
+		case "endpoint":
+			c.Endpoint = paramArr[1]
+		case "insecure":
+			v, err := strconv.ParseBool(paramArr[1])
+			if err != nil {
+				return err
+			}
+			c.Insecure = v
+		case "serviceName":
+			c.ServiceName = paramArr[1]
+		case "sampleRatio":
+			v, err := strconv.ParseFloat(paramArr[1], 64)
+			if err != nil {
+				return err
+			}
+			c.SampleRatio = v
+		default:
+			return fmt.Errorf("unknown param '%s' for part of Tracing env", paramArr[0])
+		}
+	}
+
+	return nil
+}
+
+// Validate cfg after load
+func (c *Config) Validate() error {
+	switch c.Exporter {
+	case ExporterNone, ExporterStdout:
+	case ExporterOTLP:
+		if c.Endpoint == "" {
+			return errors.New("endpoint of tracing is required for otlp exporter")
+		}
+	default:
+		return fmt.Errorf("exporter of tracing can be only %s, %s or %s", ExporterNone, ExporterOTLP, ExporterStdout)
+	}
+	if c.ServiceName == "" {
+		return errors.New("serviceName of tracing is required")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return errors.New("sampleRatio of tracing should be from 0 to 1")
+	}
+	return nil
+}
+
+// GetDefaultConfig return default config for tracing pkg
+func GetDefaultConfig() Config {
+	return Config{
+		Exporter:    ExporterNone,
+		Endpoint:    "localhost:4318",
+		ServiceName: "testchain-deployment",
+		SampleRatio: 1,
+	}
+}
@@ -0,0 +1,177 @@
+// Package tracing keeps spans of request handling, deployment stages and callbacks to gateway,
+// W3C trace context is accepted from requests and is propagated to gateway
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/makerdao/testchain-deployment"
+
+// Provider export spans, spans are flushed on shutdown
+type Provider struct {
+	// tp is nil when tracing is disabled, trace context is propagated anyway
+	tp *sdktrace.TracerProvider
+}
+
+// trace context is propagated even if tracing is disabled, so trace of caller reaches gateway
+func init() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// New init provider by config and set it as global
+func New(cfg Config) (*Provider, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterNone:
+		return &Provider{}, nil
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown exporter of tracing: %s", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return &Provider{tp: tp}, nil
+}
+
+// Run does nothing, spans are exported by batcher in background
+func (p *Provider) Run(log *logrus.Entry) error {
+	return nil
+}
+
+// Shutdown export remaining spans
+func (p *Provider) Shutdown(ctx context.Context, log *logrus.Entry) error {
+	if p.tp == nil {
+		return nil
+	}
+	return p.tp.Shutdown(ctx)
+}
+
+// Start span, it is child of span in ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End span with status by error
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartRPC start server span of rpc request
+func StartRPC(ctx context.Context, server, method, id string) (context.Context, trace.Span) {
+	return Start(ctx, "rpc "+method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", server),
+			attribute.String("rpc.method", method),
+			attribute.String("request.id", id),
+		),
+	)
+}
+
+// EndRPC end span of rpc request with status by error of method
+func EndRPC(span trace.Span, serr *serror.Error) {
+	if serr != nil {
+		span.SetAttributes(attribute.String("rpc.code", string(serr.Code)))
+		span.SetStatus(codes.Error, serr.Detail)
+	}
+	span.End()
+}
+
+// RecordStage add finished span which is started at start
+func RecordStage(ctx context.Context, name string, start time.Time, attrs ...attribute.KeyValue) {
+	_, span := Start(ctx, name, trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+	span.End()
+}
+
+// Extract return ctx with remote span from trace context fields, e.g. traceparent
+func Extract(ctx context.Context, fields map[string]string) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(fields))
+}
+
+// Inject return trace context fields of span in ctx, nil is returned if there is no span
+func Inject(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	fields := make(propagation.MapCarrier)
+	otel.GetTextMapPropagator().Inject(ctx, fields)
+	return fields
+}
+
+// ExtractHTTP return ctx with remote span from headers
+func ExtractHTTP(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// InjectHTTP set trace context headers of span in ctx
+func InjectHTTP(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// HasSpan return true when ctx has valid span, local or remote
+func HasSpan(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// WithSpanOf return ctx with span of from, it is used for background work which
+// continues trace of request, but has own cancellation
+func WithSpanOf(ctx, from context.Context) context.Context {
+	return trace.ContextWithSpan(ctx, trace.SpanFromContext(from))
+}
+
+// Log add trace id to log if ctx has span
+func Log(ctx context.Context, log *logrus.Entry) *logrus.Entry {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return log
+	}
+	return log.WithField("traceId", sc.TraceID().String())
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/makerdao/testchain-deployment/pkg/serror"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentID    = "00f067aa0ba902b7"
+	traceparent = "00-" + traceID + "-" + parentID + "-01"
+)
+
+func TestConfigDecode(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if err := cfg.Decode("exporter=otlp;endpoint=collector:4318;insecure=true;sampleRatio=0.5"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Exporter != ExporterOTLP || cfg.Endpoint != "collector:4318" || !cfg.Insecure || cfg.SampleRatio != 0.5 {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Config must be valid: %s", err)
+	}
+	for _, data := range []string{"exporter=jaeger", "sampleRatio=2", "exporter=otlp;endpoint=", "unknown=1"} {
+		cfg := GetDefaultConfig()
+		if err := cfg.Decode(data); err == nil && cfg.Validate() == nil {
+			t.Errorf("Config '%s' must be invalid", data)
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	header := make(http.Header)
+	header.Set("traceparent", traceparent)
+	ctx, span := StartRPC(ExtractHTTP(context.Background(), header), "http", "Deploy", "req-1")
+	fields := Inject(ctx)
+	EndRPC(span, serror.New(serror.ErrCodeBadRequest, "Bad request"))
+
+	if !strings.HasPrefix(fields["traceparent"], "00-"+traceID+"-") || strings.Contains(fields["traceparent"], parentID) {
+		t.Errorf("Trace context of child span is expected, got %v", fields)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("One span is expected, got %d", len(spans))
+	}
+	if spans[0].Parent().SpanID().String() != parentID || spans[0].Status().Code != codes.Error {
+		t.Errorf("Span must be child of remote span with error status, got parent %s and status %v",
+			spans[0].Parent().SpanID(), spans[0].Status())
+	}
+
+	// background work continues trace, but is not cancelled with request
+	reqCtx, cancel := context.WithCancel(Extract(context.Background(), fields))
+	cancel()
+	jobCtx := WithSpanOf(context.Background(), reqCtx)
+	if jobCtx.Err() != nil || Inject(jobCtx)["traceparent"] != fields["traceparent"] {
+		t.Errorf("Span of request without cancellation is expected")
+	}
+	if Inject(context.Background()) != nil {
+		t.Error("Context without span must have no trace context")
+	}
+}
@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Config of webhooks, hooks from config are registered on start
+type Config struct {
+	Hooks            []Hook
+	TimeoutSec       int
+	RetryMaxAttempts int
+	// QueueSize is count of events which wait for sending, new events are dropped if queue is full
+	QueueSize int
+}
+
+// Decode for envconfig, hook format is `hook=url|secret|event,event`, secret and events are optional
+func (c *Config) Decode(data string) error {
+	if data == "" {
+		return nil
+	}
+	params := strings.Split(data, ";")
+	for _, p := range params {
+		paramArr := strings.SplitN(p, "=", 2)
+		if len(paramArr) != 2 {
+			return fmt.Errorf("bad param in part of Webhooks env '%s'", paramArr[0])
+		}
+		if paramArr[0] == "hook" {
+			hook, err := decodeHook(paramArr[1])
+			if err != nil {
+				return err
+			}
+			hook.ID = fmt.Sprintf("config-%d", len(c.Hooks))
+			c.Hooks = append(c.Hooks, hook)
+			continue
+		}
+		v, err := strconv.Atoi(paramArr[1])
+		if err != nil {
+			return err
+		}
+		switch paramArr[0] {
+		case "timeoutSec":
+			c.TimeoutSec = v
+		case "retryMaxAttempts":
+			c.RetryMaxAttempts = v
+		case "queueSize":
+			c.QueueSize = v
+		default:
+			return fmt.Errorf("unknown param '%s' for part of Webhooks env", paramArr[0])
+		}
+	}
+
+	return nil
+}
+
+func decodeHook(data string) (Hook, error) {
+	hookArr := strings.SplitN(data, "|", 3)
+	hook := Hook{URL: hookArr[0]}
+	if len(hookArr) > 1 {
+		hook.Secret = hookArr[1]
+	}
+	if len(hookArr) > 2 && hookArr[2] != "" {
+		for _, event := range strings.Split(hookArr[2], ",") {
+			hook.Events = append(hook.Events, strings.TrimSpace(event))
+		}
+	}
+	if err := hook.Validate(); err != nil {
+		return hook, fmt.Errorf("bad hook in part of Webhooks env: %s", err)
+	}
+	return hook, nil
+}
+
+// Validate cfg after load
+func (c *Config) Validate() error {
+	if c.TimeoutSec < 1 {
+		return errors.New("timeoutSec of webhooks should be positive")
+	}
+	if c.RetryMaxAttempts < 1 {
+		return errors.New("retryMaxAttempts of webhooks should be positive")
+	}
+	if c.QueueSize < 1 {
+		return errors.New("queueSize of webhooks should be positive")
+	}
+	for _, hook := range c.Hooks {
+		if err := hook.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDefaultConfig return default config for webhook pkg
+func GetDefaultConfig() Config {
+	return Config{
+		TimeoutSec:       5,
+		RetryMaxAttempts: 3,
+		QueueSize:        1000,
+	}
+}
+
+// Hook is http endpoint which gets events, payload is signed with secret if it is set
+type Hook struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"-"`
+	// Events is filter of events, all events are sent if it is empty
+	Events []string `json:"events,omitempty"`
+	// registered is set for hook from RegisterWebhook, its url is checked with allowed hosts
+	registered bool
+}
+
+// Validate hook
+func (h Hook) Validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url of webhook should be absolute http or https url")
+	}
+	for _, event := range h.Events {
+		if !isEventType(event) {
+			return fmt.Errorf("unknown event '%s' of webhook", event)
+		}
+	}
+	return nil
+}
+
+// Accept return true if hook gets event of type
+func (h Hook) Accept(eventType string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, event := range h.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// String hide secret when config is printed
+func (h Hook) String() string {
+	secret := ""
+	if h.Secret != "" {
+		secret = "<redacted>"
+	}
+	return fmt.Sprintf("{ID:%s URL:%s Secret:%s Events:%v}", h.ID, h.URL, secret, h.Events)
+}
+
+// GoString hide secret when config is printed with %#v
+func (h Hook) GoString() string {
+	return h.String()
+}
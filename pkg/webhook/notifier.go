@@ -0,0 +1,350 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/makerdao/testchain-deployment/pkg/gateway"
+	"github.com/sirupsen/logrus"
+)
+
+// Types of events
+const (
+	EventQueued    = "job.queued"
+	EventStarted   = "job.started"
+	EventStage     = "job.stage"
+	EventSucceeded = "job.succeeded"
+	EventFailed    = "job.failed"
+	EventCancelled = "job.cancelled"
+)
+
+// Headers of webhook request
+const (
+	HeaderEvent     = "X-Testchain-Event"
+	HeaderDelivery  = "X-Testchain-Delivery"
+	HeaderSignature = "X-Testchain-Signature-256"
+)
+
+// ErrHookNotFound is returned when hook with id is not registered
+var ErrHookNotFound = errors.New("webhook not found")
+
+func isEventType(event string) bool {
+	switch event {
+	case EventQueued, EventStarted, EventStage, EventSucceeded, EventFailed, EventCancelled:
+		return true
+	}
+	return false
+}
+
+// Event of job lifecycle
+type Event struct {
+	ID     string    `json:"id"`
+	Type   string    `json:"type"`
+	JobID  string    `json:"jobId"`
+	Method string    `json:"method,omitempty"`
+	Time   time.Time `json:"time"`
+	// Stage is name of finished stage of deployment for job.stage event
+	Stage string `json:"stage,omitempty"`
+	// Result is the same payload as in result of job, it is set for finished job
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Notifier send events to registered hooks in background,
+// each hook has own queue and worker, so retries of slow hook don't delay other hooks
+type Notifier struct {
+	cfg    Config
+	client *http.Client
+	// restrictedClient send events to registered hooks, it doesn't connect to internal addresses
+	// if allowed hosts are not set
+	restrictedClient *http.Client
+	allowedHosts     []string
+	mu               sync.Mutex
+	hooks            map[string]Hook
+	queue            chan Event
+	// sending count events which are in queues or are being sent
+	sending sync.WaitGroup
+	closed  bool
+	done    chan struct{}
+}
+
+// hookWorker send events to one hook in order of queue
+type hookWorker struct {
+	hook  Hook
+	queue chan Event
+}
+
+// NewNotifier init notifier with hooks from config, hooks from config are trusted,
+// urls of hooks which are registered later are checked with allowedHosts like callbacks of gateway
+func NewNotifier(cfg Config, allowedHosts []string) *Notifier {
+	timeout := time.Duration(cfg.TimeoutSec) * time.Second
+	n := &Notifier{
+		cfg:              cfg,
+		client:           &http.Client{Timeout: timeout},
+		restrictedClient: gateway.NewRestrictedClient(timeout, allowedHosts),
+		allowedHosts:     allowedHosts,
+		hooks:            make(map[string]Hook, len(cfg.Hooks)),
+		queue:            make(chan Event, cfg.QueueSize),
+		done:             make(chan struct{}),
+	}
+	for _, hook := range cfg.Hooks {
+		n.hooks[hook.ID] = hook
+	}
+	return n
+}
+
+// Register add hook and return it with new id, host of url is checked with allowed hosts,
+// hook is kept only in memory, so it should be registered again after restart
+func (n *Notifier) Register(hook Hook) (Hook, error) {
+	if err := hook.Validate(); err != nil {
+		return hook, err
+	}
+	u, err := url.Parse(hook.URL)
+	if err != nil {
+		return hook, err
+	}
+	if err := gateway.CheckHost(u.Hostname(), n.allowedHosts); err != nil {
+		return hook, fmt.Errorf("url of webhook: %s", err)
+	}
+	hook.registered = true
+	id, err := randomID()
+	if err != nil {
+		return hook, err
+	}
+	hook.ID = id
+	n.mu.Lock()
+	n.hooks[id] = hook
+	n.mu.Unlock()
+	return hook, nil
+}
+
+// Unregister remove hook by id
+func (n *Notifier) Unregister(id string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.hooks[id]; !ok {
+		return ErrHookNotFound
+	}
+	delete(n.hooks, id)
+	return nil
+}
+
+// List return registered hooks sorted by id, secrets are not serialized
+func (n *Notifier) List() []Hook {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	res := make([]Hook, 0, len(n.hooks))
+	for _, hook := range n.hooks {
+		res = append(res, hook)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// Notify queue event, it never blocks caller, event is dropped if queue is full
+func (n *Notifier) Notify(log *logrus.Entry, event Event) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed || len(n.hooks) == 0 {
+		return
+	}
+	if event.ID == "" {
+		id, err := randomID()
+		if err != nil {
+			log.WithError(err).Error("Can't generate id of event")
+			return
+		}
+		event.ID = id
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	n.sending.Add(1)
+	select {
+	case n.queue <- event:
+	default:
+		n.sending.Done()
+		log.Warnf("Queue of webhooks is full, event %s of job %s is dropped", event.Type, event.JobID)
+	}
+}
+
+// Run pass queued events to workers of hooks until shutdown, queued events are sent before it returns
+func (n *Notifier) Run(log *logrus.Entry) error {
+	log = log.WithField("component", "webhook")
+	defer close(n.done)
+	workers := make(map[string]*hookWorker)
+	var running sync.WaitGroup
+	for event := range n.queue {
+		n.dispatch(log, event, workers, &running)
+		n.sending.Done()
+	}
+	for _, worker := range workers {
+		close(worker.queue)
+	}
+	running.Wait()
+	return nil
+}
+
+// Shutdown stop accepting events and wait until queued events are sent
+func (n *Notifier) Shutdown(ctx context.Context, log *logrus.Entry) error {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait block until queued events are sent or ctx is done
+func (n *Notifier) Wait(ctx context.Context) error {
+	sent := make(chan struct{})
+	go func() {
+		n.sending.Wait()
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dispatch queue event to workers of hooks which accept it, worker is started on the first event of hook
+// and is stopped when hook is unregistered
+func (n *Notifier) dispatch(log *logrus.Entry, event Event, workers map[string]*hookWorker, running *sync.WaitGroup) {
+	n.mu.Lock()
+	hooks := make(map[string]Hook, len(n.hooks))
+	for id, hook := range n.hooks {
+		hooks[id] = hook
+	}
+	n.mu.Unlock()
+
+	for id, worker := range workers {
+		if _, ok := hooks[id]; !ok {
+			close(worker.queue)
+			delete(workers, id)
+		}
+	}
+	for id, hook := range hooks {
+		if !hook.Accept(event.Type) {
+			continue
+		}
+		worker, ok := workers[id]
+		if !ok {
+			worker = &hookWorker{hook: hook, queue: make(chan Event, n.cfg.QueueSize)}
+			workers[id] = worker
+			running.Add(1)
+			go func() {
+				defer running.Done()
+				n.work(log.WithField("webhook", id), worker)
+			}()
+		}
+		n.sending.Add(1)
+		select {
+		case worker.queue <- event:
+		default:
+			n.sending.Done()
+			log.WithField("webhook", id).Warnf("Queue of webhook is full, event %s of job %s is dropped",
+				event.Type, event.JobID)
+		}
+	}
+}
+
+// work send events of queue to hook, each event gets own attempts
+func (n *Notifier) work(log *logrus.Entry, worker *hookWorker) {
+	for event := range worker.queue {
+		n.send(log, worker.hook, event)
+		n.sending.Done()
+	}
+}
+
+// send event to hook with retries, delay is doubled after each failed attempt
+func (n *Notifier) send(log *logrus.Entry, hook Hook, event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.WithError(err).Error("Can't marshal event")
+		return
+	}
+	delay := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := n.post(hook, event, body)
+		if err == nil {
+			log.Debugf("Event %s of job %s is sent", event.Type, event.JobID)
+			return
+		}
+		if attempt >= n.cfg.RetryMaxAttempts {
+			log.WithError(err).Errorf("Event %s of job %s is not sent after %d attempts",
+				event.Type, event.JobID, attempt)
+			return
+		}
+		log.WithError(err).Warnf("Event %s of job %s is not sent, retry in %s", event.Type, event.JobID, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (n *Notifier) post(hook Hook, event Event, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderDelivery, event.ID)
+	if hook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(hook.Secret, body))
+	}
+	client := n.client
+	if hook.registered {
+		client = n.restrictedClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	if err := resp.Body.Close(); err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected http status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign return value of signature header: hex of HMAC SHA256 of body with prefix `sha256=`
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestConfigDecode(t *testing.T) {
+	cfg := GetDefaultConfig()
+	err := cfg.Decode("timeoutSec=2;hook=https://hooks.example.com/x?a=b|s3cret|job.succeeded,job.failed;hook=http://localhost:8080/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TimeoutSec != 2 || len(cfg.Hooks) != 2 {
+		t.Fatalf("Unexpected config: %+v", cfg)
+	}
+	hook := cfg.Hooks[0]
+	if hook.ID != "config-0" || hook.URL != "https://hooks.example.com/x?a=b" || hook.Secret != "s3cret" || len(hook.Events) != 2 {
+		t.Errorf("Unexpected hook: %#v", hook)
+	}
+	if !hook.Accept(EventFailed) || hook.Accept(EventStarted) || !cfg.Hooks[1].Accept(EventStarted) {
+		t.Error("Hook must accept only events from filter, all events without filter")
+	}
+	if strings.Contains(fmt.Sprintf("%+v %#v", cfg, cfg), "s3cret") {
+		t.Error("Secret of hook must not be printed")
+	}
+	for _, data := range []string{"hook=ftp://example.com", "hook=http://example.com|s|job.unknown", "unknown=1"} {
+		cfg := GetDefaultConfig()
+		if err := cfg.Decode(data); err == nil {
+			t.Errorf("Decode of '%s' must fail", data)
+		}
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string][]Event)
+	signatures := make([]string, 0)
+	fails := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// first request fails to check retry
+		if fails > 0 {
+			fails--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Error(err)
+		}
+		if r.Header.Get(HeaderEvent) != event.Type || r.Header.Get(HeaderDelivery) != event.ID {
+			t.Errorf("Unexpected headers: %v", r.Header)
+		}
+		if sig := r.Header.Get(HeaderSignature); sig != "" {
+			if sig != Sign("s3cret", body) {
+				t.Errorf("Bad signature %s", sig)
+			}
+			signatures = append(signatures, sig)
+		}
+		received[r.URL.Path] = append(received[r.URL.Path], event)
+	}))
+	defer srv.Close()
+
+	cfg := GetDefaultConfig()
+	cfg.Hooks = []Hook{{ID: "config-0", URL: srv.URL + "/all"}}
+	// test server listens on loopback, so it has to be allowed for registered hook
+	n := NewNotifier(cfg, []string{"127.0.0.1"})
+	signed, err := n.Register(Hook{URL: srv.URL + "/done", Secret: "s3cret", Events: []string{EventSucceeded}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Register(Hook{URL: "not url"}); err == nil {
+		t.Error("Hook with bad url must not be registered")
+	}
+	log := logrus.NewEntry(logrus.New())
+	go n.Run(log)
+
+	for _, eventType := range []string{EventQueued, EventStarted, EventStage, EventSucceeded} {
+		n.Notify(log, Event{Type: eventType, JobID: "job1", Method: "Deploy"})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	if len(received["/all"]) != 4 || len(received["/done"]) != 1 || len(signatures) != 1 {
+		t.Errorf("Unexpected events: %+v", received)
+	}
+	if done := received["/done"]; len(done) == 1 && (done[0].Type != EventSucceeded || done[0].JobID != "job1") {
+		t.Errorf("Unexpected event: %+v", done[0])
+	}
+	mu.Unlock()
+
+	if err := n.Unregister(signed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Unregister(signed.ID); err != ErrHookNotFound {
+		t.Errorf("Unregistered hook must not be found, got %v", err)
+	}
+	if hooks := n.List(); len(hooks) != 1 || hooks[0].ID != "config-0" {
+		t.Errorf("Unexpected hooks: %+v", hooks)
+	}
+	if err := n.Shutdown(ctx, log); err != nil {
+		t.Fatal(err)
+	}
+	// events after shutdown are ignored
+	n.Notify(log, Event{Type: EventFailed, JobID: "job2"})
+}
+
+func TestNotifierSlowHook(t *testing.T) {
+	fast := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/failing" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Error(err)
+		}
+		fast <- event.JobID
+	}))
+	defer srv.Close()
+
+	cfg := GetDefaultConfig()
+	cfg.RetryMaxAttempts = 3
+	cfg.Hooks = []Hook{{ID: "failing", URL: srv.URL + "/failing"}, {ID: "fast", URL: srv.URL + "/fast"}}
+	// hooks from config are not checked with allowed hosts
+	n := NewNotifier(cfg, nil)
+	log := logrus.NewEntry(logrus.New())
+	go n.Run(log)
+
+	// retries of failing hook take 1.5s for each event, they don't delay events of other hook
+	n.Notify(log, Event{Type: EventQueued, JobID: "job1"})
+	n.Notify(log, Event{Type: EventQueued, JobID: "job2"})
+	timeout := time.After(time.Second)
+	for _, expected := range []string{"job1", "job2"} {
+		select {
+		case jobID := <-fast:
+			if jobID != expected {
+				t.Errorf("Events must be sent in order, expected %s, got %s", expected, jobID)
+			}
+		case <-timeout:
+			t.Fatal("Events of hook must not wait for retries of other hook")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := n.Shutdown(ctx, log); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifierRegisterDeniesInternalURL(t *testing.T) {
+	n := NewNotifier(GetDefaultConfig(), nil)
+	for _, url := range []string{
+		"http://localhost:8080/events",
+		"http://127.0.0.1/events",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/events",
+		"http://192.168.1.1/events",
+		"http://[::1]/events",
+	} {
+		if _, err := n.Register(Hook{URL: url}); err == nil {
+			t.Errorf("Hook with internal url %s must not be registered", url)
+		}
+	}
+	if _, err := n.Register(Hook{URL: "https://hooks.example.com/testchain"}); err != nil {
+		t.Errorf("Hook with public url must be registered, got %s", err)
+	}
+
+	n = NewNotifier(GetDefaultConfig(), []string{"*.example.com"})
+	if _, err := n.Register(Hook{URL: "https://hooks.other.io/testchain"}); err == nil {
+		t.Error("Hook with host which is not allowed must not be registered")
+	}
+	if _, err := n.Register(Hook{URL: "https://hooks.example.com/testchain"}); err != nil {
+		t.Errorf("Hook with allowed host must be registered, got %s", err)
+	}
+}
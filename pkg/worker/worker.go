@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -11,26 +12,47 @@ import (
 	"github.com/makerdao/testchain-deployment/pkg/deploy"
 	"github.com/makerdao/testchain-deployment/pkg/gateway"
 	"github.com/makerdao/testchain-deployment/pkg/git"
+	"github.com/makerdao/testchain-deployment/pkg/tracing"
+	"github.com/makerdao/testchain-deployment/pkg/webhook"
 	gonats "github.com/nats-io/go-nats"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type RunConfig struct {
 	RepoURL       string
 	RepoRef       string
 	RepoRev       string
+	ScenarioID    string
 	ScenarioNr    int
 	RequestID     string
 	DeployEnvVars map[string]string
+	Account       *deploy.AccountModel
+	SnapshotID    string
+	NoCache       bool
+	Callback      *gateway.Callback
+	// TraceContext is W3C trace context of caller from TRACEPARENT and TRACESTATE
+	TraceContext map[string]string
 }
 
 type Worker struct {
-	gatewayClient *gateway.Client
-	log           *logrus.Entry
+	dispatcher *gateway.Dispatcher
+	notifier   *webhook.Notifier
+	deployer   *deploy.Deployer
+	log        *logrus.Entry
 }
 
-func (w *Worker) failJob(reqID string, resErr *deploy.ResultErrorModel) error {
-	w.log.Errorf(resErr.Msg)
+// notify send event about deployment of worker to webhooks
+func (w *Worker) notify(reqID string, event webhook.Event) {
+	event.JobID = reqID
+	event.Method = "Deploy"
+	w.notifier.Notify(w.log, event)
+}
+
+func (w *Worker) failJob(ctx context.Context, reqID string, callback *gateway.Callback, resErr *deploy.ResultErrorModel) error {
+	w.log.Error(resErr.Msg)
 
 	errResBytes, err := json.Marshal(resErr)
 	if err != nil {
@@ -39,11 +61,13 @@ func (w *Worker) failJob(reqID string, resErr *deploy.ResultErrorModel) error {
 	}
 
 	resultReq := &gateway.RunResultRequest{
-		ID:     reqID,
-		Type:   gateway.RunResultRequestTypeErr,
-		Result: errResBytes,
+		ID:       reqID,
+		Type:     gateway.RunResultRequestTypeErr,
+		Result:   errResBytes,
+		Callback: callback,
 	}
-	if err := w.gatewayClient.RunResult(w.log, resultReq); err != nil {
+	w.notify(reqID, webhook.Event{Type: webhook.EventFailed, Result: errResBytes})
+	if err := w.dispatcher.RunResult(ctx, w.log, resultReq); err != nil {
 		w.log.WithError(err).Error("Can't send error result to gateway")
 		return err
 	}
@@ -51,19 +75,21 @@ func (w *Worker) failJob(reqID string, resErr *deploy.ResultErrorModel) error {
 	return fmt.Errorf("Worker failed to run deployment with error %+v", resErr)
 }
 
-func (w *Worker) returnResult(reqID string, res *deploy.ResultModel) error {
+func (w *Worker) returnResult(ctx context.Context, reqID string, callback *gateway.Callback, res *deploy.ResultModel) error {
 	resBytes, err := json.Marshal(res)
 	if err != nil {
 		w.log.WithError(err).Error("Can't marshal error for run result")
-		return w.failJob(reqID, deploy.NewResultErrorModelFromErr(err))
+		return w.failJob(ctx, reqID, callback, deploy.NewResultErrorModelFromErr(err))
 	}
 
 	resultReq := &gateway.RunResultRequest{
-		ID:     reqID,
-		Type:   gateway.RunResultRequestTypeOK,
-		Result: resBytes,
+		ID:       reqID,
+		Type:     gateway.RunResultRequestTypeOK,
+		Result:   resBytes,
+		Callback: callback,
 	}
-	if err := w.gatewayClient.RunResult(w.log, resultReq); err != nil {
+	w.notify(reqID, webhook.Event{Type: webhook.EventSucceeded, Result: resBytes})
+	if err := w.dispatcher.RunResult(ctx, w.log, resultReq); err != nil {
 		w.log.WithError(err).Error("Can't send request with result of run to gateway")
 		return err
 	}
@@ -71,31 +97,47 @@ func (w *Worker) returnResult(reqID string, res *deploy.ResultModel) error {
 	return nil
 }
 
-func readResult(res json.RawMessage) *deploy.ResultModel {
-	return deploy.NewResultModel(time.Now(), res)
-}
-
 func (w *Worker) Run() error {
 	runConfig, err := ParseEnvInput()
 	if err != nil {
 		return err
 	}
+	// callback is checked with allowed hosts of gateway config
+	if runConfig.Callback != nil {
+		if err := w.dispatcher.ValidateCallback(runConfig.Callback); err != nil {
+			return err
+		}
+	}
 	deployment := deploy.Deployment{
 		Commit: git.Commit{
 			URL: runConfig.RepoURL,
 			Ref: runConfig.RepoRef,
 			Rev: runConfig.RepoRev,
 		},
+		ScenarioID:    runConfig.ScenarioID,
 		ScenarioNr:    runConfig.ScenarioNr,
 		DeployEnvVars: runConfig.DeployEnvVars,
+		Account:       runConfig.Account,
+		SnapshotID:    runConfig.SnapshotID,
+		NoCache:       runConfig.NoCache,
+		OnStage: func(stage string) {
+			w.notify(runConfig.RequestID, webhook.Event{Type: webhook.EventStage, Stage: stage})
+		},
 	}
 
-	res, err := deploy.Deploy(w.log, deployment)
-	if err != nil {
-		return w.failJob(runConfig.RequestID, deploy.NewResultErrorModelFromErr(err))
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), runConfig.TraceContext), "job Deploy",
+		trace.WithAttributes(attribute.String("job.id", runConfig.RequestID)))
+	defer span.End()
+	w.log = tracing.Log(ctx, w.log)
+
+	w.notify(runConfig.RequestID, webhook.Event{Type: webhook.EventStarted})
+	res, resErr := w.deployer.Deploy(ctx, w.log, deployment)
+	if resErr != nil {
+		span.SetStatus(codes.Error, resErr.Msg)
+		return w.failJob(ctx, runConfig.RequestID, runConfig.Callback, resErr)
 	}
 
-	return w.returnResult(runConfig.RequestID, readResult(res))
+	return w.returnResult(ctx, runConfig.RequestID, runConfig.Callback, res)
 }
 
 func ParseEnvInput() (*RunConfig, error) {
@@ -106,10 +148,15 @@ func ParseEnvInput() (*RunConfig, error) {
 		return nil, fmt.Errorf("Need to specify REPO_URL and optinally REPO_REF and REPO_REV")
 	}
 
-	scenarioNrStr := os.Getenv("SCENARIO_NR")
-	scenarioNr, err := strconv.Atoi(scenarioNrStr)
-	if err != nil {
-		return nil, err
+	// SCENARIO_ID is used instead of SCENARIO_NR if set
+	scenarioID := os.Getenv("SCENARIO_ID")
+	var scenarioNr int
+	if scenarioID == "" {
+		var err error
+		scenarioNr, err = strconv.Atoi(os.Getenv("SCENARIO_NR"))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	requestID := os.Getenv("REQUEST_ID")
@@ -123,13 +170,53 @@ func ParseEnvInput() (*RunConfig, error) {
 		return nil, err
 	}
 
+	// DEPLOY_ACCOUNT is JSON with keystore and password or private key, optional
+	var account *deploy.AccountModel
+	if deployAccount := os.Getenv("DEPLOY_ACCOUNT"); deployAccount != "" {
+		account = &deploy.AccountModel{}
+		if err := json.Unmarshal([]byte(deployAccount), account); err != nil {
+			return nil, fmt.Errorf("DEPLOY_ACCOUNT is not valid JSON")
+		}
+	}
+
+	// NO_CACHE is optional, result cache is used by default if it is configured
+	noCache := false
+	if val := os.Getenv("NO_CACHE"); val != "" {
+		var err error
+		noCache, err = strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("NO_CACHE should be bool: %s", err)
+		}
+	}
+
+	// CALLBACK_URL and CALLBACK_SUBJECT are optional, they override transports of result
+	var callback *gateway.Callback
+	if url, subject := os.Getenv("CALLBACK_URL"), os.Getenv("CALLBACK_SUBJECT"); url != "" || subject != "" {
+		callback = &gateway.Callback{URL: url, Subject: subject}
+	}
+
+	// TRACEPARENT and TRACESTATE are optional, deployment continues trace of caller if they are set
+	traceContext := make(map[string]string)
+	if val := os.Getenv("TRACEPARENT"); val != "" {
+		traceContext["traceparent"] = val
+	}
+	if val := os.Getenv("TRACESTATE"); val != "" {
+		traceContext["tracestate"] = val
+	}
+
 	return &RunConfig{
 		RepoURL:       repoURL,
 		RepoRef:       repoRef,
 		RepoRev:       repoRev,
+		ScenarioID:    scenarioID,
 		ScenarioNr:    scenarioNr,
 		RequestID:     requestID,
 		DeployEnvVars: envVars,
+		Account:       account,
+		SnapshotID:    os.Getenv("SNAPSHOT_ID"),
+		NoCache:       noCache,
+		Callback:      callback,
+		TraceContext:  traceContext,
 	}, nil
 }
 
@@ -145,11 +232,59 @@ func Execute(log *logrus.Entry, cfg *config.Config) error {
 	}
 
 	gatewayClient := gateway.NewClient(cfg.Gateway, natsConn, cfg.NATS)
+	// results of service in delivery dir are not replayed by worker
+	dispatcher := gateway.NewWorkerDispatcher(cfg.Gateway, gatewayClient)
+	resolver, _, err := git.NewResolver(log, cfg.Git, &cfg.GitAuth)
+	if err != nil {
+		return err
+	}
+
+	deployer, err := deploy.NewDeployer(cfg.Deploy, resolver, cfg.Secrets, deploy.NewExecutors(cfg.Deploy))
+	if err != nil {
+		return err
+	}
+
+	notifier := webhook.NewNotifier(cfg.Webhooks, cfg.Gateway.CallbackHosts)
+	tracer, err := tracing.New(cfg.Tracing)
+	if err != nil {
+		return err
+	}
 
 	worker := &Worker{
-		gatewayClient: gatewayClient,
-		log:           log,
+		dispatcher: dispatcher,
+		notifier:   notifier,
+		deployer:   deployer,
+		log:        log,
 	}
 
-	return worker.Run()
+	// worker exits after run, so it waits until result is delivered or all attempts are failed
+	go func() {
+		if err := dispatcher.Run(log); err != nil {
+			log.WithError(err).Error("Dispatcher failed")
+		}
+	}()
+	go func() {
+		if err := notifier.Run(log); err != nil {
+			log.WithError(err).Error("Notifier failed")
+		}
+	}()
+	runErr := worker.Run()
+	waitCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Gateway.WorkerWaitSec)*time.Second)
+	defer cancel()
+	if err := dispatcher.Wait(waitCtx); err != nil {
+		for _, delivery := range dispatcher.List() {
+			log.WithError(err).Errorf("%s %s is not delivered: %s", delivery.Method, delivery.ID, delivery.LastError)
+		}
+	}
+	if err := dispatcher.Shutdown(context.Background(), log); err != nil {
+		log.WithError(err).Error("Can't shutdown dispatcher")
+	}
+	// queued events are sent before shutdown returns
+	if err := notifier.Shutdown(context.Background(), log); err != nil {
+		log.WithError(err).Error("Can't shutdown notifier")
+	}
+	if err := tracer.Shutdown(context.Background(), log); err != nil {
+		log.WithError(err).Error("Can't export spans")
+	}
+	return runErr
 }